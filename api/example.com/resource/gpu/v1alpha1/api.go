@@ -39,6 +39,9 @@ var Decoder runtime.Decoder
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // GpuConfig holds the set of parameters for configuring a GPU.
+//
+// Deprecated: use NpuConfig from npu.resource.example.com/v1alpha2. Decoded
+// GpuConfigs are converted to NpuConfigs by the driver.
 type GpuConfig struct {
 	metav1.TypeMeta `json:",inline"`
 	Sharing         *GpuSharing `json:"sharing,omitempty"`
@@ -0,0 +1,129 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package npu_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	gpuv1alpha1 "Ascend-dra-driver/api/example.com/resource/gpu/v1alpha1"
+	"Ascend-dra-driver/api/example.com/resource/npu"
+	"Ascend-dra-driver/api/example.com/resource/npu/install"
+)
+
+// TestDefaulting decodes the same settings in each version. All versions
+// default alike, so the internal config they end up as is the same.
+func TestDefaulting(t *testing.T) {
+	tests := map[string]struct {
+		fields   map[string]string
+		expected *npu.NpuConfig
+	}{
+		"empty NpuConfig": {
+			fields:   map[string]string{"v1alpha1": ``, "v1alpha2": ``},
+			expected: npu.DefaultNpuConfig(),
+		},
+		"empty NpuConfig with SpacePartitioning": {
+			fields: map[string]string{
+				"v1alpha1": `,"sharing":{"strategy":"SpacePartitioning"}`,
+				"v1alpha2": `,"sharing":{"strategy":"SpacePartitioning"}`,
+			},
+			expected: &npu.NpuConfig{
+				Sharing: &npu.NpuSharing{
+					Strategy: npu.SpacePartitioningStrategy,
+					SpacePartitioningConfig: &npu.SpacePartitioningConfig{
+						PartitionCount: 1,
+					},
+				},
+			},
+		},
+		"vNPU and HCCL are kept": {
+			fields: map[string]string{
+				"v1alpha1": `,"vnpuSpec":{"templateName":"vir04"},"hccl":{"connectTimeoutSeconds":600}`,
+				"v1alpha2": `,"vnpu":{"template":"vir04"},"hccl":{"connectTimeoutSeconds":600}`,
+			},
+			expected: &npu.NpuConfig{
+				Vnpu:    &npu.VnpuConfig{Template: "vir04"},
+				Hccl:    &npu.HcclConfig{ConnectTimeoutSeconds: 600},
+				Sharing: npu.DefaultNpuConfig().Sharing,
+			},
+		},
+		"explicit settings are kept": {
+			fields: map[string]string{
+				"v1alpha1": `,"sharing":{"strategy":"TimeSlicing","timeSlicingConfig":{"interval":"Long"}}`,
+				"v1alpha2": `,"sharing":{"strategy":"TimeSlicing","timeSlicingConfig":{"interval":"Long"}}`,
+			},
+			expected: &npu.NpuConfig{
+				Sharing: &npu.NpuSharing{
+					Strategy:          npu.TimeSlicingStrategy,
+					TimeSlicingConfig: &npu.TimeSlicingConfig{Interval: npu.LongTimeSlice},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		for version, fields := range test.fields {
+			t.Run(name+"/"+version, func(t *testing.T) {
+				raw := `{"apiVersion":"npu.resource.example.com/` + version + `","kind":"NpuConfig"` + fields + `}`
+				obj, err := runtime.Decode(install.Decoder, []byte(raw))
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, obj)
+				assert.NoError(t, obj.(*npu.NpuConfig).Validate())
+			})
+		}
+	}
+}
+
+func TestDecodeToNpuConfig(t *testing.T) {
+	gpuConfig, err := json.Marshal(gpuv1alpha1.DefaultGpuConfig())
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		raw         string
+		expected    *npu.NpuConfig
+		expectedErr bool
+	}{
+		"default GpuConfig": {
+			raw:      string(gpuConfig),
+			expected: npu.DefaultNpuConfig(),
+		},
+		"no object": {
+			raw:         `null`,
+			expectedErr: true,
+		},
+		"other kind": {
+			raw:         `{"apiVersion":"v1","kind":"Pod"}`,
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			obj, err := runtime.Decode(install.Decoder, []byte(test.raw))
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, obj)
+		})
+	}
+}
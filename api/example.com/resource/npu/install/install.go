@@ -0,0 +1,104 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package install registers all versions of the NPU configuration API and
+// provides the Decoder the driver uses for opaque device configs.
+package install
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	gpuv1alpha1 "Ascend-dra-driver/api/example.com/resource/gpu/v1alpha1"
+	"Ascend-dra-driver/api/example.com/resource/npu"
+	"Ascend-dra-driver/api/example.com/resource/npu/v1alpha1"
+	"Ascend-dra-driver/api/example.com/resource/npu/v1alpha2"
+)
+
+var (
+	// Scheme knows all versions of the NPU configuration API and the
+	// deprecated GpuConfig.
+	Scheme = runtime.NewScheme()
+
+	// Decoder strictly decodes any version of NpuConfig, or a GpuConfig, and
+	// returns it defaulted and converted to the internal *npu.NpuConfig.
+	Decoder runtime.Decoder
+)
+
+func init() {
+	Install(Scheme)
+	Decoder = &decoder{
+		scheme: Scheme,
+		deserializer: json.NewSerializerWithOptions(
+			json.DefaultMetaFactory,
+			Scheme,
+			Scheme,
+			json.SerializerOptions{
+				Pretty: true, Strict: true,
+			},
+		),
+	}
+}
+
+// Install registers the internal and all external versions of the API, plus
+// the deprecated GpuConfig, with scheme.
+func Install(scheme *runtime.Scheme) {
+	utilruntime.Must(npu.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+	utilruntime.Must(v1alpha2.AddToScheme(scheme))
+	utilruntime.Must(scheme.SetVersionPriority(v1alpha2.SchemeGroupVersion, v1alpha1.SchemeGroupVersion))
+
+	gpuGroupVersion := schema.GroupVersion{
+		Group:   gpuv1alpha1.GroupName,
+		Version: gpuv1alpha1.Version,
+	}
+	scheme.AddKnownTypes(gpuGroupVersion,
+		&gpuv1alpha1.GpuConfig{},
+	)
+	metav1.AddToGroupVersion(scheme, gpuGroupVersion)
+}
+
+// decoder converts whatever the strict deserializer returns into the internal
+// version. The target object passed to Decode is ignored.
+type decoder struct {
+	scheme       *runtime.Scheme
+	deserializer runtime.Decoder
+}
+
+func (d *decoder) Decode(data []byte, defaults *schema.GroupVersionKind, _ runtime.Object) (runtime.Object, *schema.GroupVersionKind, error) {
+	obj, gvk, err := d.deserializer.Decode(data, defaults, nil)
+	if err != nil {
+		return nil, gvk, err
+	}
+	if gvk.Version == runtime.APIVersionInternal {
+		return nil, gvk, fmt.Errorf("%v is not a served version", gvk.GroupVersion())
+	}
+	if gpuConfig, ok := obj.(*gpuv1alpha1.GpuConfig); ok {
+		obj = v1alpha1.ConvertGpuConfig(gpuConfig)
+	}
+
+	d.scheme.Default(obj)
+	out := &npu.NpuConfig{}
+	if err := d.scheme.Convert(obj, out, nil); err != nil {
+		return nil, gvk, fmt.Errorf("convert %v to the internal version: %w", gvk, err)
+	}
+	return out, gvk, nil
+}
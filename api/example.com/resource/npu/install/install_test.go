@@ -0,0 +1,163 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package install
+
+import (
+	"encoding/json"
+	"testing"
+
+	fuzz "github.com/google/gofuzz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"Ascend-dra-driver/api/example.com/resource/npu"
+	"Ascend-dra-driver/api/example.com/resource/npu/v1alpha1"
+	"Ascend-dra-driver/api/example.com/resource/npu/v1alpha2"
+)
+
+func TestDecoder(t *testing.T) {
+	timeSlicing := &npu.NpuSharing{
+		Strategy:          npu.TimeSlicingStrategy,
+		TimeSlicingConfig: &npu.TimeSlicingConfig{Interval: npu.DefaultTimeSlice},
+	}
+
+	tests := map[string]struct {
+		raw         string
+		expected    *npu.NpuConfig
+		expectedErr bool
+	}{
+		"v1alpha1 NpuConfig": {
+			raw: `{"apiVersion":"npu.resource.example.com/v1alpha1","kind":"NpuConfig",` +
+				`"vnpuSpec":{"aicore":4,"memoryGiB":8},"hccl":{"connectTimeoutSeconds":600}}`,
+			expected: &npu.NpuConfig{
+				Vnpu:    &npu.VnpuConfig{Resources: &npu.VnpuResources{Aicore: 4, MemoryGiB: 8}},
+				Sharing: timeSlicing,
+				Hccl:    &npu.HcclConfig{ConnectTimeoutSeconds: 600},
+			},
+		},
+		"v1alpha2 NpuConfig": {
+			raw: `{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"NpuConfig",` +
+				`"vnpu":{"template":"vir04"},"sharing":{"strategy":"SpacePartitioning"}}`,
+			expected: &npu.NpuConfig{
+				Vnpu: &npu.VnpuConfig{Template: "vir04"},
+				Sharing: &npu.NpuSharing{
+					Strategy:                npu.SpacePartitioningStrategy,
+					SpacePartitioningConfig: &npu.SpacePartitioningConfig{PartitionCount: 1},
+				},
+			},
+		},
+		"v1alpha2 NpuConfig with AI CPUs and DVPP": {
+			raw: `{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"NpuConfig",` +
+				`"vnpu":{"resources":{"aicore":2,"aicpu":1,"dvpp":true}}}`,
+			expected: &npu.NpuConfig{
+				Vnpu:    &npu.VnpuConfig{Resources: &npu.VnpuResources{Aicore: 2, Aicpu: 1, Dvpp: true}},
+				Sharing: timeSlicing,
+			},
+		},
+		"v1alpha1 NpuConfig with AI CPUs and DVPP": {
+			raw: `{"apiVersion":"npu.resource.example.com/v1alpha1","kind":"NpuConfig",` +
+				`"vnpuSpec":{"aicpu":1,"dvpp":true}}`,
+			expected: &npu.NpuConfig{
+				Vnpu:    &npu.VnpuConfig{Resources: &npu.VnpuResources{Aicpu: 1, Dvpp: true}},
+				Sharing: timeSlicing,
+			},
+		},
+		"deprecated GpuConfig": {
+			raw: `{"apiVersion":"gpu.resource.example.com/v1alpha1","kind":"GpuConfig",` +
+				`"vnpuSpec":{"templateName":"vir02"},"sharing":{"strategy":"TimeSlicing"}}`,
+			expected: &npu.NpuConfig{
+				Vnpu:    &npu.VnpuConfig{Template: "vir02"},
+				Sharing: timeSlicing,
+			},
+		},
+		"v1alpha1 field in v1alpha2": {
+			raw:         `{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"NpuConfig","vnpuSpec":{}}`,
+			expectedErr: true,
+		},
+		"v1alpha2 field in v1alpha1": {
+			raw:         `{"apiVersion":"npu.resource.example.com/v1alpha1","kind":"NpuConfig","vnpu":{}}`,
+			expectedErr: true,
+		},
+		"unknown kind": {
+			raw:         `{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"GpuConfig"}`,
+			expectedErr: true,
+		},
+		"internal version": {
+			raw:         `{"apiVersion":"npu.resource.example.com/__internal","kind":"NpuConfig"}`,
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			obj, err := runtime.Decode(Decoder, []byte(test.raw))
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, obj)
+		})
+	}
+}
+
+// TestRoundTrip converts random internal configs to each version, encodes
+// them, and checks that decoding returns the original config.
+func TestRoundTrip(t *testing.T) {
+	fuzzer := fuzz.New().NilChance(0.3).Funcs(
+		func(obj *npu.NpuConfig, c fuzz.Continue) {
+			c.FuzzNoCustom(obj)
+			obj.TypeMeta = metav1.TypeMeta{}
+			// Sharing is always set by defaulting.
+			if obj.Sharing == nil {
+				obj.Sharing = &npu.NpuSharing{}
+			}
+		},
+		func(obj *npu.VnpuConfig, c fuzz.Continue) {
+			c.FuzzNoCustom(obj)
+			// v1alpha1 cannot tell empty resources from none.
+			if obj.Resources != nil && *obj.Resources == (npu.VnpuResources{}) {
+				obj.Resources = nil
+			}
+		},
+	)
+
+	for _, gv := range []schema.GroupVersion{v1alpha1.SchemeGroupVersion, v1alpha2.SchemeGroupVersion} {
+		t.Run(gv.Version, func(t *testing.T) {
+			for range 500 {
+				original := &npu.NpuConfig{}
+				fuzzer.Fuzz(original)
+				if gv == v1alpha1.SchemeGroupVersion {
+					// v1alpha1 has no runtime settings.
+					original.Runtime = nil
+				}
+
+				external, err := Scheme.ConvertToVersion(original.DeepCopy(), gv)
+				require.NoError(t, err)
+				data, err := json.Marshal(external)
+				require.NoError(t, err)
+
+				decoded, err := runtime.Decode(Decoder, data)
+				require.NoError(t, err, string(data))
+				require.Equal(t, original, decoded, string(data))
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package npu
+
+import (
+	"fmt"
+)
+
+// IsTimeSlicing checks if the TimeSlicing strategy is applied.
+func (s *NpuSharing) IsTimeSlicing() bool {
+	if s == nil {
+		return false
+	}
+	return s.Strategy == TimeSlicingStrategy
+}
+
+// IsSpacePartitioning checks if the SpacePartitioning strategy is applied.
+func (s *NpuSharing) IsSpacePartitioning() bool {
+	if s == nil {
+		return false
+	}
+	return s.Strategy == SpacePartitioningStrategy
+}
+
+// GetTimeSlicingConfig returns the timeslicing config that applies to the given strategy.
+func (s *NpuSharing) GetTimeSlicingConfig() (*TimeSlicingConfig, error) {
+	if s == nil {
+		return nil, fmt.Errorf("no sharing set to get config from")
+	}
+	if s.Strategy != TimeSlicingStrategy {
+		return nil, fmt.Errorf("strategy is not set to '%v'", TimeSlicingStrategy)
+	}
+	if s.SpacePartitioningConfig != nil {
+		return nil, fmt.Errorf("cannot use SpacePartitioningConfig with the '%v' strategy", TimeSlicingStrategy)
+	}
+	return s.TimeSlicingConfig, nil
+}
+
+// GetSpacePartitioningConfig returns the SpacePartitioning config that applies to the given strategy.
+func (s *NpuSharing) GetSpacePartitioningConfig() (*SpacePartitioningConfig, error) {
+	if s == nil {
+		return nil, fmt.Errorf("no sharing set to get config from")
+	}
+	if s.Strategy != SpacePartitioningStrategy {
+		return nil, fmt.Errorf("strategy is not set to '%v'", SpacePartitioningStrategy)
+	}
+	if s.TimeSlicingConfig != nil {
+		return nil, fmt.Errorf("cannot use TimeSlicingConfig with the '%v' strategy", SpacePartitioningStrategy)
+	}
+	return s.SpacePartitioningConfig, nil
+}
+
+// Partitions returns the number of vNPUs the card is partitioned into, or 1
+// if the card is not partitioned.
+func (s *NpuSharing) Partitions() int {
+	if config, err := s.GetSpacePartitioningConfig(); err == nil && config != nil && config.PartitionCount > 1 {
+		return config.PartitionCount
+	}
+	return 1
+}
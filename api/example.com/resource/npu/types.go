@@ -0,0 +1,111 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package npu
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NpuConfig holds the set of parameters for configuring an NPU.
+type NpuConfig struct {
+	metav1.TypeMeta
+	// Vnpu requests a vNPU instead of a whole card.
+	Vnpu    *VnpuConfig
+	Sharing *NpuSharing
+	// Hccl configures the collective communication library.
+	Hccl *HcclConfig
+	// Runtime tunes the CANN runtime of the containers using the NPU.
+	Runtime *RuntimeConfig
+}
+
+// VnpuConfig selects the vNPU to carve, either by template or by the
+// resources it has to provide.
+type VnpuConfig struct {
+	// Template is the name of a vNPU template, e.g. vir02.
+	Template  string
+	Resources *VnpuResources
+}
+
+// VnpuResources are the minimum resources a vNPU has to provide.
+type VnpuResources struct {
+	Aicore    int
+	MemoryGiB int
+	Aicpu     int
+	// Dvpp requires the vNPU to have media processing units.
+	Dvpp bool
+}
+
+// NpuSharingStrategy defines the valid Sharing strategies as a string.
+type NpuSharingStrategy string
+
+// These constants represent the different Sharing strategies.
+const (
+	TimeSlicingStrategy       NpuSharingStrategy = "TimeSlicing"
+	SpacePartitioningStrategy NpuSharingStrategy = "SpacePartitioning"
+)
+
+// TimeSliceInterval defines the valid timeslice interval as a string.
+type TimeSliceInterval string
+
+// These constants represent the different TimeSlicing configurations.
+const (
+	DefaultTimeSlice TimeSliceInterval = "Default"
+	ShortTimeSlice   TimeSliceInterval = "Short"
+	MediumTimeSlice  TimeSliceInterval = "Medium"
+	LongTimeSlice    TimeSliceInterval = "Long"
+)
+
+// NpuSharing holds the current sharing strategy for NPUs and its settings.
+type NpuSharing struct {
+	Strategy                NpuSharingStrategy
+	TimeSlicingConfig       *TimeSlicingConfig
+	SpacePartitioningConfig *SpacePartitioningConfig
+}
+
+// TimeSlicingConfig provides the settings for the TimeSlicing strategy.
+type TimeSlicingConfig struct {
+	Interval TimeSliceInterval
+}
+
+// SpacePartitioningConfig provides the configuring for the SpacePartitioning strategy.
+type SpacePartitioningConfig struct {
+	PartitionCount int
+}
+
+// HcclConfig holds the HCCL settings applied to the containers using the NPU.
+type HcclConfig struct {
+	ConnectTimeoutSeconds int
+	ExecTimeoutSeconds    int
+	SocketIfname          string
+}
+
+// RuntimeConfig holds CANN and HCCL settings applied to the containers using
+// the NPU. Only the settings the cluster administrator allows are applied.
+type RuntimeConfig struct {
+	Env    map[string]string
+	Mounts []RuntimeMount
+}
+
+// RuntimeMount mounts a host directory into the containers, e.g. to collect
+// profiling output.
+type RuntimeMount struct {
+	HostPath      string
+	ContainerPath string
+	ReadOnly      bool
+}
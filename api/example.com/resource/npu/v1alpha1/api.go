@@ -0,0 +1,58 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NpuConfig holds the set of parameters for configuring an NPU.
+type NpuConfig struct {
+	metav1.TypeMeta `json:",inline"`
+	// VnpuSpec requests a vNPU instead of a whole card.
+	VnpuSpec *VnpuSpec   `json:"vnpuSpec,omitempty"`
+	Sharing  *NpuSharing `json:"sharing,omitempty"`
+	// Hccl configures the collective communication library.
+	Hccl *HcclConfig `json:"hccl,omitempty"`
+}
+
+// VnpuSpec selects the vNPU to carve, either by template or by the resources
+// it has to provide.
+type VnpuSpec struct {
+	// TemplateName is the name of a vNPU template, e.g. vir02.
+	TemplateName string `json:"templateName,omitempty"`
+	// Aicore is the minimum number of AI Cores of the vNPU.
+	Aicore int `json:"aicore,omitempty"`
+	// MemoryGiB is the minimum HBM size of the vNPU in GiB.
+	MemoryGiB int `json:"memoryGiB,omitempty"`
+	// Aicpu is the minimum number of AI CPUs of the vNPU.
+	Aicpu int `json:"aicpu,omitempty"`
+	// Dvpp requires the vNPU to have DVPP media processing units.
+	Dvpp bool `json:"dvpp,omitempty"`
+}
+
+// HcclConfig holds the HCCL settings applied to the containers using the NPU.
+type HcclConfig struct {
+	// ConnectTimeoutSeconds sets HCCL_CONNECT_TIMEOUT.
+	ConnectTimeoutSeconds int `json:"connectTimeoutSeconds,omitempty"`
+	// ExecTimeoutSeconds sets HCCL_EXEC_TIMEOUT.
+	ExecTimeoutSeconds int `json:"execTimeoutSeconds,omitempty"`
+	// SocketIfname sets HCCL_SOCKET_IFNAME.
+	SocketIfname string `json:"socketIfname,omitempty"`
+}
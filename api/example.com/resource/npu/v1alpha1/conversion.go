@@ -0,0 +1,64 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/conversion"
+
+	"Ascend-dra-driver/api/example.com/resource/npu"
+)
+
+// Convert_v1alpha1_NpuConfig_To_npu_NpuConfig converts the flat vNPU spec of
+// v1alpha1 into a template and resources.
+func Convert_v1alpha1_NpuConfig_To_npu_NpuConfig(in *NpuConfig, out *npu.NpuConfig, s conversion.Scope) error {
+	if err := autoConvert_v1alpha1_NpuConfig_To_npu_NpuConfig(in, out, s); err != nil {
+		return err
+	}
+	out.Vnpu = nil
+	if in.VnpuSpec != nil {
+		out.Vnpu = &npu.VnpuConfig{Template: in.VnpuSpec.TemplateName}
+		resources := npu.VnpuResources{
+			Aicore:    in.VnpuSpec.Aicore,
+			MemoryGiB: in.VnpuSpec.MemoryGiB,
+			Aicpu:     in.VnpuSpec.Aicpu,
+			Dvpp:      in.VnpuSpec.Dvpp,
+		}
+		if resources != (npu.VnpuResources{}) {
+			out.Vnpu.Resources = &resources
+		}
+	}
+	return nil
+}
+
+// Convert_npu_NpuConfig_To_v1alpha1_NpuConfig flattens the vNPU template and
+// resources into the v1alpha1 vNPU spec.
+func Convert_npu_NpuConfig_To_v1alpha1_NpuConfig(in *npu.NpuConfig, out *NpuConfig, s conversion.Scope) error {
+	if err := autoConvert_npu_NpuConfig_To_v1alpha1_NpuConfig(in, out, s); err != nil {
+		return err
+	}
+	out.VnpuSpec = nil
+	if in.Vnpu != nil {
+		out.VnpuSpec = &VnpuSpec{TemplateName: in.Vnpu.Template}
+		if in.Vnpu.Resources != nil {
+			out.VnpuSpec.Aicore = in.Vnpu.Resources.Aicore
+			out.VnpuSpec.MemoryGiB = in.Vnpu.Resources.MemoryGiB
+			out.VnpuSpec.Aicpu = in.Vnpu.Resources.Aicpu
+			out.VnpuSpec.Dvpp = in.Vnpu.Resources.Dvpp
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1alpha1
+
+import (
+	gpuv1alpha1 "Ascend-dra-driver/api/example.com/resource/gpu/v1alpha1"
+)
+
+// ConvertGpuConfig converts the deprecated GpuConfig into an NpuConfig.
+func ConvertGpuConfig(in *gpuv1alpha1.GpuConfig) *NpuConfig {
+	out := &NpuConfig{}
+	out.APIVersion = GroupName + "/" + Version
+	out.Kind = NpuConfigKind
+	if in.VnpuSpec != nil {
+		out.VnpuSpec = &VnpuSpec{
+			TemplateName: in.VnpuSpec.TemplateName,
+		}
+	}
+	if in.Sharing != nil {
+		out.Sharing = &NpuSharing{
+			Strategy: NpuSharingStrategy(in.Sharing.Strategy),
+		}
+		if in.Sharing.TimeSlicingConfig != nil {
+			out.Sharing.TimeSlicingConfig = &TimeSlicingConfig{
+				Interval: TimeSliceInterval(in.Sharing.TimeSlicingConfig.Interval),
+			}
+		}
+		if in.Sharing.SpacePartitioningConfig != nil {
+			out.Sharing.SpacePartitioningConfig = &SpacePartitioningConfig{
+				PartitionCount: in.Sharing.SpacePartitioningConfig.PartitionCount,
+			}
+		}
+	}
+	return out
+}
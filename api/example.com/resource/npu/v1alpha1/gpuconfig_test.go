@@ -0,0 +1,80 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	gpuv1alpha1 "Ascend-dra-driver/api/example.com/resource/gpu/v1alpha1"
+)
+
+func TestConvertGpuConfig(t *testing.T) {
+	typeMeta := metav1.TypeMeta{APIVersion: SchemeGroupVersion.String(), Kind: NpuConfigKind}
+
+	tests := map[string]struct {
+		gpuConfig *gpuv1alpha1.GpuConfig
+		expected  *NpuConfig
+	}{
+		"empty GpuConfig": {
+			gpuConfig: &gpuv1alpha1.GpuConfig{},
+			expected:  &NpuConfig{TypeMeta: typeMeta},
+		},
+		"GpuConfig with VnpuSpec": {
+			gpuConfig: &gpuv1alpha1.GpuConfig{
+				VnpuSpec: &gpuv1alpha1.VnpuSpec{TemplateName: "vir04"},
+			},
+			expected: &NpuConfig{
+				TypeMeta: typeMeta,
+				VnpuSpec: &VnpuSpec{TemplateName: "vir04"},
+			},
+		},
+		"GpuConfig with SpacePartitioning": {
+			gpuConfig: &gpuv1alpha1.GpuConfig{
+				Sharing: &gpuv1alpha1.GpuSharing{
+					Strategy: gpuv1alpha1.SpacePartitioningStrategy,
+					TimeSlicingConfig: &gpuv1alpha1.TimeSlicingConfig{
+						Interval: gpuv1alpha1.LongTimeSlice,
+					},
+					SpacePartitioningConfig: &gpuv1alpha1.SpacePartitioningConfig{
+						PartitionCount: 2,
+					},
+				},
+			},
+			expected: &NpuConfig{
+				TypeMeta: typeMeta,
+				Sharing: &NpuSharing{
+					Strategy: SpacePartitioningStrategy,
+					TimeSlicingConfig: &TimeSlicingConfig{
+						Interval: LongTimeSlice,
+					},
+					SpacePartitioningConfig: &SpacePartitioningConfig{
+						PartitionCount: 2,
+					},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, ConvertGpuConfig(test.gpuConfig))
+		})
+	}
+}
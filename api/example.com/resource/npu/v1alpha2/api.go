@@ -0,0 +1,88 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NpuConfig holds the set of parameters for configuring an NPU.
+type NpuConfig struct {
+	metav1.TypeMeta `json:",inline"`
+	// Vnpu requests a vNPU instead of a whole card.
+	Vnpu *VnpuConfig `json:"vnpu,omitempty"`
+	// Sharing configures how the NPU is shared between containers.
+	Sharing *NpuSharing `json:"sharing,omitempty"`
+	// Hccl configures the collective communication library.
+	Hccl *HcclConfig `json:"hccl,omitempty"`
+	// Runtime tunes the CANN runtime of the containers using the NPU.
+	Runtime *RuntimeConfig `json:"runtime,omitempty"`
+}
+
+// VnpuConfig selects the vNPU to carve, either by template or by the
+// resources it has to provide.
+type VnpuConfig struct {
+	// Template is the name of a vNPU template, e.g. vir02.
+	Template string `json:"template,omitempty"`
+	// Resources are the minimum resources of the vNPU.
+	Resources *VnpuResources `json:"resources,omitempty"`
+}
+
+// VnpuResources are the minimum resources a vNPU has to provide.
+type VnpuResources struct {
+	// Aicore is the minimum number of AI Cores.
+	Aicore int `json:"aicore,omitempty"`
+	// MemoryGiB is the minimum HBM size in GiB.
+	MemoryGiB int `json:"memoryGiB,omitempty"`
+	// Aicpu is the minimum number of AI CPUs.
+	Aicpu int `json:"aicpu,omitempty"`
+	// Dvpp requires the vNPU to have DVPP media processing units.
+	Dvpp bool `json:"dvpp,omitempty"`
+}
+
+// HcclConfig holds the HCCL settings applied to the containers using the NPU.
+type HcclConfig struct {
+	// ConnectTimeoutSeconds sets HCCL_CONNECT_TIMEOUT.
+	ConnectTimeoutSeconds int `json:"connectTimeoutSeconds,omitempty"`
+	// ExecTimeoutSeconds sets HCCL_EXEC_TIMEOUT.
+	ExecTimeoutSeconds int `json:"execTimeoutSeconds,omitempty"`
+	// SocketIfname sets HCCL_SOCKET_IFNAME.
+	SocketIfname string `json:"socketIfname,omitempty"`
+}
+
+// RuntimeConfig holds CANN and HCCL settings applied to the containers using
+// the NPU. Only the settings the cluster administrator allows are applied.
+type RuntimeConfig struct {
+	// Env are environment variables read by CANN and HCCL, e.g.
+	// ASCEND_GLOBAL_LOG_LEVEL.
+	Env map[string]string `json:"env,omitempty"`
+	// Mounts are host directories mounted into the containers.
+	Mounts []RuntimeMount `json:"mounts,omitempty"`
+}
+
+// RuntimeMount mounts a host directory into the containers, e.g. to collect
+// profiling output.
+type RuntimeMount struct {
+	// HostPath is the absolute path of the directory on the host.
+	HostPath string `json:"hostPath"`
+	// ContainerPath is the absolute path the directory is mounted at.
+	ContainerPath string `json:"containerPath"`
+	// ReadOnly mounts the directory read-only.
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
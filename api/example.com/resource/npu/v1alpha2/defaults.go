@@ -0,0 +1,40 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1alpha2
+
+// SetDefaults_NpuConfig defaults to time-slicing the NPU.
+func SetDefaults_NpuConfig(obj *NpuConfig) {
+	if obj.Sharing == nil {
+		obj.Sharing = &NpuSharing{
+			Strategy: TimeSlicingStrategy,
+		}
+	}
+}
+
+// SetDefaults_NpuSharing sets the settings implied by the sharing strategy.
+func SetDefaults_NpuSharing(obj *NpuSharing) {
+	if obj.Strategy == TimeSlicingStrategy && obj.TimeSlicingConfig == nil {
+		obj.TimeSlicingConfig = &TimeSlicingConfig{
+			Interval: DefaultTimeSlice,
+		}
+	}
+	if obj.Strategy == SpacePartitioningStrategy && obj.SpacePartitioningConfig == nil {
+		obj.SpacePartitioningConfig = &SpacePartitioningConfig{
+			PartitionCount: 1,
+		}
+	}
+}
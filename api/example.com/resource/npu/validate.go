@@ -0,0 +1,278 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package npu
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// envNameRegexp matches valid environment variable names.
+var envNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// runtimeEnvValidators check the values of the CANN and HCCL settings whose
+// format is known. Other settings only need to be valid environment variables.
+var runtimeEnvValidators = map[string]func(string) error{
+	"HCCL_CONNECT_TIMEOUT":        validateSeconds,
+	"HCCL_EXEC_TIMEOUT":           validateSeconds,
+	"ASCEND_GLOBAL_LOG_LEVEL":     validateLogLevel,
+	"ASCEND_GLOBAL_EVENT_ENABLE":  validateSwitch,
+	"ASCEND_SLOG_PRINT_TO_STDOUT": validateSwitch,
+	"ASCEND_RT_VISIBLE_DEVICES": func(value string) error {
+		_, err := ParseRuntimeVisibleDevices(value)
+		return err
+	},
+}
+
+// Validate ensures that NpuSharingStrategy has a valid set of values.
+func (s NpuSharingStrategy) Validate() error {
+	switch s {
+	case TimeSlicingStrategy, SpacePartitioningStrategy:
+		return nil
+	}
+	return fmt.Errorf("unknown NPU sharing strategy: %v", s)
+}
+
+// Validate ensures that TimeSliceInterval has a valid set of values.
+func (d TimeSliceInterval) Validate() error {
+	switch d {
+	case DefaultTimeSlice, ShortTimeSlice, MediumTimeSlice, LongTimeSlice:
+		return nil
+	}
+	return fmt.Errorf("unknown time-slice interval: %v", d)
+}
+
+// Validate ensures that TimeSlicingConfig has a valid set of values. Ascend
+// NPUs schedule the processes sharing them on their own, so intervals other
+// than Default are still accepted, but have no effect.
+func (c *TimeSlicingConfig) Validate() error {
+	return c.Interval.Validate()
+}
+
+// Validate ensures that SpacePartitioningConfig has a valid set of values.
+// vNPU templates split a card into halves, quarters, eighths or sixteenths.
+func (c *SpacePartitioningConfig) Validate() error {
+	switch c.PartitionCount {
+	case 1, 2, 4, 8, 16:
+		return nil
+	}
+	return fmt.Errorf("invalid partition count: %v, Ascend NPUs can be partitioned into 1, 2, 4, 8 or 16 vNPUs", c.PartitionCount)
+}
+
+// Validate ensures that NpuSharing has a valid set of values.
+func (s *NpuSharing) Validate() error {
+	if err := s.Strategy.Validate(); err != nil {
+		return err
+	}
+	switch {
+	case s.IsTimeSlicing():
+		return s.TimeSlicingConfig.Validate()
+	case s.IsSpacePartitioning():
+		return s.SpacePartitioningConfig.Validate()
+	}
+	return fmt.Errorf("invalid NPU sharing settings: %v", s)
+}
+
+// Validate ensures that NpuConfig has a valid set of values.
+func (c *NpuConfig) Validate() error {
+	if c.Sharing == nil {
+		return fmt.Errorf("no sharing strategy set")
+	}
+	if err := c.Sharing.Validate(); err != nil {
+		return err
+	}
+	if c.Vnpu != nil {
+		if err := c.Vnpu.Validate(); err != nil {
+			return err
+		}
+		if partitions := c.Sharing.Partitions(); partitions > 1 {
+			return fmt.Errorf("a vNPU and partitioning the NPU into %d vNPUs are mutually exclusive", partitions)
+		}
+	}
+	if c.Hccl != nil {
+		if err := c.Hccl.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Runtime != nil {
+		if err := c.Runtime.Validate(); err != nil {
+			return err
+		}
+		for _, name := range hcclEnvNames(c.Hccl) {
+			if _, ok := c.Runtime.Env[name]; ok {
+				return fmt.Errorf("%s is set by both the HCCL and the runtime settings", name)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate ensures that VnpuConfig has a valid set of values.
+func (v *VnpuConfig) Validate() error {
+	if v.Resources != nil {
+		if v.Resources.Aicore < 0 {
+			return fmt.Errorf("invalid vNPU AI Core count: %v", v.Resources.Aicore)
+		}
+		if v.Resources.MemoryGiB < 0 {
+			return fmt.Errorf("invalid vNPU memory size: %v", v.Resources.MemoryGiB)
+		}
+		if v.Resources.Aicpu < 0 {
+			return fmt.Errorf("invalid vNPU AI CPU count: %v", v.Resources.Aicpu)
+		}
+		if v.Resources.Aicore > 0 && v.Resources.Aicpu > v.Resources.Aicore {
+			return fmt.Errorf("vNPU AI CPU count %v exceeds the AI Core count %v", v.Resources.Aicpu, v.Resources.Aicore)
+		}
+		if *v.Resources != (VnpuResources{}) {
+			if v.Template != "" {
+				return fmt.Errorf("vNPU template %q and resources are mutually exclusive", v.Template)
+			}
+			return nil
+		}
+	}
+	if v.Template == "" {
+		return fmt.Errorf("vNPU template name or resources are required")
+	}
+	return nil
+}
+
+// Validate ensures that HcclConfig has a valid set of values.
+func (h *HcclConfig) Validate() error {
+	if h.ConnectTimeoutSeconds < 0 {
+		return fmt.Errorf("invalid HCCL connect timeout: %v", h.ConnectTimeoutSeconds)
+	}
+	if h.ExecTimeoutSeconds < 0 {
+		return fmt.Errorf("invalid HCCL exec timeout: %v", h.ExecTimeoutSeconds)
+	}
+	return nil
+}
+
+// hcclEnvNames returns the names of the environment variables set by hccl.
+func hcclEnvNames(h *HcclConfig) []string {
+	if h == nil {
+		return nil
+	}
+	var names []string
+	if h.ConnectTimeoutSeconds > 0 {
+		names = append(names, "HCCL_CONNECT_TIMEOUT")
+	}
+	if h.ExecTimeoutSeconds > 0 {
+		names = append(names, "HCCL_EXEC_TIMEOUT")
+	}
+	if h.SocketIfname != "" {
+		names = append(names, "HCCL_SOCKET_IFNAME")
+	}
+	return names
+}
+
+// Validate ensures that RuntimeConfig has a valid set of values. Whether the
+// settings are allowed is up to the cluster administrator and checked when
+// the claim is prepared.
+func (r *RuntimeConfig) Validate() error {
+	for _, name := range slices.Sorted(maps.Keys(r.Env)) {
+		value := r.Env[name]
+		if !envNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid environment variable name: %q", name)
+		}
+		if strings.ContainsAny(value, "\x00\n") {
+			return fmt.Errorf("invalid value of environment variable %s: %q", name, value)
+		}
+		if validate, ok := runtimeEnvValidators[name]; ok {
+			if err := validate(value); err != nil {
+				return fmt.Errorf("invalid value of environment variable %s: %v", name, err)
+			}
+		}
+	}
+	containerPaths := make(map[string]bool)
+	for _, mount := range r.Mounts {
+		if err := mount.Validate(); err != nil {
+			return err
+		}
+		if containerPaths[mount.ContainerPath] {
+			return fmt.Errorf("container path %s is mounted more than once", mount.ContainerPath)
+		}
+		containerPaths[mount.ContainerPath] = true
+	}
+	return nil
+}
+
+// Validate ensures that RuntimeMount has a valid set of values.
+func (m *RuntimeMount) Validate() error {
+	if err := validateMountPath(m.HostPath); err != nil {
+		return fmt.Errorf("invalid host path: %v", err)
+	}
+	if err := validateMountPath(m.ContainerPath); err != nil {
+		return fmt.Errorf("invalid container path: %v", err)
+	}
+	return nil
+}
+
+// validateMountPath ensures that path is an absolute, clean path other than
+// the root directory.
+func validateMountPath(path string) error {
+	switch {
+	case !filepath.IsAbs(path):
+		return fmt.Errorf("%q is not absolute", path)
+	case filepath.Clean(path) != path:
+		return fmt.Errorf("%q is not clean", path)
+	case path == "/":
+		return fmt.Errorf("the root directory cannot be mounted")
+	}
+	return nil
+}
+
+// ParseRuntimeVisibleDevices parses the comma-separated logical device
+// indices of ASCEND_RT_VISIBLE_DEVICES.
+func ParseRuntimeVisibleDevices(value string) ([]int, error) {
+	var indices []int
+	for _, field := range strings.Split(value, ",") {
+		index, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("%q is not a device index", field)
+		}
+		if slices.Contains(indices, index) {
+			return nil, fmt.Errorf("device index %d is listed more than once", index)
+		}
+		indices = append(indices, index)
+	}
+	return indices, nil
+}
+
+func validateSeconds(value string) error {
+	if seconds, err := strconv.Atoi(value); err != nil || seconds < 0 {
+		return fmt.Errorf("%q is not a number of seconds", value)
+	}
+	return nil
+}
+
+func validateLogLevel(value string) error {
+	if level, err := strconv.Atoi(value); err != nil || level < 0 || level > 4 {
+		return fmt.Errorf("%q is not a log level from 0 (debug) to 4 (null)", value)
+	}
+	return nil
+}
+
+func validateSwitch(value string) error {
+	if value != "0" && value != "1" {
+		return fmt.Errorf("%q is neither 0 nor 1", value)
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package npu
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNpuConfigValidate(t *testing.T) {
+	tests := map[string]struct {
+		npuConfig *NpuConfig
+		expected  error
+	}{
+		"empty NpuConfig": {
+			npuConfig: &NpuConfig{},
+			expected:  errors.New("no sharing strategy set"),
+		},
+		"unknown NPU sharing strategy": {
+			npuConfig: &NpuConfig{
+				Sharing: &NpuSharing{
+					Strategy: "unknown",
+				},
+			},
+			expected: errors.New("unknown NPU sharing strategy: unknown"),
+		},
+		"default NpuConfig": {
+			npuConfig: DefaultNpuConfig(),
+			expected:  nil,
+		},
+		"empty Vnpu": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Vnpu:    &VnpuConfig{},
+			},
+			expected: errors.New("vNPU template name or resources are required"),
+		},
+		"negative Vnpu.Resources.Aicore": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Vnpu:    &VnpuConfig{Resources: &VnpuResources{Aicore: -1}},
+			},
+			expected: errors.New("invalid vNPU AI Core count: -1"),
+		},
+		"negative Vnpu.Resources.MemoryGiB": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Vnpu:    &VnpuConfig{Resources: &VnpuResources{MemoryGiB: -1}},
+			},
+			expected: errors.New("invalid vNPU memory size: -1"),
+		},
+		"valid Vnpu with template": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Vnpu:    &VnpuConfig{Template: "vir02"},
+			},
+			expected: nil,
+		},
+		"empty Vnpu.Resources": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Vnpu:    &VnpuConfig{Resources: &VnpuResources{}},
+			},
+			expected: errors.New("vNPU template name or resources are required"),
+		},
+		"negative Vnpu.Resources.Aicpu": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Vnpu:    &VnpuConfig{Resources: &VnpuResources{Aicpu: -1}},
+			},
+			expected: errors.New("invalid vNPU AI CPU count: -1"),
+		},
+		"more AI CPUs than AI Cores": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Vnpu:    &VnpuConfig{Resources: &VnpuResources{Aicore: 2, Aicpu: 3}},
+			},
+			expected: errors.New("vNPU AI CPU count 3 exceeds the AI Core count 2"),
+		},
+		"valid Vnpu with DVPP only": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Vnpu:    &VnpuConfig{Resources: &VnpuResources{Dvpp: true}},
+			},
+			expected: nil,
+		},
+		"Vnpu with template and resources": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Vnpu:    &VnpuConfig{Template: "vir02", Resources: &VnpuResources{Aicore: 2}},
+			},
+			expected: errors.New(`vNPU template "vir02" and resources are mutually exclusive`),
+		},
+		"valid Vnpu with resources": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Vnpu:    &VnpuConfig{Resources: &VnpuResources{Aicore: 4, MemoryGiB: 8}},
+			},
+			expected: nil,
+		},
+		"ignored time-slice interval": {
+			npuConfig: &NpuConfig{
+				Sharing: &NpuSharing{
+					Strategy:          TimeSlicingStrategy,
+					TimeSlicingConfig: &TimeSlicingConfig{Interval: LongTimeSlice},
+				},
+			},
+			expected: nil,
+		},
+		"unsupported partition count": {
+			npuConfig: &NpuConfig{
+				Sharing: &NpuSharing{
+					Strategy:                SpacePartitioningStrategy,
+					SpacePartitioningConfig: &SpacePartitioningConfig{PartitionCount: 3},
+				},
+			},
+			expected: errors.New("invalid partition count: 3, Ascend NPUs can be partitioned into 1, 2, 4, 8 or 16 vNPUs"),
+		},
+		"valid partition count": {
+			npuConfig: &NpuConfig{
+				Sharing: &NpuSharing{
+					Strategy:                SpacePartitioningStrategy,
+					SpacePartitioningConfig: &SpacePartitioningConfig{PartitionCount: 4},
+				},
+			},
+			expected: nil,
+		},
+		"Vnpu with partitions": {
+			npuConfig: &NpuConfig{
+				Sharing: &NpuSharing{
+					Strategy:                SpacePartitioningStrategy,
+					SpacePartitioningConfig: &SpacePartitioningConfig{PartitionCount: 2},
+				},
+				Vnpu: &VnpuConfig{Template: "vir02"},
+			},
+			expected: errors.New("a vNPU and partitioning the NPU into 2 vNPUs are mutually exclusive"),
+		},
+		"valid Vnpu with a single partition": {
+			npuConfig: &NpuConfig{
+				Sharing: &NpuSharing{
+					Strategy:                SpacePartitioningStrategy,
+					SpacePartitioningConfig: &SpacePartitioningConfig{PartitionCount: 1},
+				},
+				Vnpu: &VnpuConfig{Template: "vir02"},
+			},
+			expected: nil,
+		},
+		"negative Hccl.ConnectTimeoutSeconds": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Hccl:    &HcclConfig{ConnectTimeoutSeconds: -1},
+			},
+			expected: errors.New("invalid HCCL connect timeout: -1"),
+		},
+		"negative Hccl.ExecTimeoutSeconds": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Hccl:    &HcclConfig{ExecTimeoutSeconds: -1},
+			},
+			expected: errors.New("invalid HCCL exec timeout: -1"),
+		},
+		"valid Hccl": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Hccl:    &HcclConfig{ConnectTimeoutSeconds: 600, ExecTimeoutSeconds: 1800, SocketIfname: "eth0"},
+			},
+			expected: nil,
+		},
+		"invalid Runtime.Env name": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Runtime: &RuntimeConfig{Env: map[string]string{"ASCEND-LOG": "1"}},
+			},
+			expected: errors.New(`invalid environment variable name: "ASCEND-LOG"`),
+		},
+		"multi-line Runtime.Env value": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Runtime: &RuntimeConfig{Env: map[string]string{"HCCL_SOCKET_IFNAME": "eth0\nLD_PRELOAD=x"}},
+			},
+			expected: errors.New(`invalid value of environment variable HCCL_SOCKET_IFNAME: "eth0\nLD_PRELOAD=x"`),
+		},
+		"invalid ASCEND_GLOBAL_LOG_LEVEL": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Runtime: &RuntimeConfig{Env: map[string]string{"ASCEND_GLOBAL_LOG_LEVEL": "5"}},
+			},
+			expected: errors.New(`invalid value of environment variable ASCEND_GLOBAL_LOG_LEVEL: "5" is not a log level from 0 (debug) to 4 (null)`),
+		},
+		"invalid ASCEND_RT_VISIBLE_DEVICES": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Runtime: &RuntimeConfig{Env: map[string]string{"ASCEND_RT_VISIBLE_DEVICES": "1,1"}},
+			},
+			expected: errors.New("invalid value of environment variable ASCEND_RT_VISIBLE_DEVICES: device index 1 is listed more than once"),
+		},
+		"Runtime.Env conflicting with Hccl": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Hccl:    &HcclConfig{ConnectTimeoutSeconds: 600},
+				Runtime: &RuntimeConfig{Env: map[string]string{"HCCL_CONNECT_TIMEOUT": "300"}},
+			},
+			expected: errors.New("HCCL_CONNECT_TIMEOUT is set by both the HCCL and the runtime settings"),
+		},
+		"relative Runtime.Mounts host path": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Runtime: &RuntimeConfig{Mounts: []RuntimeMount{{HostPath: "profiling", ContainerPath: "/profiling"}}},
+			},
+			expected: errors.New(`invalid host path: "profiling" is not absolute`),
+		},
+		"unclean Runtime.Mounts container path": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Runtime: &RuntimeConfig{Mounts: []RuntimeMount{{HostPath: "/var/log/npu", ContainerPath: "/profiling/../etc"}}},
+			},
+			expected: errors.New(`invalid container path: "/profiling/../etc" is not clean`),
+		},
+		"Runtime.Mounts sharing a container path": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Runtime: &RuntimeConfig{Mounts: []RuntimeMount{
+					{HostPath: "/var/log/npu/a", ContainerPath: "/profiling"},
+					{HostPath: "/var/log/npu/b", ContainerPath: "/profiling"},
+				}},
+			},
+			expected: errors.New("container path /profiling is mounted more than once"),
+		},
+		"valid Runtime": {
+			npuConfig: &NpuConfig{
+				Sharing: DefaultNpuConfig().Sharing,
+				Hccl:    &HcclConfig{SocketIfname: "eth0"},
+				Runtime: &RuntimeConfig{
+					Env: map[string]string{
+						"HCCL_CONNECT_TIMEOUT":      "600",
+						"ASCEND_GLOBAL_LOG_LEVEL":   "1",
+						"ASCEND_RT_VISIBLE_DEVICES": "1,0",
+					},
+					Mounts: []RuntimeMount{{HostPath: "/var/log/npu/profiling", ContainerPath: "/profiling"}},
+				},
+			},
+			expected: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.npuConfig.Validate()
+			assert.Equal(t, test.expected, err)
+		})
+	}
+}
@@ -0,0 +1,272 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/yaml"
+
+	configapi "Ascend-dra-driver/api/example.com/resource/npu"
+	configinstall "Ascend-dra-driver/api/example.com/resource/npu/install"
+	configv1alpha2 "Ascend-dra-driver/api/example.com/resource/npu/v1alpha2"
+	"Ascend-dra-driver/pkg/common"
+)
+
+// ClassFamilyKind determines which DeviceClasses a family generates.
+type ClassFamilyKind string
+
+const (
+	// WholeCardFamily generates one DeviceClass per model for whole cards.
+	WholeCardFamily ClassFamilyKind = "WholeCard"
+	// MemoryFamily generates one DeviceClass per model and distinct memory
+	// size of its vNPU templates, requesting a vNPU with at least that memory.
+	MemoryFamily ClassFamilyKind = "Memory"
+	// AICoreFamily generates one DeviceClass per model and distinct AI Core
+	// count of its vNPU templates, requesting a vNPU with at least that many
+	// AI Cores.
+	AICoreFamily ClassFamilyKind = "AICore"
+	// TemplateFamily generates one DeviceClass per model and vNPU template,
+	// requesting a vNPU of exactly that template.
+	TemplateFamily ClassFamilyKind = "Template"
+)
+
+// ClassDefinitions is the content of the file describing the DeviceClasses
+// the controller generates.
+type ClassDefinitions struct {
+	Families []ClassFamily `json:"families"`
+}
+
+// ClassFamily describes a set of generated DeviceClasses. Name, Selectors and
+// the values of Labels and Annotations are Go templates executed with a
+// classTemplateData.
+type ClassFamily struct {
+	Kind ClassFamilyKind `json:"kind"`
+	// Models restricts the family to the models matching any of these
+	// path.Match patterns, e.g. 910B*. All models are used if it is empty.
+	Models []string `json:"models,omitempty"`
+	// Name is the name of a generated DeviceClass. No DeviceClass is
+	// generated if it renders to an empty string.
+	Name string `json:"name"`
+	// Selectors are CEL expressions, all of which devices have to match.
+	Selectors   []string          `json:"selectors"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Sharing is added to the config of the generated DeviceClasses.
+	Sharing *configv1alpha2.NpuSharing `json:"sharing,omitempty"`
+
+	name        *template.Template
+	selectors   []*template.Template
+	labels      map[string]*template.Template
+	annotations map[string]*template.Template
+}
+
+// classTemplateData is what the templates of a ClassFamily are executed with.
+type classTemplateData struct {
+	// Model is the NPU model as published by the kubelet plugins.
+	Model string
+	// SafeModel is Model in lower case, usable in names.
+	SafeModel string
+	// Memory is the memory size in GiB of a Memory family class, or of the
+	// template of a Template family class.
+	Memory int
+	// AICore is the AI Core count of an AICore family class, or of the
+	// template of a Template family class.
+	AICore int
+	// Template is the vNPU template of a Template family class.
+	Template string
+	// SafeTemplate is Template in lower case with underscores replaced by
+	// dashes, usable in names.
+	SafeTemplate string
+}
+
+// defaultClassDefinitions are the DeviceClasses generated without a class
+// definition file.
+func defaultClassDefinitions() *ClassDefinitions {
+	byModel := `device.attributes["` + DriverName + `"].model == "{{ .Model }}"`
+	hostable := `device.attributes["` + common.TemplateHostableAttributeDomain + `"]`
+	defs := &ClassDefinitions{
+		Families: []ClassFamily{
+			{
+				Kind:      WholeCardFamily,
+				Name:      "npu-{{ .SafeModel }}.example.com",
+				Selectors: []string{byModel + ` && device.attributes["` + DriverName + `"].type == "NPU"`},
+			},
+			{
+				Kind:      MemoryFamily,
+				Name:      "npu-{{ .SafeModel }}-mem{{ .Memory }}.example.com",
+				Selectors: []string{`device.attributes["` + DriverName + `"].memory >= {{ .Memory }} && ` + byModel},
+			},
+			{
+				Kind:      AICoreFamily,
+				Name:      "npu-{{ .SafeModel }}-aicore{{ .AICore }}.example.com",
+				Selectors: []string{`device.attributes["` + DriverName + `"].aicore >= {{ .AICore }} && ` + byModel},
+			},
+			{
+				Kind:      TemplateFamily,
+				Name:      "npu-{{ .SafeModel }}-{{ .SafeTemplate }}.example.com",
+				Selectors: []string{byModel + ` && has(` + hostable + `.{{ .Template }}) && ` + hostable + `.{{ .Template }}`},
+			},
+		},
+	}
+	utilruntime.Must(defs.compile())
+	return defs
+}
+
+// loadClassDefinitions reads the class definitions from path, or returns the
+// default ones if path is empty.
+func loadClassDefinitions(path string) (*ClassDefinitions, error) {
+	if path == "" {
+		return defaultClassDefinitions(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read DeviceClass definitions: %w", err)
+	}
+	defs := &ClassDefinitions{}
+	if err := yaml.UnmarshalStrict(data, defs); err != nil {
+		return nil, fmt.Errorf("parse DeviceClass definitions %s: %w", path, err)
+	}
+	if err := defs.compile(); err != nil {
+		return nil, fmt.Errorf("invalid DeviceClass definitions: %w", err)
+	}
+	return defs, nil
+}
+
+// compile parses the templates of all families and validates them.
+func (d *ClassDefinitions) compile() error {
+	if len(d.Families) == 0 {
+		return fmt.Errorf("no class families defined")
+	}
+	for i := range d.Families {
+		if err := d.Families[i].compile(); err != nil {
+			return fmt.Errorf("families[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (f *ClassFamily) compile() error {
+	switch f.Kind {
+	case WholeCardFamily, MemoryFamily, AICoreFamily, TemplateFamily:
+	default:
+		return fmt.Errorf("unknown kind %q", f.Kind)
+	}
+	for _, pattern := range f.Models {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid model pattern %q: %w", pattern, err)
+		}
+	}
+	if len(f.Selectors) == 0 {
+		return fmt.Errorf("at least one selector is required")
+	}
+
+	var err error
+	parse := func(field, text string) *template.Template {
+		if err != nil {
+			return nil
+		}
+		var t *template.Template
+		t, err = template.New(field).Option("missingkey=error").Parse(text)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", field, err)
+		}
+		return t
+	}
+	f.name = parse("name", f.Name)
+	f.selectors = make([]*template.Template, len(f.Selectors))
+	for i, selector := range f.Selectors {
+		f.selectors[i] = parse(fmt.Sprintf("selectors[%d]", i), selector)
+	}
+	f.labels = make(map[string]*template.Template, len(f.Labels))
+	for key, value := range f.Labels {
+		f.labels[key] = parse("labels."+key, value)
+	}
+	f.annotations = make(map[string]*template.Template, len(f.Annotations))
+	for key, value := range f.Annotations {
+		f.annotations[key] = parse("annotations."+key, value)
+	}
+	if err != nil {
+		return err
+	}
+	if f.Labels[managedByLabel] != "" {
+		return fmt.Errorf("label %s is reserved", managedByLabel)
+	}
+
+	// The sharing settings are validated like those of a user-provided config.
+	if _, err := f.config(nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// appliesTo reports whether the family generates DeviceClasses for model.
+func (f *ClassFamily) appliesTo(model string) bool {
+	if len(f.Models) == 0 {
+		return true
+	}
+	for _, pattern := range f.Models {
+		if ok, _ := path.Match(pattern, model); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// config returns the opaque config parameters of the family's DeviceClasses
+// requesting vnpu, or nil if they need no config.
+func (f *ClassFamily) config(vnpu *configv1alpha2.VnpuConfig) ([]byte, error) {
+	if vnpu == nil && f.Sharing == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(&configv1alpha2.NpuConfig{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: configv1alpha2.SchemeGroupVersion.String(),
+			Kind:       configv1alpha2.NpuConfigKind,
+		},
+		Vnpu:    vnpu,
+		Sharing: f.Sharing,
+	})
+	if err != nil {
+		return nil, err
+	}
+	decoded, _, err := configinstall.Decoder.Decode(raw, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decode config: %w", err)
+	}
+	if err := decoded.(*configapi.NpuConfig).Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return raw, nil
+}
+
+// execute renders t with data.
+func execute(t *template.Template, data classTemplateData) (string, error) {
+	var out bytes.Buffer
+	if err := t.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	configapi "Ascend-dra-driver/api/example.com/resource/npu"
+)
+
+// writeClassDefinitions writes content to a class definition file.
+func writeClassDefinitions(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "deviceclasses.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadClassDefinitions(t *testing.T) {
+	path := writeClassDefinitions(t, `
+families:
+- kind: Memory
+  models: ["910B*"]
+  name: |
+    {{ if eq .Memory 32 }}ascend-{{ .SafeModel }}-half{{ end }}
+  selectors:
+  - device.attributes["npu.example.com"].model == "{{ .Model }}"
+  - device.attributes["npu.example.com"].memory >= {{ .Memory }}
+  labels:
+    team: ml
+  annotations:
+    example.com/memory: "{{ .Memory }}Gi"
+  sharing:
+    strategy: TimeSlicing
+    timeSlicingConfig:
+      interval: Long
+- kind: WholeCard
+  name: ascend-{{ .SafeModel }}
+  selectors:
+  - device.attributes["npu.example.com"].model == "{{ .Model }}"
+`)
+	defs, err := loadClassDefinitions(path)
+	require.NoError(t, err)
+
+	classes, err := desiredDeviceClasses(modelTemplates{
+		"910B":  {"vir05_1c_16g": {AICore: 5, Memory: 16}, "vir10_3c_32g": {AICore: 10, Memory: 32}},
+		"310P3": {"vir02": vir02},
+	}, defs)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ascend-310p3", "ascend-910b", "ascend-910b-half"}, slices.Sorted(maps.Keys(classes)))
+
+	half := classes["ascend-910b-half"]
+	assert.Equal(t, map[string]string{managedByLabel: managedByValue, "team": "ml"}, half.Labels)
+	assert.Equal(t, "32Gi", half.Annotations["example.com/memory"])
+	require.Len(t, half.Spec.Selectors, 2)
+	assert.Equal(t, `device.attributes["npu.example.com"].memory >= 32`, half.Spec.Selectors[1].CEL.Expression)
+	require.Len(t, half.Spec.Config, 1)
+	decoded, err := decodeConfig(half.Spec.Config[0].Opaque.Parameters.Raw)
+	require.NoError(t, err)
+	assert.Equal(t, &configapi.VnpuResources{MemoryGiB: 32}, decoded.Vnpu.Resources)
+	assert.Equal(t, configapi.TimeSlicingStrategy, decoded.Sharing.Strategy)
+	assert.Equal(t, configapi.LongTimeSlice, decoded.Sharing.TimeSlicingConfig.Interval)
+
+	// Whole cards without sharing settings need no config.
+	assert.Empty(t, classes["ascend-910b"].Spec.Config)
+}
+
+func TestLoadClassDefinitionsErrors(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		err     string
+	}{
+		"no families": {
+			content: `families: []`,
+			err:     "no class families defined",
+		},
+		"unknown field": {
+			content: `{"families": [{"kind": "WholeCard", "name": "a", "selectors": ["true"], "selector": "true"}]}`,
+			err:     `unknown field "selector"`,
+		},
+		"unknown kind": {
+			content: `{"families": [{"kind": "Partition", "name": "a", "selectors": ["true"]}]}`,
+			err:     `families[0]: unknown kind "Partition"`,
+		},
+		"bad model pattern": {
+			content: `{"families": [{"kind": "WholeCard", "models": ["910["], "name": "a", "selectors": ["true"]}]}`,
+			err:     `invalid model pattern "910["`,
+		},
+		"no selectors": {
+			content: `{"families": [{"kind": "WholeCard", "name": "a"}]}`,
+			err:     "at least one selector is required",
+		},
+		"bad template": {
+			content: `{"families": [{"kind": "WholeCard", "name": "a", "selectors": ["{{ .Model "]}]}`,
+			err:     "selectors[0]",
+		},
+		"reserved label": {
+			content: `{"families": [{"kind": "WholeCard", "name": "a", "selectors": ["true"], "labels": {"app.kubernetes.io/managed-by": "me"}}]}`,
+			err:     "label app.kubernetes.io/managed-by is reserved",
+		},
+		"invalid sharing": {
+			content: `{"families": [{"kind": "WholeCard", "name": "a", "selectors": ["true"], "sharing": {"strategy": "Exclusive"}}]}`,
+			err:     "invalid config",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadClassDefinitions(writeClassDefinitions(t, test.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
+func TestDesiredDeviceClassesErrors(t *testing.T) {
+	models := modelTemplates{"310P3": {"vir01": vir01, "vir02": vir02}}
+	tests := map[string]struct {
+		family ClassFamily
+		err    string
+	}{
+		"name not unique": {
+			family: ClassFamily{Kind: MemoryFamily, Name: "npu-{{ .SafeModel }}", Selectors: []string{"true"}},
+			err:    "DeviceClass npu-310p3 is generated more than once",
+		},
+		"invalid name": {
+			family: ClassFamily{Kind: WholeCardFamily, Name: "NPU {{ .Model }}", Selectors: []string{"true"}},
+			err:    `invalid name "NPU 310P3"`,
+		},
+		"unknown field": {
+			family: ClassFamily{Kind: WholeCardFamily, Name: "npu-{{ .Chip }}", Selectors: []string{"true"}},
+			err:    "can't evaluate field Chip",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			defs := &ClassDefinitions{Families: []ClassFamily{test.family}}
+			require.NoError(t, defs.compile())
+			_, err := desiredDeviceClasses(models, defs)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
@@ -0,0 +1,317 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	coreclientset "k8s.io/client-go/kubernetes"
+	resourcelisters "k8s.io/client-go/listers/resource/v1beta1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+)
+
+// reconcileKey is the only key in the work queue: all DeviceClasses are
+// derived from all ResourceSlices at once.
+const reconcileKey = "deviceclasses"
+
+// StaleClassPolicy is what happens to generated DeviceClasses that are no
+// longer backed by any published device.
+type StaleClassPolicy string
+
+const (
+	// StaleClassDelete deletes stale DeviceClasses once they have been
+	// deprecated for the grace period.
+	StaleClassDelete StaleClassPolicy = "Delete"
+	// StaleClassDeprecate only marks stale DeviceClasses as deprecated.
+	StaleClassDeprecate StaleClassPolicy = "Deprecate"
+)
+
+// ControllerConfig configures a Controller.
+type ControllerConfig struct {
+	// ResyncPeriod is how often generated DeviceClasses are reconciled
+	// without changes. Zero disables resyncs.
+	ResyncPeriod time.Duration
+	// StaleClassPolicy is applied to generated DeviceClasses no longer
+	// backed by any device.
+	StaleClassPolicy StaleClassPolicy
+	// StaleClassGracePeriod is how long stale DeviceClasses stay deprecated
+	// before they are deleted, so that they survive kubelet plugin restarts.
+	StaleClassGracePeriod time.Duration
+	// ClassDefinitions describes the DeviceClasses to generate.
+	ClassDefinitions *ClassDefinitions
+}
+
+// Controller keeps the generated DeviceClasses in line with the NPU models
+// and vNPU templates published in the ResourceSlices of the driver.
+type Controller struct {
+	config      ControllerConfig
+	client      coreclientset.Interface
+	clock       clock.PassiveClock
+	factories   []informers.SharedInformerFactory
+	sliceLister resourcelisters.ResourceSliceLister
+	classLister resourcelisters.DeviceClassLister
+	cacheSynced []cache.InformerSynced
+	queue       workqueue.TypedRateLimitingInterface[string]
+}
+
+// NewController creates a controller using client.
+func NewController(client coreclientset.Interface, config ControllerConfig) *Controller {
+	sliceFactory := informers.NewSharedInformerFactoryWithOptions(client, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("spec.driver", DriverName).String()
+		}))
+	classFactory := informers.NewSharedInformerFactoryWithOptions(client, config.ResyncPeriod,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = labels.SelectorFromSet(labels.Set{managedByLabel: managedByValue}).String()
+		}))
+	sliceInformer := sliceFactory.Resource().V1beta1().ResourceSlices()
+	classInformer := classFactory.Resource().V1beta1().DeviceClasses()
+
+	c := &Controller{
+		config:      config,
+		client:      client,
+		clock:       clock.RealClock{},
+		factories:   []informers.SharedInformerFactory{sliceFactory, classFactory},
+		sliceLister: sliceInformer.Lister(),
+		classLister: classInformer.Lister(),
+		cacheSynced: []cache.InformerSynced{sliceInformer.Informer().HasSynced, classInformer.Informer().HasSynced},
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "deviceclasses"},
+		),
+	}
+
+	enqueue := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { c.queue.Add(reconcileKey) },
+		UpdateFunc: func(any, any) { c.queue.Add(reconcileKey) },
+		DeleteFunc: func(any) { c.queue.Add(reconcileKey) },
+	}
+	_, _ = sliceInformer.Informer().AddEventHandler(enqueue)
+	_, _ = classInformer.Informer().AddEventHandler(enqueue)
+	return c
+}
+
+// Run reconciles DeviceClasses until ctx is done.
+func (c *Controller) Run(ctx context.Context) {
+	logger := klog.FromContext(ctx)
+	defer c.queue.ShutDown()
+
+	for _, factory := range c.factories {
+		factory.Start(ctx.Done())
+		defer factory.Shutdown()
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), c.cacheSynced...) {
+		return
+	}
+	logger.Info("Managing DeviceClasses")
+	if err := c.adoptLegacyClasses(ctx); err != nil {
+		logger.Error(err, "Failed to adopt DeviceClasses generated by earlier versions")
+	}
+
+	go func() {
+		<-ctx.Done()
+		c.queue.ShutDown()
+	}()
+	c.queue.Add(reconcileKey)
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextItem(ctx context.Context) bool {
+	key, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.reconcile(ctx); err != nil {
+		klog.FromContext(ctx).Error(err, "Failed to reconcile DeviceClasses, will retry")
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+// adoptLegacyClasses labels the DeviceClasses generated by earlier versions of
+// the kubelet plugin, so that they are updated or retired like the ones the
+// controller generates.
+func (c *Controller) adoptLegacyClasses(ctx context.Context) error {
+	logger := klog.FromContext(ctx)
+	classes := c.client.ResourceV1beta1().DeviceClasses()
+	list, err := classes.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("list DeviceClasses: %w", err)
+	}
+	var errs []error
+	for i := range list.Items {
+		class := &list.Items[i]
+		if !isLegacyDeviceClass(class) {
+			continue
+		}
+		if class.Labels == nil {
+			class.Labels = make(map[string]string)
+		}
+		class.Labels[managedByLabel] = managedByValue
+		if _, err := classes.Update(ctx, class, metav1.UpdateOptions{}); err != nil {
+			errs = append(errs, fmt.Errorf("adopt DeviceClass %s: %w", class.Name, err))
+			continue
+		}
+		logger.Info("Adopted DeviceClass", "deviceClass", class.Name)
+	}
+	return errors.Join(errs...)
+}
+
+// reconcile creates and updates the DeviceClasses of the models and templates
+// currently published, and retires generated DeviceClasses of models and
+// templates that are gone.
+func (c *Controller) reconcile(ctx context.Context) error {
+	logger := klog.FromContext(ctx)
+
+	resourceSlices, err := c.sliceLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("list ResourceSlices: %w", err)
+	}
+	desired, err := desiredDeviceClasses(collectModelTemplates(logger, resourceSlices), c.config.ClassDefinitions)
+	if err != nil {
+		return err
+	}
+	existing, err := c.classLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("list DeviceClasses: %w", err)
+	}
+	existingByName := make(map[string]*resourceapi.DeviceClass, len(existing))
+	for _, class := range existing {
+		existingByName[class.Name] = class
+	}
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(desired)) {
+		if err := c.apply(ctx, desired[name], existingByName[name]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(existingByName)) {
+		if _, ok := desired[name]; ok {
+			continue
+		}
+		retryAfter, err := c.retire(ctx, existingByName[name])
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if retryAfter > 0 {
+			c.queue.AddAfter(reconcileKey, retryAfter)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// retire deprecates a stale DeviceClass, and deletes it once it has been
+// deprecated for the grace period if the policy says so. Until then, it
+// returns how long is left of the grace period.
+func (c *Controller) retire(ctx context.Context, class *resourceapi.DeviceClass) (time.Duration, error) {
+	logger := klog.FromContext(ctx)
+	classes := c.client.ResourceV1beta1().DeviceClasses()
+	now := c.clock.Now()
+
+	deprecatedSince, err := time.Parse(time.RFC3339, class.Annotations[deprecatedSinceAnnotation])
+	immediately := c.config.StaleClassPolicy == StaleClassDelete && c.config.StaleClassGracePeriod <= 0
+	if err != nil && !immediately {
+		deprecatedSince = now
+		updated := class.DeepCopy()
+		if updated.Annotations == nil {
+			updated.Annotations = make(map[string]string)
+		}
+		updated.Annotations[deprecatedSinceAnnotation] = now.UTC().Format(time.RFC3339)
+		if _, err := classes.Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+			return 0, fmt.Errorf("deprecate DeviceClass %s: %w", class.Name, err)
+		}
+		logger.Info("Deprecated DeviceClass that is no longer backed by any device", "deviceClass", class.Name)
+	}
+	if c.config.StaleClassPolicy != StaleClassDelete {
+		return 0, nil
+	}
+
+	if remaining := deprecatedSince.Add(c.config.StaleClassGracePeriod).Sub(now); !immediately && remaining > 0 {
+		return remaining, nil
+	}
+	err = classes.Delete(ctx, class.Name, metav1.DeleteOptions{})
+	switch {
+	case err == nil:
+		logger.Info("Deleted DeviceClass", "deviceClass", class.Name)
+	case !apierrors.IsNotFound(err):
+		return 0, fmt.Errorf("delete DeviceClass %s: %w", class.Name, err)
+	}
+	return 0, nil
+}
+
+// apply creates want, or updates current to want. A DeviceClass of the same
+// name that the controller does not know about yet, such as one created by an
+// earlier version of the driver, is taken over.
+func (c *Controller) apply(ctx context.Context, want, current *resourceapi.DeviceClass) error {
+	logger := klog.FromContext(ctx)
+	classes := c.client.ResourceV1beta1().DeviceClasses()
+
+	if current == nil {
+		_, err := classes.Create(ctx, want, metav1.CreateOptions{})
+		if err == nil {
+			logger.Info("Created DeviceClass", "deviceClass", want.Name)
+			return nil
+		}
+		if !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("create DeviceClass %s: %w", want.Name, err)
+		}
+		current, err = classes.Get(ctx, want.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("get DeviceClass %s: %w", want.Name, err)
+		}
+	}
+	if deviceClassEquals(current, want) {
+		return nil
+	}
+
+	updated := current.DeepCopy()
+	if updated.Labels == nil {
+		updated.Labels = make(map[string]string)
+	}
+	maps.Copy(updated.Labels, want.Labels)
+	if updated.Annotations == nil {
+		updated.Annotations = make(map[string]string)
+	}
+	maps.Copy(updated.Annotations, want.Annotations)
+	delete(updated.Annotations, deprecatedSinceAnnotation)
+	updated.Spec = want.Spec
+	if _, err := classes.Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("update DeviceClass %s: %w", want.Name, err)
+	}
+	logger.Info("Updated DeviceClass", "deviceClass", want.Name)
+	return nil
+}
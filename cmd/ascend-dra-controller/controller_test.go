@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resourceapi "k8s.io/api/resource/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/klog/v2/ktesting"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"Ascend-dra-driver/pkg/common"
+)
+
+// classNames returns the names of all DeviceClasses, and the names of the
+// generated ones.
+func classNames(t *testing.T, client *fake.Clientset) ([]string, []string) {
+	list, err := client.ResourceV1beta1().DeviceClasses().List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	var all, generated []string
+	for _, class := range list.Items {
+		all = append(all, class.Name)
+		if class.Labels[managedByLabel] == managedByValue {
+			generated = append(generated, class.Name)
+		}
+	}
+	slices.Sort(all)
+	slices.Sort(generated)
+	return all, generated
+}
+
+func TestController(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Classes created by an earlier version of the driver, one with an
+	// outdated selector and one that is stale, and a class the controller
+	// has nothing to do with.
+	legacy := legacyDeviceClass("npu-310p3.example.com", `device.attributes["npu.example.com"].model == "310P"`, "")
+	stale := legacyDeviceClass("npu-310p3-mem99.example.com", `device.attributes["npu.example.com"].model == "310P3"`, "vir99")
+	foreign := &resourceapi.DeviceClass{ObjectMeta: metav1.ObjectMeta{Name: "gpu.example.com"}}
+
+	client := fake.NewSimpleClientset(legacy, stale, foreign,
+		resourceSlice("node-1", DriverName,
+			npuDevice("npu-0-0", "310P3", map[string]common.TemplateShape{"vir01": vir01, "vir02": vir02}),
+		),
+	)
+	controller := NewController(client, ControllerConfig{
+		StaleClassPolicy: StaleClassDelete,
+		ClassDefinitions: defaultClassDefinitions(),
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		controller.Run(ctx)
+	}()
+
+	expected := []string{
+		"npu-310p3-aicore1.example.com",
+		"npu-310p3-aicore2.example.com",
+		"npu-310p3-mem3.example.com",
+		"npu-310p3-mem6.example.com",
+		"npu-310p3-vir01.example.com",
+		"npu-310p3-vir02.example.com",
+		"npu-310p3.example.com",
+	}
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		all, generated := classNames(t, client)
+		assert.Equal(c, expected, generated)
+		assert.ElementsMatch(c, append(slices.Clone(expected), "gpu.example.com"), all)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	adopted, err := client.ResourceV1beta1().DeviceClasses().Get(ctx, "npu-310p3.example.com", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, adopted.Spec.Selectors[0].CEL.Expression, `.model == "310P3"`)
+	assert.NotEmpty(t, adopted.Annotations[sourceHashAnnotation])
+
+	// A new model shows up and the old one goes away.
+	_, err = client.ResourceV1beta1().ResourceSlices().Create(ctx, resourceSlice("node-2", DriverName,
+		npuDevice("npu-0-0", "910", map[string]common.TemplateShape{"vir04": vir04}),
+	), metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, client.ResourceV1beta1().ResourceSlices().Delete(ctx, "node-1", metav1.DeleteOptions{}))
+
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		all, generated := classNames(t, client)
+		assert.Equal(c, []string{
+			"npu-910-aicore4.example.com",
+			"npu-910-mem12.example.com",
+			"npu-910-vir04.example.com",
+			"npu-910.example.com",
+		}, generated)
+		assert.Contains(c, all, "gpu.example.com")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Manual edits of generated classes are reverted.
+	class, err := client.ResourceV1beta1().DeviceClasses().Get(ctx, "npu-910.example.com", metav1.GetOptions{})
+	require.NoError(t, err)
+	want := class.Spec.DeepCopy()
+	class.Spec.Selectors = nil
+	_, err = client.ResourceV1beta1().DeviceClasses().Update(ctx, class, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		class, err := client.ResourceV1beta1().DeviceClasses().Get(ctx, "npu-910.example.com", metav1.GetOptions{})
+		if assert.NoError(c, err) {
+			assert.Equal(c, *want, class.Spec)
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestRetire(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		config          ControllerConfig
+		deprecatedSince string
+		elapsed         time.Duration
+		expectDeleted   bool
+		expectRetry     time.Duration
+	}{
+		"deleted right away": {
+			config:        ControllerConfig{StaleClassPolicy: StaleClassDelete},
+			expectDeleted: true,
+		},
+		"deprecated first": {
+			config:      ControllerConfig{StaleClassPolicy: StaleClassDelete, StaleClassGracePeriod: time.Hour},
+			expectRetry: time.Hour,
+		},
+		"still in the grace period": {
+			config:          ControllerConfig{StaleClassPolicy: StaleClassDelete, StaleClassGracePeriod: time.Hour},
+			deprecatedSince: now.Add(-30 * time.Minute).Format(time.RFC3339),
+			expectRetry:     30 * time.Minute,
+		},
+		"grace period is over": {
+			config:          ControllerConfig{StaleClassPolicy: StaleClassDelete, StaleClassGracePeriod: time.Hour},
+			deprecatedSince: now.Add(-time.Hour).Format(time.RFC3339),
+			expectDeleted:   true,
+		},
+		"only deprecated": {
+			config:          ControllerConfig{StaleClassPolicy: StaleClassDeprecate},
+			deprecatedSince: now.Add(-24 * time.Hour).Format(time.RFC3339),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, ctx := ktesting.NewTestContext(t)
+			class := legacyDeviceClass("npu-310p3-mem3.example.com", "true", "vir01")
+			class.Labels = map[string]string{managedByLabel: managedByValue}
+			if test.deprecatedSince != "" {
+				class.Annotations = map[string]string{deprecatedSinceAnnotation: test.deprecatedSince}
+			}
+			client := fake.NewSimpleClientset(class)
+			controller := NewController(client, test.config)
+			defer controller.queue.ShutDown()
+			controller.clock = testingclock.NewFakePassiveClock(now)
+
+			retryAfter, err := controller.retire(ctx, class)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectRetry, retryAfter)
+
+			got, err := client.ResourceV1beta1().DeviceClasses().Get(ctx, class.Name, metav1.GetOptions{})
+			if test.expectDeleted {
+				assert.True(t, apierrors.IsNotFound(err), "expected deletion, got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			expectedSince := test.deprecatedSince
+			if expectedSince == "" {
+				expectedSince = now.Format(time.RFC3339)
+			}
+			assert.Equal(t, expectedSince, got.Annotations[deprecatedSinceAnnotation])
+		})
+	}
+}
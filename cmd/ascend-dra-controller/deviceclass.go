@@ -0,0 +1,304 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	resourceapi "k8s.io/api/resource/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+
+	configv1alpha2 "Ascend-dra-driver/api/example.com/resource/npu/v1alpha2"
+	"Ascend-dra-driver/pkg/common"
+)
+
+const (
+	// managedByLabel marks the DeviceClasses generated by the controller,
+	// which are the only ones it updates or deletes.
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "ascend-dra-controller"
+
+	// sourceHashAnnotation holds a hash of the family, model and template or
+	// resource a generated DeviceClass was derived from.
+	sourceHashAnnotation = DriverDomain + "source-hash"
+	// deprecatedSinceAnnotation is set, to an RFC 3339 time, on generated
+	// DeviceClasses that are no longer backed by any published device.
+	deprecatedSinceAnnotation = DriverDomain + "deprecated-since"
+)
+
+// legacyClassName matches the names of the DeviceClasses that earlier
+// versions of the kubelet plugin generated without the managedByLabel.
+var legacyClassName = regexp.MustCompile(`^npu-[a-z0-9.-]+?(-mem[0-9]+|-aicore[0-9]+)?\.example\.com$`)
+
+// modelTemplates maps each NPU model published by the kubelet plugins to the
+// vNPU templates its nodes can carve, by name.
+type modelTemplates map[string]map[string]common.TemplateShape
+
+// collectModelTemplates derives the NPU models and their vNPU templates from
+// the devices the driver publishes in slices. Should nodes disagree on the
+// shape of a template, the smallest one is used so that the result does not
+// depend on the order of slices.
+func collectModelTemplates(logger klog.Logger, slices []*resourceapi.ResourceSlice) modelTemplates {
+	models := make(modelTemplates)
+	for _, slice := range slices {
+		if slice.Spec.Driver != DriverName {
+			continue
+		}
+		for _, device := range slice.Spec.Devices {
+			if device.Basic == nil {
+				continue
+			}
+			model := ""
+			if attr, ok := device.Basic.Attributes[DriverDomain+"model"]; ok && attr.StringValue != nil {
+				model = *attr.StringValue
+			}
+			templates := models[model]
+			if templates == nil {
+				templates = make(map[string]common.TemplateShape)
+				models[model] = templates
+			}
+			for name, attr := range device.Basic.Attributes {
+				domain, template, ok := strings.Cut(string(name), "/")
+				if !ok || domain != common.TemplateAttributeDomain || attr.StringValue == nil {
+					continue
+				}
+				shape, err := common.ParseTemplateShape(*attr.StringValue)
+				if err != nil {
+					logger.Error(err, "Ignoring vNPU template", "slice", slice.Name, "device", device.Name, "template", template)
+					continue
+				}
+				if known, ok := templates[template]; !ok || shape.String() < known.String() {
+					templates[template] = shape
+				}
+			}
+		}
+	}
+	return models
+}
+
+// desiredDeviceClasses returns the DeviceClasses the families of defs generate
+// for models, by name.
+func desiredDeviceClasses(models modelTemplates, defs *ClassDefinitions) (map[string]*resourceapi.DeviceClass, error) {
+	classes := make(map[string]*resourceapi.DeviceClass)
+	add := func(family *ClassFamily, data classTemplateData, vnpu *configv1alpha2.VnpuConfig, source ...string) error {
+		class, err := buildDeviceClass(family, data, vnpu)
+		if err != nil {
+			return fmt.Errorf("%s DeviceClass of model %q: %w", family.Kind, data.Model, err)
+		}
+		if class == nil {
+			return nil
+		}
+		if _, ok := classes[class.Name]; ok {
+			return fmt.Errorf("DeviceClass %s is generated more than once, its name has to depend on the model and the family", class.Name)
+		}
+		class.Annotations[sourceHashAnnotation] = sourceHash(append([]string{string(family.Kind), data.Model}, source...)...)
+		classes[class.Name] = class
+		return nil
+	}
+
+	for _, model := range slices.Sorted(maps.Keys(models)) {
+		templates := models[model]
+		data := classTemplateData{Model: model, SafeModel: toSafeModelName(model)}
+		for i := range defs.Families {
+			family := &defs.Families[i]
+			if !family.appliesTo(model) {
+				continue
+			}
+			switch family.Kind {
+			case WholeCardFamily:
+				if err := add(family, data, nil); err != nil {
+					return nil, err
+				}
+			case MemoryFamily:
+				for _, memory := range distinct(templates, func(shape common.TemplateShape) int { return shape.Memory }) {
+					data := data
+					data.Memory = memory
+					vnpu := &configv1alpha2.VnpuConfig{Resources: &configv1alpha2.VnpuResources{MemoryGiB: memory}}
+					if err := add(family, data, vnpu, fmt.Sprint(memory)); err != nil {
+						return nil, err
+					}
+				}
+			case AICoreFamily:
+				for _, aicore := range distinct(templates, func(shape common.TemplateShape) int { return shape.AICore }) {
+					data := data
+					data.AICore = aicore
+					vnpu := &configv1alpha2.VnpuConfig{Resources: &configv1alpha2.VnpuResources{Aicore: aicore}}
+					if err := add(family, data, vnpu, fmt.Sprint(aicore)); err != nil {
+						return nil, err
+					}
+				}
+			case TemplateFamily:
+				for _, template := range slices.Sorted(maps.Keys(templates)) {
+					shape := templates[template]
+					data := data
+					data.Template = template
+					data.SafeTemplate = toSafeTemplateName(template)
+					data.Memory = shape.Memory
+					data.AICore = shape.AICore
+					vnpu := &configv1alpha2.VnpuConfig{Template: template}
+					if err := add(family, data, vnpu, template); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	return classes, nil
+}
+
+// distinct returns the distinct positive values of the templates, sorted.
+func distinct(templates map[string]common.TemplateShape, value func(common.TemplateShape) int) []int {
+	var values []int
+	for _, shape := range templates {
+		if v := value(shape); v > 0 && !slices.Contains(values, v) {
+			values = append(values, v)
+		}
+	}
+	slices.Sort(values)
+	return values
+}
+
+// sourceHash returns a short hash identifying what a DeviceClass is derived
+// from.
+func sourceHash(source ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(source, "/")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// buildDeviceClass generates the DeviceClass of family for data, whose config
+// requests vnpu. It returns nil if the family generates no DeviceClass for data.
+func buildDeviceClass(family *ClassFamily, data classTemplateData, vnpu *configv1alpha2.VnpuConfig) (*resourceapi.DeviceClass, error) {
+	name, err := execute(family.name, data)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, nil
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid name %q: %s", name, strings.Join(errs, ", "))
+	}
+
+	class := &resourceapi.DeviceClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      map[string]string{managedByLabel: managedByValue},
+			Annotations: map[string]string{},
+		},
+	}
+	for key, t := range family.labels {
+		if class.Labels[key], err = execute(t, data); err != nil {
+			return nil, err
+		}
+	}
+	for key, t := range family.annotations {
+		if class.Annotations[key], err = execute(t, data); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range family.selectors {
+		expression, err := execute(t, data)
+		if err != nil {
+			return nil, err
+		}
+		class.Spec.Selectors = append(class.Spec.Selectors, resourceapi.DeviceSelector{
+			CEL: &resourceapi.CELDeviceSelector{Expression: expression},
+		})
+	}
+
+	raw, err := family.config(vnpu)
+	if err != nil || raw == nil {
+		return class, err
+	}
+	class.Spec.Config = []resourceapi.DeviceClassConfiguration{{
+		DeviceConfiguration: resourceapi.DeviceConfiguration{
+			Opaque: &resourceapi.OpaqueDeviceConfiguration{
+				Driver:     DriverName,
+				Parameters: runtime.RawExtension{Raw: raw},
+			},
+		},
+	}}
+	return class, nil
+}
+
+// deviceClassEquals reports whether current has the labels, annotations and
+// spec of want, and is not deprecated.
+func deviceClassEquals(current, want *resourceapi.DeviceClass) bool {
+	for key, value := range want.Labels {
+		if current.Labels[key] != value {
+			return false
+		}
+	}
+	for key, value := range want.Annotations {
+		if current.Annotations[key] != value {
+			return false
+		}
+	}
+	if _, ok := current.Annotations[deprecatedSinceAnnotation]; ok {
+		return false
+	}
+	currentSpec, _ := json.Marshal(current.Spec)
+	wantSpec, _ := json.Marshal(want.Spec)
+	return string(currentSpec) == string(wantSpec)
+}
+
+// isLegacyDeviceClass reports whether class looks like one generated by an
+// earlier version of the kubelet plugin: it has a generated name, and selects
+// devices of this driver by model.
+func isLegacyDeviceClass(class *resourceapi.DeviceClass) bool {
+	if class.Labels[managedByLabel] != "" || !legacyClassName.MatchString(class.Name) || len(class.Spec.Selectors) == 0 {
+		return false
+	}
+	for _, selector := range class.Spec.Selectors {
+		if selector.CEL == nil ||
+			!strings.Contains(selector.CEL.Expression, fmt.Sprintf(`device.attributes["%s"].model == `, DriverName)) {
+			return false
+		}
+	}
+	for _, config := range class.Spec.Config {
+		if config.Opaque == nil || config.Opaque.Driver != DriverName {
+			return false
+		}
+	}
+	return true
+}
+
+// toSafeModelName removes extra characters from model and converts to lowercase
+func toSafeModelName(model string) string {
+	if model == "" {
+		return "unknown"
+	}
+	model = strings.ReplaceAll(model, " ", "-")
+	model = strings.ReplaceAll(model, "/", "-")
+	return strings.ToLower(model)
+}
+
+// toSafeTemplateName converts template to lowercase and replaces the
+// underscores that are not allowed in names.
+func toSafeTemplateName(template string) string {
+	return strings.ToLower(strings.ReplaceAll(template, "_", "-"))
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resourceapi "k8s.io/api/resource/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2/ktesting"
+	"k8s.io/utils/ptr"
+
+	configapi "Ascend-dra-driver/api/example.com/resource/npu"
+	configinstall "Ascend-dra-driver/api/example.com/resource/npu/install"
+	"Ascend-dra-driver/pkg/common"
+)
+
+// npuDevice returns a published device of model supporting templates.
+func npuDevice(name, model string, templates map[string]common.TemplateShape) resourceapi.Device {
+	attributes := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		DriverDomain + "model": {StringValue: ptr.To(model)},
+		DriverDomain + "type":  {StringValue: ptr.To("NPU")},
+	}
+	for template, shape := range templates {
+		attributes[resourceapi.QualifiedName(common.TemplateAttributeDomain+"/"+template)] = resourceapi.DeviceAttribute{
+			StringValue: ptr.To(shape.String()),
+		}
+	}
+	return resourceapi.Device{Name: name, Basic: &resourceapi.BasicDevice{Attributes: attributes}}
+}
+
+func resourceSlice(name, driver string, devices ...resourceapi.Device) *resourceapi.ResourceSlice {
+	return &resourceapi.ResourceSlice{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: resourceapi.ResourceSliceSpec{
+			Driver:  driver,
+			Devices: devices,
+		},
+	}
+}
+
+// legacyDeviceClass returns a DeviceClass like the ones earlier versions of the
+// kubelet plugin generated, pinning template if it is set.
+func legacyDeviceClass(name, expr, template string) *resourceapi.DeviceClass {
+	class := &resourceapi.DeviceClass{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: resourceapi.DeviceClassSpec{
+			Selectors: []resourceapi.DeviceSelector{{CEL: &resourceapi.CELDeviceSelector{Expression: expr}}},
+		},
+	}
+	if template != "" {
+		class.Spec.Config = []resourceapi.DeviceClassConfiguration{{
+			DeviceConfiguration: resourceapi.DeviceConfiguration{
+				Opaque: &resourceapi.OpaqueDeviceConfiguration{
+					Driver:     DriverName,
+					Parameters: runtime.RawExtension{Raw: []byte(`{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"NpuConfig","vnpu":{"template":"` + template + `"}}`)},
+				},
+			},
+		}}
+	}
+	return class
+}
+
+var (
+	vir01 = common.TemplateShape{AICore: 1, Memory: 3, AICPU: 1}
+	vir02 = common.TemplateShape{AICore: 2, Memory: 6, AICPU: 2}
+	vir21 = common.TemplateShape{AICore: 2, Memory: 6, AICPU: 1}
+	vir04 = common.TemplateShape{AICore: 4, Memory: 12, AICPU: 4, DVPP: true}
+)
+
+func TestCollectModelTemplates(t *testing.T) {
+	logger, _ := ktesting.NewTestContext(t)
+	bad := npuDevice("npu-2-0", "910", nil)
+	bad.Basic.Attributes[common.TemplateAttributeDomain+"/vir16"] = resourceapi.DeviceAttribute{StringValue: ptr.To("cores=16")}
+
+	models := collectModelTemplates(logger, []*resourceapi.ResourceSlice{
+		resourceSlice("node-1", DriverName,
+			npuDevice("npu-0-0", "310P3", map[string]common.TemplateShape{"vir01": vir01, "vir02": vir02}),
+			npuDevice("npu-1-0", "310P3", map[string]common.TemplateShape{"vir04": vir04}),
+		),
+		// A node reporting a bigger vir02 does not win over the smaller one.
+		resourceSlice("node-2", DriverName,
+			npuDevice("npu-0-0", "310P3", map[string]common.TemplateShape{"vir02": {AICore: 2, Memory: 8, AICPU: 2}}),
+			bad,
+		),
+		resourceSlice("node-3", "gpu.example.com", npuDevice("gpu-0", "A100", nil)),
+	})
+
+	assert.Equal(t, modelTemplates{
+		"310P3": {"vir01": vir01, "vir02": vir02, "vir04": vir04},
+		"910":   {},
+	}, models)
+}
+
+func TestDesiredDeviceClasses(t *testing.T) {
+	classes, err := desiredDeviceClasses(modelTemplates{
+		"310P3": {"vir01": vir01, "vir02": vir02, "vir02_1c": vir21, "vir04": vir04},
+		"":      {},
+	}, defaultClassDefinitions())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"npu-310p3-aicore1.example.com",
+		"npu-310p3-aicore2.example.com",
+		"npu-310p3-aicore4.example.com",
+		"npu-310p3-mem12.example.com",
+		"npu-310p3-mem3.example.com",
+		"npu-310p3-mem6.example.com",
+		"npu-310p3-vir01.example.com",
+		"npu-310p3-vir02-1c.example.com",
+		"npu-310p3-vir02.example.com",
+		"npu-310p3-vir04.example.com",
+		"npu-310p3.example.com",
+		"npu-unknown.example.com",
+	}, slices.Sorted(maps.Keys(classes)))
+
+	for name, class := range classes {
+		assert.Equal(t, managedByValue, class.Labels[managedByLabel], name)
+		assert.NotEmpty(t, class.Annotations[sourceHashAnnotation], name)
+	}
+
+	whole := classes["npu-310p3.example.com"]
+	assert.Empty(t, whole.Spec.Config)
+	assert.Equal(t, `device.attributes["npu.example.com"].model == "310P3" && device.attributes["npu.example.com"].type == "NPU"`,
+		whole.Spec.Selectors[0].CEL.Expression)
+
+	// Memory classes request the memory rather than pinning a template, and
+	// the config decodes and validates like a user-provided one.
+	mem6 := classes["npu-310p3-mem6.example.com"]
+	assert.Equal(t, `device.attributes["npu.example.com"].memory >= 6 && device.attributes["npu.example.com"].model == "310P3"`,
+		mem6.Spec.Selectors[0].CEL.Expression)
+	require.Len(t, mem6.Spec.Config, 1)
+	decoded, err := decodeConfig(mem6.Spec.Config[0].Opaque.Parameters.Raw)
+	require.NoError(t, err)
+	assert.Equal(t, &configapi.VnpuResources{MemoryGiB: 6}, decoded.Vnpu.Resources)
+	assert.Empty(t, decoded.Vnpu.Template)
+	assert.NoError(t, decoded.Validate())
+
+	aicore4 := classes["npu-310p3-aicore4.example.com"]
+	decoded, err = decodeConfig(aicore4.Spec.Config[0].Opaque.Parameters.Raw)
+	require.NoError(t, err)
+	assert.Equal(t, &configapi.VnpuResources{Aicore: 4}, decoded.Vnpu.Resources)
+
+	// Template classes pin the template and select devices that can host it.
+	vir021c := classes["npu-310p3-vir02-1c.example.com"]
+	assert.Equal(t, `device.attributes["npu.example.com"].model == "310P3" && `+
+		`has(device.attributes["hostable.vnpu.npu.example.com"].vir02_1c) && device.attributes["hostable.vnpu.npu.example.com"].vir02_1c`,
+		vir021c.Spec.Selectors[0].CEL.Expression)
+	decoded, err = decodeConfig(vir021c.Spec.Config[0].Opaque.Parameters.Raw)
+	require.NoError(t, err)
+	assert.Equal(t, "vir02_1c", decoded.Vnpu.Template)
+	assert.Nil(t, decoded.Vnpu.Resources)
+}
+
+// decodeConfig decodes raw like the kubelet plugin does.
+func decodeConfig(raw []byte) (*configapi.NpuConfig, error) {
+	obj, _, err := configinstall.Decoder.Decode(raw, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*configapi.NpuConfig), nil
+}
+
+func TestDeviceClassEquals(t *testing.T) {
+	classes, err := desiredDeviceClasses(modelTemplates{"310P3": {"vir02": vir02, "vir04": vir04}}, defaultClassDefinitions())
+	require.NoError(t, err)
+	want := classes["npu-310p3-mem6.example.com"]
+
+	current := want.DeepCopy()
+	current.ResourceVersion = "42"
+	current.Labels["team"] = "ml"
+	current.Annotations["note"] = "kept"
+	assert.True(t, deviceClassEquals(current, want))
+
+	current.Annotations[deprecatedSinceAnnotation] = "2025-06-01T12:00:00Z"
+	assert.False(t, deviceClassEquals(current, want), "deprecated")
+
+	current = want.DeepCopy()
+	current.Labels = nil
+	assert.False(t, deviceClassEquals(current, want), "missing label")
+
+	current = want.DeepCopy()
+	current.Annotations[sourceHashAnnotation] = "0"
+	assert.False(t, deviceClassEquals(current, want), "different source")
+
+	assert.False(t, deviceClassEquals(classes["npu-310p3-mem12.example.com"], want), "different spec")
+}
+
+func TestIsLegacyDeviceClass(t *testing.T) {
+	byModel := `device.attributes["npu.example.com"].model == "310P3"`
+	class := legacyDeviceClass
+	foreignConfig := class("npu-310p3-mem6.example.com", byModel, "vir02")
+	foreignConfig.Spec.Config[0].Opaque.Driver = "gpu.example.com"
+	managed := class("npu-310p3.example.com", byModel, "")
+	managed.Labels = map[string]string{managedByLabel: "someone-else"}
+
+	tests := map[string]struct {
+		class    *resourceapi.DeviceClass
+		expected bool
+	}{
+		"whole card":         {class("npu-310p3.example.com", byModel+` && device.attributes["npu.example.com"].type == "NPU"`, ""), true},
+		"memory":             {class("npu-310p3-mem6.example.com", byModel, "vir02"), true},
+		"aicore":             {class("npu-ascend-910-aicore8.example.com", byModel, "vir08"), true},
+		"other name":         {class("ascend-910b-half", byModel, ""), false},
+		"other selector":     {class("npu-310p3.example.com", `device.driver == "npu.example.com"`, ""), false},
+		"other driver":       {foreignConfig, false},
+		"managed by someone": {managed, false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, isLegacyDeviceClass(test.class))
+		})
+	}
+}
@@ -0,0 +1,246 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/urfave/cli/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+
+	"Ascend-dra-driver/pkg/flags"
+)
+
+const (
+	DriverName   = "npu.example.com"
+	DriverDomain = "npu.example.com/"
+)
+
+type Flags struct {
+	kubeClientConfig flags.KubeClientConfig
+	loggingConfig    *flags.LoggingConfig
+
+	leaderElect                 bool
+	leaderElectionNamespace     string
+	leaderElectionLeaseName     string
+	leaderElectionLeaseDuration time.Duration
+	leaderElectionRenewDeadline time.Duration
+	leaderElectionRetryPeriod   time.Duration
+	deviceClassResyncPeriod     time.Duration
+	staleDeviceClassPolicy      string
+	staleDeviceClassGracePeriod time.Duration
+	deviceClassFile             string
+}
+
+func main() {
+	if err := newApp().Run(os.Args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func newApp() *cli.App {
+	flags := &Flags{
+		loggingConfig: flags.NewLoggingConfig(),
+	}
+	cliFlags := []cli.Flag{
+		&cli.BoolFlag{
+			Category:    "Leader election:",
+			Name:        "leader-elect",
+			Usage:       "Elect a leader among the controller replicas, so that only one of them manages DeviceClasses at a time.",
+			Value:       true,
+			Destination: &flags.leaderElect,
+			EnvVars:     []string{"LEADER_ELECT"},
+		},
+		&cli.StringFlag{
+			Category:    "Leader election:",
+			Name:        "leader-election-namespace",
+			Usage:       "The `namespace` of the Lease object used for leader election.",
+			Value:       "default",
+			Destination: &flags.leaderElectionNamespace,
+			EnvVars:     []string{"NAMESPACE"},
+		},
+		&cli.StringFlag{
+			Category:    "Leader election:",
+			Name:        "leader-election-lease-name",
+			Usage:       "The `name` of the Lease object used for leader election.",
+			Value:       "ascend-dra-controller",
+			Destination: &flags.leaderElectionLeaseName,
+			EnvVars:     []string{"LEADER_ELECTION_LEASE_NAME"},
+		},
+		&cli.DurationFlag{
+			Category:    "Leader election:",
+			Name:        "leader-election-lease-duration",
+			Usage:       "How long replicas that are not the leader wait before trying to take over leadership.",
+			Value:       15 * time.Second,
+			Destination: &flags.leaderElectionLeaseDuration,
+			EnvVars:     []string{"LEADER_ELECTION_LEASE_DURATION"},
+		},
+		&cli.DurationFlag{
+			Category:    "Leader election:",
+			Name:        "leader-election-renew-deadline",
+			Usage:       "How long the leader keeps retrying to renew its leadership before giving it up.",
+			Value:       10 * time.Second,
+			Destination: &flags.leaderElectionRenewDeadline,
+			EnvVars:     []string{"LEADER_ELECTION_RENEW_DEADLINE"},
+		},
+		&cli.DurationFlag{
+			Category:    "Leader election:",
+			Name:        "leader-election-retry-period",
+			Usage:       "How long replicas wait between attempts to acquire or renew leadership.",
+			Value:       2 * time.Second,
+			Destination: &flags.leaderElectionRetryPeriod,
+			EnvVars:     []string{"LEADER_ELECTION_RETRY_PERIOD"},
+		},
+		&cli.DurationFlag{
+			Name:        "device-class-resync-period",
+			Usage:       "How often to reconcile DeviceClasses even if no ResourceSlice changed, to revert manual edits.",
+			Value:       10 * time.Minute,
+			Destination: &flags.deviceClassResyncPeriod,
+			EnvVars:     []string{"DEVICE_CLASS_RESYNC_PERIOD"},
+		},
+		&cli.StringFlag{
+			Name:        "device-class-file",
+			Usage:       "Path to a file defining the families of DeviceClasses to generate: their naming, CEL selectors, labels, annotations and sharing config. The built-in whole-card, memory, AI Core and template families are used if it is not set.",
+			Destination: &flags.deviceClassFile,
+			EnvVars:     []string{"DEVICE_CLASS_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "stale-device-class-policy",
+			Usage:       "What to do with generated DeviceClasses no longer backed by any device: Delete them after the grace period, or only Deprecate them. Deprecated DeviceClasses carry the " + deprecatedSinceAnnotation + " annotation either way.",
+			Value:       string(StaleClassDelete),
+			Destination: &flags.staleDeviceClassPolicy,
+			EnvVars:     []string{"STALE_DEVICE_CLASS_POLICY"},
+		},
+		&cli.DurationFlag{
+			Name:        "stale-device-class-grace-period",
+			Usage:       "How long generated DeviceClasses stay deprecated before they are deleted, so that they survive kubelet plugin restarts. A zero value deletes them right away.",
+			Value:       time.Hour,
+			Destination: &flags.staleDeviceClassGracePeriod,
+			EnvVars:     []string{"STALE_DEVICE_CLASS_GRACE_PERIOD"},
+		},
+	}
+	cliFlags = append(cliFlags, flags.kubeClientConfig.Flags()...)
+	cliFlags = append(cliFlags, flags.loggingConfig.Flags()...)
+
+	app := &cli.App{
+		Name:  "ascend-dra-controller",
+		Usage: "ascend-dra-controller manages the DeviceClasses of the NPU models and vNPU templates published by the kubelet plugins.",
+		Flags: cliFlags,
+		Before: func(c *cli.Context) error {
+			switch StaleClassPolicy(flags.staleDeviceClassPolicy) {
+			case StaleClassDelete, StaleClassDeprecate:
+			default:
+				return fmt.Errorf("invalid stale DeviceClass policy %q, must be %s or %s",
+					flags.staleDeviceClassPolicy, StaleClassDelete, StaleClassDeprecate)
+			}
+			return flags.loggingConfig.Apply()
+		},
+		Action: func(c *cli.Context) error {
+			classDefinitions, err := loadClassDefinitions(flags.deviceClassFile)
+			if err != nil {
+				return err
+			}
+			clientSets, err := flags.kubeClientConfig.NewClientSets()
+			if err != nil {
+				return fmt.Errorf("create client: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(c.Context)
+			defer cancel()
+			sigc := make(chan os.Signal, 1)
+			signal.Notify(sigc, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+			go func() {
+				<-sigc
+				cancel()
+			}()
+
+			run := func(ctx context.Context) {
+				NewController(clientSets.Core, ControllerConfig{
+					ResyncPeriod:          flags.deviceClassResyncPeriod,
+					StaleClassPolicy:      StaleClassPolicy(flags.staleDeviceClassPolicy),
+					StaleClassGracePeriod: flags.staleDeviceClassGracePeriod,
+					ClassDefinitions:      classDefinitions,
+				}).Run(ctx)
+			}
+			if !flags.leaderElect {
+				run(ctx)
+				return nil
+			}
+			return runWithLeaderElection(ctx, flags, clientSets.Core, run)
+		},
+	}
+
+	return app
+}
+
+// runWithLeaderElection calls run once this replica becomes the leader, and
+// returns when ctx is done or leadership is lost.
+func runWithLeaderElection(ctx context.Context, flags *Flags, client coreclientset.Interface, run func(context.Context)) error {
+	logger := klog.FromContext(ctx)
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("get hostname: %v", err)
+	}
+	identity := hostname + "_" + uuid.NewString()
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Namespace: flags.leaderElectionNamespace,
+			Name:      flags.leaderElectionLeaseName,
+		},
+		Client: client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+
+	lostLeadership := false
+	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   flags.leaderElectionLeaseDuration,
+		RenewDeadline:   flags.leaderElectionRenewDeadline,
+		RetryPeriod:     flags.leaderElectionRetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            flags.leaderElectionLeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: run,
+			OnStoppedLeading: func() {
+				lostLeadership = ctx.Err() == nil
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					logger.Info("Another replica is the leader", "leader", leader)
+				}
+			},
+		},
+	})
+	if lostLeadership {
+		return fmt.Errorf("lost leadership")
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+
+	npuCommon "huawei.com/npu-exporter/v5/devmanager/common"
+
+	"Ascend-dra-driver/pkg/common"
+)
+
+// errNpuDriverUnavailable is wrapped by errors caused by the NPU driver not
+// being usable, as opposed to configuration errors.
+var errNpuDriverUnavailable = errors.New("NPU driver unavailable")
+
+// NpuBackend is the view of the NPU driver used to discover and carve devices.
+type NpuBackend interface {
+	NewHwDevManager() (common.NpuAllInfo, error)
+	GetChipAiCoreCount() (int32, error)
+	GetChipMem() (int32, error)
+	GetChipName(logicID int32) (string, error)
+	GetPhysicIDFromLogicID(logicID int32) (int32, error)
+	GetVirtualDevices(logicID int32) ([]VirtualDevice, error)
+	CreateVirtualDevice(logicID int32, templateName string) (uint32, error)
+}
+
+// backendFactory connects to the NPU driver.
+type backendFactory func() (NpuBackend, error)
+
+// deviceManager is the subset of the devmanager used by AscendManager.
+type deviceManager interface {
+	GetDeviceList() (int32, []int32, error)
+	GetPhysicIDFromLogicID(logicID int32) (int32, error)
+	GetCardIDDeviceID(logicID int32) (int32, int32, error)
+	GetChipInfo(logicID int32) (*npuCommon.ChipInfo, error)
+	GetVirtualDeviceInfo(logicID int32) (npuCommon.VirtualDevInfo, error)
+	CreateVirtualDevice(logicID int32, vDevInfo npuCommon.CgoCreateVDevRes) (npuCommon.CgoCreateVDevOut, error)
+}
+
+var _ NpuBackend = &AscendManager{}
+
+// newAscendBackend connects to the NPU driver through the devmanager.
+func newAscendBackend() (NpuBackend, error) {
+	mgr, err := NewAscendManager()
+	if err != nil {
+		return nil, err
+	}
+	return mgr, nil
+}
+
+// isNpuDriverUnavailable reports whether err was caused by the NPU driver not
+// being usable.
+func isNpuDriverUnavailable(err error) bool {
+	return errors.Is(err, errNpuDriverUnavailable)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"slices"
 
 	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
 	cdiparser "tags.cncf.io/container-device-interface/pkg/parser"
@@ -69,9 +70,19 @@ func (cdi *CDIHandler) CreateClaimSpecFile(claimUID string, devices PreparedDevi
 	var merged cdispec.ContainerEdits
 	for _, d := range devices {
 		merged.Env = append(merged.Env, d.ContainerEdits.Env...)
-		merged.DeviceNodes = append(merged.DeviceNodes, d.ContainerEdits.DeviceNodes...)
+		for _, node := range d.ContainerEdits.DeviceNodes {
+			// Devices on the same NPU share its device nodes.
+			if !slices.ContainsFunc(merged.DeviceNodes, func(n *cdispec.DeviceNode) bool { return n.Path == node.Path }) {
+				merged.DeviceNodes = append(merged.DeviceNodes, node)
+			}
+		}
 		merged.Hooks = append(merged.Hooks, d.ContainerEdits.Hooks...)
-		merged.Mounts = append(merged.Mounts, d.ContainerEdits.Mounts...)
+		for _, mount := range d.ContainerEdits.Mounts {
+			// Devices configured by the same config share its mounts.
+			if !slices.ContainsFunc(merged.Mounts, func(m *cdispec.Mount) bool { return m.ContainerPath == mount.ContainerPath }) {
+				merged.Mounts = append(merged.Mounts, mount)
+			}
+		}
 	}
 
 	spec := &cdispec.Spec{
@@ -84,6 +95,12 @@ func (cdi *CDIHandler) CreateClaimSpecFile(claimUID string, devices PreparedDevi
 		},
 	}
 
+	minVersion, err := cdiapi.MinimumRequiredVersion(spec)
+	if err != nil {
+		return fmt.Errorf("failed to get minimum required CDI spec version: %v", err)
+	}
+	spec.Version = minVersion
+
 	return cdi.cache.WriteSpec(spec, specName)
 }
 
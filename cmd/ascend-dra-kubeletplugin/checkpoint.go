@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"maps"
 
 	"k8s.io/kubernetes/pkg/kubelet/checkpointmanager/checksum"
 )
@@ -13,18 +14,45 @@ type Checkpoint struct {
 
 type CheckpointV1 struct {
 	PreparedClaims PreparedClaims `json:"preparedClaims,omitempty"`
+	// PreparingClaims are the claims being prepared. A claim is recorded here
+	// before Prepare has any side effect, and moved to PreparedClaims once it
+	// is prepared, so that claims whose Prepare was interrupted by a crash can
+	// be rolled back on startup.
+	PreparingClaims map[string]*PreparingClaim `json:"preparingClaims,omitempty"`
+}
+
+// PreparingClaim is the intent to prepare a claim.
+type PreparingClaim struct {
+	Devices []PreparingDevice `json:"devices"`
+}
+
+// PreparingDevice is an allocated device a claim is being prepared on.
+type PreparingDevice struct {
+	DeviceName string `json:"deviceName"`
+	// TemplateName is the vNPU template requested for the device, if any.
+	TemplateName string `json:"templateName,omitempty"`
 }
 
 func newCheckpoint() *Checkpoint {
 	pc := &Checkpoint{
 		Checksum: 0,
 		V1: &CheckpointV1{
-			PreparedClaims: make(PreparedClaims),
+			PreparedClaims:  make(PreparedClaims),
+			PreparingClaims: make(map[string]*PreparingClaim),
 		},
 	}
 	return pc
 }
 
+// clone returns a copy of cp whose claims can be added and removed without
+// affecting cp. The records of the claims are shared.
+func (cp *Checkpoint) clone() *Checkpoint {
+	cloned := newCheckpoint()
+	maps.Copy(cloned.V1.PreparedClaims, cp.V1.PreparedClaims)
+	maps.Copy(cloned.V1.PreparingClaims, cp.V1.PreparingClaims)
+	return cloned
+}
+
 func (cp *Checkpoint) MarshalCheckpoint() ([]byte, error) {
 	cp.Checksum = 0
 	out, err := json.Marshal(*cp)
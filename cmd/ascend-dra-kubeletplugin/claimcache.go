@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	coreclientset "k8s.io/client-go/kubernetes"
+	resourcelisters "k8s.io/client-go/listers/resource/v1beta1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// claimCache looks up the ResourceClaims to prepare in an informer cache of
+// the claims allocated to the node by the driver. Claims the cache does not
+// have, such as those allocated moments ago, are fetched from the API server.
+type claimCache struct {
+	client   coreclientset.Interface
+	nodeName string
+	factory  informers.SharedInformerFactory
+	informer cache.SharedIndexInformer
+	lister   resourcelisters.ResourceClaimLister
+}
+
+func newClaimCache(client coreclientset.Interface, nodeName string) *claimCache {
+	c := &claimCache{
+		client:   client,
+		nodeName: nodeName,
+	}
+	c.factory = informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithTransform(trim))
+	c.informer = c.factory.InformerFor(&resourceapi.ResourceClaim{}, c.newInformer)
+	c.lister = resourcelisters.NewResourceClaimLister(c.informer.GetIndexer())
+	return c
+}
+
+// newInformer creates an informer for the claims allocated to the node by
+// the driver. The API server can only select ResourceClaims by name and
+// namespace, not by their allocation, so all claims are listed and watched
+// and the others are dropped before they reach the cache.
+func (c *claimCache) newInformer(client coreclientset.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	claims := client.ResourceV1beta1().ResourceClaims(metav1.NamespaceAll)
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			list, err := claims.List(context.TODO(), options)
+			if err != nil {
+				return nil, err
+			}
+			list.Items = slices.DeleteFunc(list.Items, func(claim resourceapi.ResourceClaim) bool {
+				return !c.allocatedToNode(&claim)
+			})
+			return list, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			w, err := claims.Watch(context.TODO(), options)
+			if err != nil {
+				return nil, err
+			}
+			return watch.Filter(w, c.filterEvent), nil
+		},
+	}
+	return cache.NewSharedIndexInformer(lw, &resourceapi.ResourceClaim{}, resyncPeriod,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+}
+
+// filterEvent turns the events of claims that are not allocated to the node
+// by the driver into deletions, which drop claims from the cache once they
+// are deallocated and are ignored for claims the cache does not have.
+func (c *claimCache) filterEvent(event watch.Event) (watch.Event, bool) {
+	if event.Type != watch.Added && event.Type != watch.Modified {
+		return event, true
+	}
+	if claim, ok := event.Object.(*resourceapi.ResourceClaim); ok && !c.allocatedToNode(claim) {
+		event.Type = watch.Deleted
+	}
+	return event, true
+}
+
+// Start starts filling the cache until ctx is done. Lookups do not wait for
+// the cache to be synced.
+func (c *claimCache) Start(ctx context.Context) {
+	c.factory.Start(ctx.Done())
+}
+
+// Stop waits for the cache to stop once the context passed to Start is done.
+func (c *claimCache) Stop() {
+	c.factory.Shutdown()
+}
+
+// Get returns the claim namespace/name with the given UID.
+func (c *claimCache) Get(ctx context.Context, namespace, name, uid string) (*resourceapi.ResourceClaim, error) {
+	claim, err := c.lister.ResourceClaims(namespace).Get(name)
+	if err == nil && string(claim.UID) == uid && c.allocatedToNode(claim) {
+		return claim, nil
+	}
+	claim, err = c.client.ResourceV1beta1().ResourceClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if string(claim.UID) != uid {
+		return nil, fmt.Errorf("claim has UID %s instead of %s, it was recreated", claim.UID, uid)
+	}
+	return claim, nil
+}
+
+// allocatedToNode reports whether any device of the node was allocated to
+// claim by the driver.
+func (c *claimCache) allocatedToNode(claim *resourceapi.ResourceClaim) bool {
+	if claim.Status.Allocation == nil {
+		return false
+	}
+	for _, result := range claim.Status.Allocation.Devices.Results {
+		if result.Driver == DriverName && isNodePool(c.nodeName, result.Pool) {
+			return true
+		}
+	}
+	return false
+}
+
+// trim drops the managed fields of the cached claims, which are not needed
+// to prepare them.
+func trim(obj any) (any, error) {
+	if claim, ok := obj.(*resourceapi.ResourceClaim); ok {
+		claim.ManagedFields = nil
+	}
+	return obj, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resourceapi "k8s.io/api/resource/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+func TestClaimCacheGet(t *testing.T) {
+	otherNode := newTestClaim("claim-1", []string{"npu-0-0"})
+	otherNode.Status.Allocation.Devices.Results[0].Pool = "node-2"
+	recreated := newTestClaim("claim-1", []string{"npu-0-0"})
+	recreated.UID = "claim-1-recreated"
+
+	tests := map[string]struct {
+		cached        *resourceapi.ResourceClaim
+		live          *resourceapi.ResourceClaim
+		expectedLive  bool
+		expectedError string
+	}{
+		"cached": {
+			cached: newTestClaim("claim-1", []string{"npu-0-0"}),
+		},
+		"not cached yet": {
+			live:         newTestClaim("claim-1", []string{"npu-0-0"}),
+			expectedLive: true,
+		},
+		"cached before it was allocated to the node": {
+			cached:       otherNode,
+			live:         newTestClaim("claim-1", []string{"npu-0-0"}),
+			expectedLive: true,
+		},
+		"cached with another UID": {
+			cached:       recreated,
+			live:         newTestClaim("claim-1", []string{"npu-0-0"}),
+			expectedLive: true,
+		},
+		"recreated": {
+			live:          recreated,
+			expectedLive:  true,
+			expectedError: "claim has UID claim-1-recreated instead of claim-1, it was recreated",
+		},
+		"not found": {
+			expectedLive:  true,
+			expectedError: `resourceclaims.resource.k8s.io "claim-1" not found`,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var objects []runtime.Object
+			if test.live != nil {
+				objects = append(objects, test.live)
+			}
+			client := fake.NewSimpleClientset(objects...)
+			cache := newClaimCache(client, testNodeName)
+			if test.cached != nil {
+				trimmed, err := trim(test.cached.DeepCopy())
+				require.NoError(t, err)
+				require.NoError(t, cache.informer.GetIndexer().Add(trimmed))
+			}
+
+			claim, err := cache.Get(context.Background(), "default", "claim-1", "claim-1")
+			assert.Equal(t, test.expectedLive, len(client.Actions()) > 0, "fetched from the API server")
+			if test.expectedError != "" {
+				require.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, types.UID("claim-1"), claim.UID)
+			assert.NotNil(t, claim.Status.Allocation)
+		})
+	}
+}
+
+func TestClaimCacheTrim(t *testing.T) {
+	claim := newTestClaim("claim-1", []string{"npu-0-0"})
+	managed := claim.DeepCopy()
+	managed.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kube-scheduler"}}
+	trimmed, err := trim(managed)
+	require.NoError(t, err)
+	assert.Equal(t, claim, trimmed)
+}
+
+func TestClaimCacheHoldsNodeClaims(t *testing.T) {
+	otherNode := newTestClaim("other-node", []string{"npu-0-0"})
+	otherNode.Status.Allocation.Devices.Results[0].Pool = "node-2"
+	otherDriver := newTestClaim("other-driver", []string{"gpu-0"})
+	otherDriver.Status.Allocation.Devices.Results[0].Driver = "gpu.example.com"
+	unallocated := newTestClaim("unallocated", nil)
+	unallocated.Status.Allocation = nil
+	client := fake.NewSimpleClientset(newTestClaim("claim-1", []string{"npu-0-0"}), otherNode, otherDriver, unallocated)
+
+	claimCache := newClaimCache(client, testNodeName)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		claimCache.Stop()
+	}()
+	claimCache.Start(ctx)
+	require.True(t, toolscache.WaitForCacheSync(ctx.Done(), claimCache.informer.HasSynced))
+	cachedNames := func() []string {
+		var names []string
+		for _, obj := range claimCache.informer.GetStore().List() {
+			names = append(names, obj.(*resourceapi.ResourceClaim).Name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"claim-1"}, cachedNames())
+
+	// Claims are added once they are allocated to the node and dropped once
+	// they are deallocated.
+	otherNode.Status.Allocation.Devices.Results[0].Pool = testNodeName
+	_, err := client.ResourceV1beta1().ResourceClaims("default").Update(ctx, otherNode, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	claim := newTestClaim("claim-1", nil)
+	claim.Status.Allocation = nil
+	_, err = client.ResourceV1beta1().ResourceClaims("default").Update(ctx, claim, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		names := cachedNames()
+		return len(names) == 1 && names[0] == "other-node"
+	}, 5*time.Second, 10*time.Millisecond)
+}
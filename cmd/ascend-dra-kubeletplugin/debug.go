@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// debugServer exposes metrics and debugging information about the plugin.
+type debugServer struct {
+	server *http.Server
+}
+
+// startDebugServer starts serving the debug endpoints on the given address.
+func startDebugServer(ctx context.Context, endpoint string, d *driver) (*debugServer, error) {
+	state := d.state
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsHandler(state))
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		npuState, message := d.status.Get()
+		if npuState != npuDriverReady {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "NPU driver %s: %s\n", npuState, message)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/debug/defrag", func(w http.ResponseWriter, r *http.Request) {
+		report := &DefragReport{}
+		if vnpuManager := state.VnpuManager(); vnpuManager != nil {
+			report = vnpuManager.PlanDefragmentation()
+		}
+		writeJSON(w, struct {
+			*DefragReport
+			ReclaimCandidates []CardDefragStatus `json:"reclaimCandidates,omitempty"`
+		}{report, report.ReclaimCandidates()})
+	})
+	mux.HandleFunc("/debug/rediscover", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		changed, err := d.rediscover(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]bool{"changed": changed})
+	})
+
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", endpoint, err)
+	}
+
+	s := &debugServer{
+		server: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.FromContext(ctx).Error(err, "Debug server stopped unexpectedly")
+		}
+	}()
+	klog.FromContext(ctx).Info("Debug server started", "endpoint", listener.Addr().String())
+	return s, nil
+}
+
+// Stop shuts down the debug server.
+func (s *debugServer) Stop(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// writeJSON writes v as an indented JSON response.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"slices"
+	"sort"
+)
+
+// DefragReport describes how fragmented the vNPU layouts of all physical NPUs
+// on this node are.
+type DefragReport struct {
+	Cards []CardDefragStatus `json:"cards"`
+}
+
+// CardDefragStatus is the defragmentation status of a single physical NPU.
+type CardDefragStatus struct {
+	DeviceName  string `json:"deviceName"`
+	Model       string `json:"model"`
+	TotalAicore int    `json:"totalAicore"`
+	TotalMemory int    `json:"totalMemory"`
+	FreeAicore  int    `json:"freeAicore"`
+	FreeMemory  int    `json:"freeMemory"`
+	// Static cards have an administrator-declared layout and are never carved on demand.
+	Static bool `json:"static,omitempty"`
+	// Whole is true when the card is published as a single, uncarved NPU.
+	Whole bool `json:"whole"`
+	// Reclaimable is true when no slice is allocated on the card but its
+	// layout has not been restored to a whole card.
+	Reclaimable bool `json:"reclaimable"`
+	// SupportedTemplates are the templates the current layout can host.
+	SupportedTemplates []string `json:"supportedTemplates,omitempty"`
+	// BlockedTemplates fit into the free capacity of the card but cannot be
+	// created with its current layout.
+	BlockedTemplates []string `json:"blockedTemplates,omitempty"`
+	// BlockingClaims own the slices that have to be released before the card
+	// can be restored to a whole card.
+	BlockingClaims []string `json:"blockingClaims,omitempty"`
+	// UnownedSlices are on-chip vNPUs not owned by any claim; they have to be
+	// destroyed by the administrator before the card can be reclaimed.
+	UnownedSlices []string `json:"unownedSlices,omitempty"`
+}
+
+// PlanDefragmentation inspects the layout of every physical NPU and reports
+// which cards could be fully reclaimed and which claims block larger templates.
+func (m *VnpuManager) PlanDefragmentation() *DefragReport {
+	m.Lock()
+	defer m.Unlock()
+
+	report := &DefragReport{}
+	for _, npu := range m.PhysicalNpus {
+		report.Cards = append(report.Cards, m.planCard(npu))
+	}
+	sort.Slice(report.Cards, func(i, j int) bool {
+		return report.Cards[i].DeviceName < report.Cards[j].DeviceName
+	})
+	return report
+}
+
+// ReclaimCandidates returns the carved cards ordered by how few claims have to
+// be released before they can be restored to a whole card.
+func (r *DefragReport) ReclaimCandidates() []CardDefragStatus {
+	var candidates []CardDefragStatus
+	for _, card := range r.Cards {
+		if !card.Whole && !card.Static {
+			candidates = append(candidates, card)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i].BlockingClaims) < len(candidates[j].BlockingClaims)
+	})
+	return candidates
+}
+
+// planCard computes the defragmentation status of a single physical NPU.
+func (m *VnpuManager) planCard(npu *PhysicalNpuState) CardDefragStatus {
+	totalAicore, totalMemory := npu.TotalAicore, npu.TotalMemory
+	if totalAicore == 0 || totalMemory == 0 {
+		totalAicore, totalMemory = maxTemplateResources(m.Templates)
+	}
+
+	status := CardDefragStatus{
+		DeviceName:  npu.DeviceName,
+		Model:       npu.ModelName,
+		TotalAicore: totalAicore,
+		TotalMemory: totalMemory,
+		FreeAicore:  totalAicore,
+		FreeMemory:  totalMemory,
+		Static:      npu.Static,
+		Whole:       len(npu.AllocatedSlices) == 0 && m.wholeCardIsAvailable(npu),
+	}
+	status.Reclaimable = len(npu.AllocatedSlices) == 0 && !status.Whole && !npu.Static
+
+	for _, slice := range npu.AllocatedSlices {
+		tpl, ok := m.Templates[slice.TemplateName]
+		if !ok {
+			// A slice without a known template occupies everything left.
+			status.FreeAicore, status.FreeMemory = 0, 0
+		} else {
+			status.FreeAicore = max(status.FreeAicore-tpl.Attributes.AICORE, 0)
+			status.FreeMemory = max(status.FreeMemory-tpl.Attributes.Memory, 0)
+		}
+		switch {
+		case slice.ClaimUID == "":
+			status.UnownedSlices = append(status.UnownedSlices, slice.SliceID)
+		case !slices.Contains(status.BlockingClaims, slice.ClaimUID):
+			status.BlockingClaims = append(status.BlockingClaims, slice.ClaimUID)
+		}
+	}
+
+	for name := range npu.SupportTemplates {
+		status.SupportedTemplates = append(status.SupportedTemplates, name)
+	}
+	if !npu.Static {
+		for name, tpl := range m.Templates {
+			if _, ok := npu.SupportTemplates[name]; ok {
+				continue
+			}
+			if tpl.Attributes.AICORE <= status.FreeAicore && tpl.Attributes.Memory <= status.FreeMemory {
+				status.BlockedTemplates = append(status.BlockedTemplates, name)
+			}
+		}
+	}
+	sort.Strings(status.SupportedTemplates)
+	sort.Strings(status.BlockedTemplates)
+	sort.Strings(status.BlockingClaims)
+	sort.Strings(status.UnownedSlices)
+
+	return status
+}
+
+// maxTemplateResources returns the largest AI Core and memory values offered
+// by any template.
+func maxTemplateResources(templates map[string]*VnpuTemplate) (int, int) {
+	maxAicore, maxMemory := 0, 0
+	for _, tpl := range templates {
+		maxAicore = max(maxAicore, tpl.Attributes.AICORE)
+		maxMemory = max(maxMemory, tpl.Attributes.Memory)
+	}
+	return maxAicore, maxMemory
+}
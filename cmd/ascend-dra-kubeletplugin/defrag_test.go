@@ -0,0 +1,182 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// supportTemplates returns the named templates of templates310P.
+func supportTemplates(names ...string) map[string]*VnpuTemplate {
+	templates := templates310P()
+	supported := make(map[string]*VnpuTemplate)
+	for _, name := range names {
+		supported[name] = templates[name]
+	}
+	return supported
+}
+
+func TestPlanCard(t *testing.T) {
+	allTemplates := []string{"vir01", "vir02", "vir02_1c", "vir04", "vir04_3c", "vir04_3c_ndvpp", "vir04_4c_dvpp"}
+
+	tests := map[string]struct {
+		npu      *PhysicalNpuState
+		expected CardDefragStatus
+	}{
+		"fragmented": {
+			npu: &PhysicalNpuState{
+				DeviceName: "npu-0", ModelName: "310P3", TotalAicore: 8, TotalMemory: 24,
+				AllocatedSlices: []*VnpuSlice{
+					{SliceID: "npu-0-0", TemplateName: "vir02", Allocated: true, ClaimUID: "claim-b"},
+					{SliceID: "npu-0-1", TemplateName: "vir01", Allocated: true, ClaimUID: "claim-a"},
+					{SliceID: "npu-0-2", TemplateName: "vir01", Allocated: true, ClaimUID: "claim-b"},
+					{SliceID: "npu-0-3", TemplateName: "vir01", Allocated: true, OnChip: true},
+				},
+				SupportTemplates: supportTemplates("vir01"),
+			},
+			expected: CardDefragStatus{
+				DeviceName: "npu-0", Model: "310P3", TotalAicore: 8, TotalMemory: 24,
+				FreeAicore: 3, FreeMemory: 9,
+				SupportedTemplates: []string{"vir01"},
+				BlockedTemplates:   []string{"vir02", "vir02_1c"},
+				BlockingClaims:     []string{"claim-a", "claim-b"},
+				UnownedSlices:      []string{"npu-0-3"},
+			},
+		},
+		"unknown template occupies the rest": {
+			npu: &PhysicalNpuState{
+				DeviceName: "npu-0", ModelName: "310P3", TotalAicore: 8, TotalMemory: 24,
+				AllocatedSlices: []*VnpuSlice{
+					{SliceID: "npu-0-0", TemplateName: "vir03", Allocated: true, ClaimUID: "claim-a"},
+				},
+				SupportTemplates: supportTemplates("vir01"),
+			},
+			expected: CardDefragStatus{
+				DeviceName: "npu-0", Model: "310P3", TotalAicore: 8, TotalMemory: 24,
+				SupportedTemplates: []string{"vir01"},
+				BlockingClaims:     []string{"claim-a"},
+			},
+		},
+		"already compact": {
+			npu: &PhysicalNpuState{
+				DeviceName: "npu-1", ModelName: "310P3", TotalAicore: 8, TotalMemory: 24,
+				AvailableSlices:  []*VnpuSlice{{SliceID: "npu-1", Type: "NPU"}},
+				SupportTemplates: supportTemplates(allTemplates...),
+			},
+			expected: CardDefragStatus{
+				DeviceName: "npu-1", Model: "310P3", TotalAicore: 8, TotalMemory: 24,
+				FreeAicore: 8, FreeMemory: 24,
+				Whole:              true,
+				SupportedTemplates: allTemplates,
+			},
+		},
+		"reclaimable": {
+			npu: &PhysicalNpuState{
+				DeviceName: "npu-2", ModelName: "310P3", TotalAicore: 8, TotalMemory: 24,
+				AvailableSlices:  []*VnpuSlice{{SliceID: "npu-2-0", TemplateName: "vir04", Type: "vNPU"}},
+				SupportTemplates: supportTemplates(allTemplates...),
+			},
+			expected: CardDefragStatus{
+				DeviceName: "npu-2", Model: "310P3", TotalAicore: 8, TotalMemory: 24,
+				FreeAicore: 8, FreeMemory: 24,
+				Reclaimable:        true,
+				SupportedTemplates: allTemplates,
+			},
+		},
+		"static": {
+			npu: &PhysicalNpuState{
+				DeviceName: "npu-3", ModelName: "310P3", TotalAicore: 8, TotalMemory: 24, Static: true,
+				AvailableSlices: []*VnpuSlice{{SliceID: "npu-3-1", TemplateName: "vir02", Type: "vNPU"}},
+				AllocatedSlices: []*VnpuSlice{
+					{SliceID: "npu-3-0", TemplateName: "vir04", Allocated: true, ClaimUID: "claim-c"},
+				},
+				SupportTemplates: supportTemplates("vir02", "vir04"),
+			},
+			expected: CardDefragStatus{
+				DeviceName: "npu-3", Model: "310P3", TotalAicore: 8, TotalMemory: 24,
+				FreeAicore: 4, FreeMemory: 12,
+				Static:             true,
+				SupportedTemplates: []string{"vir02", "vir04"},
+				BlockingClaims:     []string{"claim-c"},
+			},
+		},
+		"empty static": {
+			npu: &PhysicalNpuState{
+				DeviceName: "npu-3", ModelName: "310P3", TotalAicore: 8, TotalMemory: 24, Static: true,
+				AvailableSlices:  []*VnpuSlice{{SliceID: "npu-3-0", TemplateName: "vir04", Type: "vNPU"}},
+				SupportTemplates: supportTemplates("vir04"),
+			},
+			expected: CardDefragStatus{
+				DeviceName: "npu-3", Model: "310P3", TotalAicore: 8, TotalMemory: 24,
+				FreeAicore: 8, FreeMemory: 24,
+				Static:             true,
+				SupportedTemplates: []string{"vir04"},
+			},
+		},
+		"unknown capacity": {
+			npu: &PhysicalNpuState{
+				DeviceName: "npu-4", ModelName: "310P3",
+				AvailableSlices: []*VnpuSlice{{SliceID: "npu-4", Type: "NPU"}},
+			},
+			expected: CardDefragStatus{
+				DeviceName: "npu-4", Model: "310P3", TotalAicore: 4, TotalMemory: 12,
+				FreeAicore: 4, FreeMemory: 12,
+				Whole:            true,
+				BlockedTemplates: allTemplates,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &VnpuManager{Templates: templates310P()}
+			assert.Equal(t, test.expected, m.planCard(test.npu))
+		})
+	}
+}
+
+func TestPlanDefragmentation(t *testing.T) {
+	m := &VnpuManager{
+		Templates: templates310P(),
+		PhysicalNpus: map[string]*PhysicalNpuState{
+			"npu-2": {
+				DeviceName: "npu-2", ModelName: "310P3", TotalAicore: 8, TotalMemory: 24,
+				AllocatedSlices: []*VnpuSlice{
+					{SliceID: "npu-2-0", TemplateName: "vir01", Allocated: true, ClaimUID: "claim-a"},
+					{SliceID: "npu-2-1", TemplateName: "vir01", Allocated: true, ClaimUID: "claim-b"},
+				},
+			},
+			"npu-0": {
+				DeviceName: "npu-0", ModelName: "310P3", TotalAicore: 8, TotalMemory: 24,
+				AvailableSlices: []*VnpuSlice{{SliceID: "npu-0", Type: "NPU"}},
+			},
+			"npu-1": {
+				DeviceName: "npu-1", ModelName: "310P3", TotalAicore: 8, TotalMemory: 24,
+				AllocatedSlices: []*VnpuSlice{
+					{SliceID: "npu-1-0", TemplateName: "vir02", Allocated: true, ClaimUID: "claim-c"},
+				},
+			},
+			"npu-3": {
+				DeviceName: "npu-3", ModelName: "310P3", TotalAicore: 8, TotalMemory: 24, Static: true,
+				AllocatedSlices: []*VnpuSlice{
+					{SliceID: "npu-3-0", TemplateName: "vir04", Allocated: true, ClaimUID: "claim-d"},
+				},
+			},
+		},
+	}
+
+	report := m.PlanDefragmentation()
+	var cards []string
+	for _, card := range report.Cards {
+		cards = append(cards, card.DeviceName)
+	}
+	assert.Equal(t, []string{"npu-0", "npu-1", "npu-2", "npu-3"}, cards)
+
+	// Whole and static cards are never candidates, and cards blocked by fewer
+	// claims come first.
+	var candidates []string
+	for _, card := range report.ReclaimCandidates() {
+		candidates = append(candidates, card.DeviceName)
+	}
+	assert.Equal(t, []string{"npu-1", "npu-2"}, candidates)
+}
@@ -3,14 +3,20 @@ package main
 import (
 	"fmt"
 	"log"
+	"maps"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
 
 	resourceapi "k8s.io/api/resource/v1beta1"
 	"k8s.io/utils/ptr"
+
+	"Ascend-dra-driver/pkg/common"
 )
 
 // fetchAiCore attempts to retrieve the total number of AI Cores on the card.
-func fetchAiCore(mgr *AscendManager) (int, error) {
+func fetchAiCore(mgr NpuBackend) (int, error) {
 	aiCoreCount, err := mgr.GetChipAiCoreCount()
 	if err == nil {
 		return int(aiCoreCount), nil
@@ -19,7 +25,7 @@ func fetchAiCore(mgr *AscendManager) (int, error) {
 }
 
 // fetchMemory attempts to retrieve total memory from the card.
-func fetchMemory(hdm *AscendManager) (int, error) {
+func fetchMemory(hdm NpuBackend) (int, error) {
 	memSize, err := hdm.GetChipMem()
 	if err == nil {
 		return int(memSize), nil
@@ -29,7 +35,7 @@ func fetchMemory(hdm *AscendManager) (int, error) {
 
 // getDeviceResources returns the maximum AI Core and memory for a device
 // depending on whether it has been split into vNPUs or not.
-func getDeviceResources(mgr *AscendManager, devType string, vnpuManager *VnpuManager, deviceName string) (int, int) {
+func getDeviceResources(mgr NpuBackend, devType string, vnpuManager *VnpuManager, deviceName string) (int, int) {
 	if vnpuManager == nil {
 		return 0, 0
 	}
@@ -65,43 +71,281 @@ func getDeviceResources(mgr *AscendManager, devType string, vnpuManager *VnpuMan
 	return maxAicore, maxMemory
 }
 
-// enumerateAllPossibleDevices initializes the devmanager, creates a vNPU manager if possible,
+// templateAttributeName matches the template names that can be published as
+// attribute identifiers.
+var templateAttributeName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// addTemplateAttributes publishes the shape of every vNPU template in
+// templates, so that the cluster can tell which templates exist without
+// access to the node, and whether the device can currently host it, i.e. is
+// among hostable. Templates whose names cannot be attribute identifiers, or
+// that no longer fit into the attribute limit of a device, are skipped.
+func addTemplateAttributes(
+	devAttributes map[resourceapi.QualifiedName]resourceapi.DeviceAttribute,
+	templates map[string]*VnpuTemplate,
+	hostable map[string]*VnpuTemplate,
+) {
+	for _, name := range slices.Sorted(maps.Keys(templates)) {
+		if !templateAttributeName.MatchString(name) || len(name) > resourceapi.DeviceMaxIDLength {
+			log.Printf("Not publishing vNPU template %q: not a valid attribute name", name)
+			continue
+		}
+		if len(devAttributes)+2 > resourceapi.ResourceSliceMaxAttributesAndCapacitiesPerDevice {
+			log.Printf("Not publishing vNPU template %q: too many device attributes", name)
+			continue
+		}
+		attrs := templates[name].Attributes
+		shape := common.TemplateShape{AICore: attrs.AICORE, Memory: attrs.Memory, AICPU: attrs.AICPU, DVPP: attrs.DVPP}
+		devAttributes[resourceapi.QualifiedName(common.TemplateAttributeDomain+"/"+name)] = resourceapi.DeviceAttribute{
+			StringValue: ptr.To(shape.String()),
+		}
+		_, canHost := hostable[name]
+		devAttributes[resourceapi.QualifiedName(common.TemplateHostableAttributeDomain+"/"+name)] = resourceapi.DeviceAttribute{
+			BoolValue: ptr.To(canHost),
+		}
+	}
+}
+
+// enumerateAllPossibleDevices connects to the NPU driver, creates a vNPU manager if possible,
 // and enumerates all possible devices to produce an AllocatableDevices map.
-func enumerateAllPossibleDevices() (AllocatableDevices, *VnpuManager, error) {
-	mgr, err := NewAscendManager()
-	allInfo, _ := mgr.NewHwDevManager()
+// Cards declared in layout are carved accordingly and publish fixed devices.
+// vNPUs that already exist on the chip of other cards are imported, and handed
+// back to their claims if they match a device recorded in prepared.
+// Errors caused by the NPU driver wrap errNpuDriverUnavailable.
+func enumerateAllPossibleDevices(newBackend backendFactory, layout *NodeVnpuLayout, prepared PreparedClaims) (AllocatableDevices, *VnpuManager, NpuBackend, error) {
+	mgr, err := newBackend()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", errNpuDriverUnavailable, err)
+	}
+	allInfo, err := mgr.NewHwDevManager()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: error listing NPU devices: %v", errNpuDriverUnavailable, err)
+	}
 	vnpuManager, err := NewVnpuManager()
 	if err != nil {
 		log.Printf("Failed to initialize vNPU manager: %v. Only full-card allocation is supported.", err)
 	}
+	if layout != nil {
+		if vnpuManager == nil {
+			return nil, nil, nil, fmt.Errorf("a static vNPU layout is declared but no vNPU templates are available")
+		}
+		if err := layout.Validate(vnpuManager.Templates); err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid vNPU layout: %v", err)
+		}
+	}
+
+	adoptions := onChipAdoptions(prepared)
 
 	alldevices := make(AllocatableDevices)
 	for _, dev := range allInfo.AllDevs {
-		deviceName := fmt.Sprintf("npu-%d-0", dev.LogicID)
-		uuidStr := fmt.Sprintf("%s-%d", os.Getenv("NODE_NAME"), dev.LogicID)
+		if err := enumerateCard(mgr, vnpuManager, layout, dev, adoptions[dev.LogicID], alldevices); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return alldevices, vnpuManager, mgr, nil
+}
+
+// enumerateCard initializes the physical NPU a device reported by the driver
+// belongs to and adds the devices it publishes to alldevices. Cards that have
+// already been initialized are skipped, since the driver reports one device
+// per vNPU on carved cards.
+func enumerateCard(
+	mgr NpuBackend,
+	vnpuManager *VnpuManager,
+	layout *NodeVnpuLayout,
+	dev common.NpuDevice,
+	adoptions []onChipAdoption,
+	alldevices AllocatableDevices,
+) error {
+	deviceName := fmt.Sprintf("npu-%d-0", dev.LogicID)
+	if card, ok := layout.Card(dev.LogicID); ok {
+		if _, done := vnpuManager.PhysicalNpus[deviceName]; done {
+			return nil
+		}
+		if err := enumerateStaticCard(mgr, vnpuManager, card, deviceName, alldevices); err != nil {
+			return fmt.Errorf("error applying static vNPU layout: %v", err)
+		}
+		return nil
+	}
+	uuidStr := fmt.Sprintf("%s-%d", os.Getenv("NODE_NAME"), dev.LogicID)
+
+	devAttributes := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		DriverDomain + "index": {IntValue: ptr.To(int64(dev.LogicID))},
+		DriverDomain + "uuid":  {StringValue: ptr.To(uuidStr)},
+		DriverDomain + "model": {StringValue: ptr.To(dev.DevType)},
+		DriverDomain + "type":  {StringValue: ptr.To("NPU")},
+	}
+
+	if vnpuManager != nil {
+		if _, done := vnpuManager.PhysicalNpus[deviceName]; done {
+			return nil
+		}
+		vdevs, err := mgr.GetVirtualDevices(dev.LogicID)
+		if err != nil {
+			log.Printf("Failed to query on-chip vNPUs of device %s: %v", deviceName, err)
+		}
+		if len(vdevs) > 0 {
+			enumerateCarvedCard(mgr, vnpuManager, dev.LogicID, deviceName, vdevs, adoptions, alldevices)
+			return nil
+		}
 
+		vnpuManager.InitPhysicalNpu(deviceName, dev.LogicID, dev.DevType)
+		maxAicore, maxMemory := getDeviceResources(mgr, dev.DevType, vnpuManager, deviceName)
+		vnpuManager.SetCapacity(deviceName, maxAicore, maxMemory)
+		devAttributes[DriverDomain+"aicore"] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(maxAicore))}
+		devAttributes[DriverDomain+"memory"] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(maxMemory))}
+		addTemplateAttributes(devAttributes, vnpuManager.TemplatesFor(dev.DevType), vnpuManager.PhysicalNpus[deviceName].SupportTemplates)
+	}
+
+	device := resourceapi.Device{
+		Name: deviceName,
+		Basic: &resourceapi.BasicDevice{
+			Attributes: devAttributes,
+		},
+	}
+	alldevices[device.Name] = device
+	log.Printf("Discovered NPU device: %s, Type: NPU, Model: %s", deviceName, dev.DevType)
+	return nil
+}
+
+// enumerateStaticCard carves a card according to its declared layout and adds
+// each of its vNPUs, or the whole card if none are declared, as a fixed device.
+func enumerateStaticCard(
+	mgr NpuBackend,
+	vnpuManager *VnpuManager,
+	card *CardVnpuLayout,
+	deviceName string,
+	alldevices AllocatableDevices,
+) error {
+	vdevs, err := carveStaticLayout(mgr, card)
+	if err != nil {
+		return err
+	}
+	modelName, err := mgr.GetChipName(card.LogicID)
+	if err != nil {
+		return fmt.Errorf("failed to get chip name of card %d: %v", card.LogicID, err)
+	}
+
+	vnpuManager.InitStaticNpu(deviceName, card.LogicID, modelName, vdevs)
+	physicalNpu := vnpuManager.PhysicalNpus[deviceName]
+	uuidStr := fmt.Sprintf("%s-%d", os.Getenv("NODE_NAME"), card.LogicID)
+
+	for _, slice := range physicalNpu.AvailableSlices {
 		devAttributes := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
-			DriverDomain + "index": {IntValue: ptr.To(int64(dev.LogicID))},
+			DriverDomain + "index": {IntValue: ptr.To(int64(card.LogicID))},
 			DriverDomain + "uuid":  {StringValue: ptr.To(uuidStr)},
-			DriverDomain + "model": {StringValue: ptr.To(dev.DevType)},
-			DriverDomain + "type":  {StringValue: ptr.To("NPU")},
+			DriverDomain + "model": {StringValue: ptr.To(modelName)},
+			DriverDomain + "type":  {StringValue: ptr.To(slice.Type)},
 		}
 
-		if vnpuManager != nil {
-			vnpuManager.InitPhysicalNpu(deviceName, dev.LogicID, dev.DevType)
-			maxAicore, maxMemory := getDeviceResources(mgr, dev.DevType, vnpuManager, deviceName)
-			devAttributes[DriverDomain+"aicore"] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(maxAicore))}
-			devAttributes[DriverDomain+"memory"] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(maxMemory))}
+		var aicore, memory int
+		// A fixed vNPU can only host its own template.
+		hostable := physicalNpu.SupportTemplates
+		if tpl, ok := vnpuManager.Templates[slice.TemplateName]; ok {
+			aicore, memory = tpl.Attributes.AICORE, tpl.Attributes.Memory
+			hostable = map[string]*VnpuTemplate{slice.TemplateName: tpl}
+			devAttributes[DriverDomain+"template"] = resourceapi.DeviceAttribute{StringValue: ptr.To(slice.TemplateName)}
+		} else {
+			aicore, memory = getDeviceResources(mgr, modelName, vnpuManager, deviceName)
+			vnpuManager.SetCapacity(deviceName, aicore, memory)
 		}
+		devAttributes[DriverDomain+"aicore"] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(aicore))}
+		devAttributes[DriverDomain+"memory"] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(memory))}
+		addTemplateAttributes(devAttributes, vnpuManager.TemplatesFor(modelName), hostable)
 
-		device := resourceapi.Device{
-			Name: deviceName,
+		alldevices[slice.SliceID] = resourceapi.Device{
+			Name: slice.SliceID,
 			Basic: &resourceapi.BasicDevice{
 				Attributes: devAttributes,
 			},
 		}
-		alldevices[device.Name] = device
-		log.Printf("Discovered NPU device: %s, Type: NPU, Model: %s", deviceName, dev.DevType)
+		log.Printf("Discovered static NPU device: %s, Type: %s, Template: %s, Model: %s",
+			slice.SliceID, slice.Type, slice.TemplateName, modelName)
+	}
+	return nil
+}
+
+// enumerateCarvedCard imports the vNPUs that already exist on a dynamically
+// split card and adds its remainder and adopted slices as devices. The whole
+// card is not added while any vNPU exists on it.
+func enumerateCarvedCard(
+	mgr NpuBackend,
+	vnpuManager *VnpuManager,
+	logicID int32,
+	deviceName string,
+	vdevs []VirtualDevice,
+	adoptions []onChipAdoption,
+	alldevices AllocatableDevices,
+) {
+	modelName, err := mgr.GetChipName(logicID)
+	if err != nil {
+		log.Printf("Failed to get chip name of card %d: %v", logicID, err)
+	}
+
+	vnpuManager.InitPhysicalNpu(deviceName, logicID, modelName)
+	aicore, memory := getDeviceResources(mgr, modelName, vnpuManager, deviceName)
+	vnpuManager.SetCapacity(deviceName, aicore, memory)
+
+	adopted := vnpuManager.ImportOnChipSlices(deviceName, vdevs, adoptions)
+	physicalNpu := vnpuManager.PhysicalNpus[deviceName]
+	for _, slice := range append(physicalNpu.AvailableSlices, adopted...) {
+		alldevices[slice.SliceID] = newSliceDevice(slice.SliceID, slice.Type, physicalNpu, vnpuManager)
+	}
+	log.Printf("Discovered carved NPU device: %s with %d on-chip vNPUs (%d adopted), Model: %s",
+		deviceName, len(vdevs), len(adopted), modelName)
+}
+
+// onChipAdoptions collects, per physical NPU, the vNPU slices recorded in the
+// prepared claims that can be matched with vNPUs found on the chip.
+func onChipAdoptions(prepared PreparedClaims) map[int32][]onChipAdoption {
+	adoptions := make(map[int32][]onChipAdoption)
+	for claimUID, devices := range prepared {
+		for _, device := range devices {
+			if device.AdminAccess {
+				continue
+			}
+			templateName := device.TemplateName
+			if templateName == "" && device.ContainerEdits != nil && device.ContainerEdits.ContainerEdits != nil {
+				// Checkpoints written before the template was recorded
+				// still carry it in the container environment.
+				for _, env := range device.ContainerEdits.Env {
+					if value, ok := strings.CutPrefix(env, "ASCEND_VNPU_SPECS="); ok {
+						templateName = value
+					}
+				}
+			}
+			logicID, _, ok := parseSliceID(device.DeviceName)
+			if templateName == "" || !ok {
+				continue
+			}
+			adoptions[logicID] = append(adoptions[logicID], onChipAdoption{
+				ClaimUID:     claimUID,
+				SliceID:      device.DeviceName,
+				TemplateName: templateName,
+				VDevID:       device.VDevID,
+			})
+		}
+	}
+	return adoptions
+}
+
+// republishCard replaces the devices of a physical NPU in allocatable with
+// its free slices, unless it is unavailable, and the slices of its claims.
+func republishCard(allocatable AllocatableDevices, npu *PhysicalNpuState, vnpuManager *VnpuManager) {
+	maps.DeleteFunc(allocatable, func(name string, _ resourceapi.Device) bool {
+		logicID, _, ok := parseSliceID(name)
+		return ok && logicID == npu.LogicID
+	})
+	var published []*VnpuSlice
+	if !npu.Unavailable {
+		published = append(published, npu.AvailableSlices...)
+	}
+	for _, slice := range npu.AllocatedSlices {
+		if slice.ClaimUID != "" {
+			published = append(published, slice)
+		}
+	}
+	for _, slice := range published {
+		allocatable[slice.SliceID] = newSliceDevice(slice.SliceID, slice.Type, npu, vnpuManager)
 	}
-	return alldevices, vnpuManager, nil
 }
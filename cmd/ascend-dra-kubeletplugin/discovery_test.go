@@ -0,0 +1,362 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resourceapi "k8s.io/api/resource/v1beta1"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+	"k8s.io/utils/ptr"
+	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+
+	configapi "Ascend-dra-driver/api/example.com/resource/npu"
+	"Ascend-dra-driver/pkg/common"
+)
+
+// fakeBackend is an NPU driver whose cards and failures are set by tests.
+type fakeBackend struct {
+	devices     []common.NpuDevice
+	vdevs       map[int32][]VirtualDevice
+	phyIDs      map[int32]int32
+	listErr     error
+	vdevErr     error
+	chipNameErr error
+	createErr   error
+	created     []string
+}
+
+func (f *fakeBackend) NewHwDevManager() (common.NpuAllInfo, error) {
+	if f.listErr != nil {
+		return common.NpuAllInfo{}, f.listErr
+	}
+	return common.NpuAllInfo{AllDevs: f.devices}, nil
+}
+
+func (f *fakeBackend) GetChipAiCoreCount() (int32, error) { return 8, nil }
+
+func (f *fakeBackend) GetChipMem() (int32, error) { return 21, nil }
+
+func (f *fakeBackend) GetChipName(logicID int32) (string, error) {
+	if f.chipNameErr != nil {
+		return "", f.chipNameErr
+	}
+	return "310P3", nil
+}
+
+func (f *fakeBackend) GetPhysicIDFromLogicID(logicID int32) (int32, error) {
+	if phyID, ok := f.phyIDs[logicID]; ok {
+		return phyID, nil
+	}
+	return logicID, nil
+}
+
+func (f *fakeBackend) GetVirtualDevices(logicID int32) ([]VirtualDevice, error) {
+	if f.vdevErr != nil {
+		return nil, f.vdevErr
+	}
+	return f.vdevs[logicID], nil
+}
+
+func (f *fakeBackend) CreateVirtualDevice(logicID int32, templateName string) (uint32, error) {
+	if f.createErr != nil {
+		return 0, f.createErr
+	}
+	f.created = append(f.created, templateName)
+	return uint32(100 + len(f.created)), nil
+}
+
+// backendOf returns a factory handing out backend, or failing with err.
+func backendOf(backend NpuBackend, err error) backendFactory {
+	return func() (NpuBackend, error) {
+		if err != nil {
+			return nil, err
+		}
+		return backend, nil
+	}
+}
+
+func twoCards() []common.NpuDevice {
+	return []common.NpuDevice{
+		{DevType: "310P3", DeviceName: "310P3-0", LogicID: 0},
+		{DevType: "310P3", DeviceName: "310P3-1", LogicID: 1},
+	}
+}
+
+func TestEnumerateAllPossibleDevices(t *testing.T) {
+	driverErr := errors.New("dcmi error")
+
+	tests := map[string]struct {
+		newBackend        backendFactory
+		layout            *NodeVnpuLayout
+		expected          []string
+		expectUnavailable bool
+		expectedErr       bool
+	}{
+		"driver cannot be initialized": {
+			newBackend:        backendOf(nil, driverErr),
+			expectUnavailable: true,
+		},
+		"devices cannot be listed": {
+			newBackend:        backendOf(&fakeBackend{listErr: driverErr}, nil),
+			expectUnavailable: true,
+		},
+		"whole cards": {
+			newBackend: backendOf(&fakeBackend{devices: twoCards()}, nil),
+			expected:   []string{"npu-0-0", "npu-1-0"},
+		},
+		"on-chip vNPUs cannot be queried": {
+			newBackend: backendOf(&fakeBackend{devices: twoCards(), vdevErr: driverErr}, nil),
+			expected:   []string{"npu-0-0", "npu-1-0"},
+		},
+		"carved card": {
+			newBackend: backendOf(&fakeBackend{
+				devices: twoCards(),
+				vdevs:   map[int32][]VirtualDevice{1: {{VDevID: 100, TemplateName: "vir02"}}},
+			}, nil),
+			expected: []string{"npu-0-0", "npu-1-2"},
+		},
+		"static layout": {
+			newBackend: backendOf(&fakeBackend{devices: twoCards()}, nil),
+			layout:     &NodeVnpuLayout{Cards: []CardVnpuLayout{{LogicID: 0, Templates: []string{"vir02", "vir02"}}}},
+			expected:   []string{"npu-0-1", "npu-0-2", "npu-1-0"},
+		},
+		"static layout with unknown template": {
+			newBackend:  backendOf(&fakeBackend{devices: twoCards()}, nil),
+			layout:      &NodeVnpuLayout{Cards: []CardVnpuLayout{{LogicID: 0, Templates: []string{"vir99"}}}},
+			expectedErr: true,
+		},
+		"static layout cannot be carved": {
+			newBackend:  backendOf(&fakeBackend{devices: twoCards(), createErr: driverErr}, nil),
+			layout:      &NodeVnpuLayout{Cards: []CardVnpuLayout{{LogicID: 0, Templates: []string{"vir02"}}}},
+			expectedErr: true,
+		},
+		"static card chip name cannot be read": {
+			newBackend:  backendOf(&fakeBackend{devices: twoCards(), chipNameErr: driverErr}, nil),
+			layout:      &NodeVnpuLayout{Cards: []CardVnpuLayout{{LogicID: 0}}},
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			allocatable, _, _, err := enumerateAllPossibleDevices(test.newBackend, test.layout, nil)
+			switch {
+			case test.expectUnavailable:
+				assert.True(t, isNpuDriverUnavailable(err), "expected driver unavailable, got %v", err)
+				return
+			case test.expectedErr:
+				assert.Error(t, err)
+				assert.False(t, isNpuDriverUnavailable(err))
+				return
+			}
+			assert.NoError(t, err)
+			var names []string
+			for name := range allocatable {
+				names = append(names, name)
+			}
+			assert.ElementsMatch(t, test.expected, names)
+		})
+	}
+}
+
+func TestTemplateAttributes(t *testing.T) {
+	allocatable, _, _, err := enumerateAllPossibleDevices(backendOf(&fakeBackend{
+		devices: twoCards(),
+		vdevs:   map[int32][]VirtualDevice{1: {{VDevID: 100, TemplateName: "vir02"}}},
+	}, nil), nil, nil)
+	assert.NoError(t, err)
+
+	for name, device := range allocatable {
+		value := device.Basic.Attributes[common.TemplateAttributeDomain+"/vir02"].StringValue
+		if assert.NotNil(t, value, "device %s", name) {
+			assert.Equal(t, "aicore=8,memory=12,aicpu=2,dvpp=true", *value)
+		}
+		assert.Contains(t, device.Basic.Attributes, resourceapi.QualifiedName(common.TemplateAttributeDomain+"/vir01"))
+		assert.Contains(t, device.Basic.Attributes, resourceapi.QualifiedName(common.TemplateAttributeDomain+"/vir04"))
+	}
+
+	// The whole card can host every template, the rest of the carved card
+	// only the smallest ones.
+	hostable := func(device, template string) bool {
+		return *allocatable[device].Basic.Attributes[resourceapi.QualifiedName(common.TemplateHostableAttributeDomain+"/"+template)].BoolValue
+	}
+	assert.True(t, hostable("npu-0-0", "vir02"))
+	assert.True(t, hostable("npu-0-0", "vir04"))
+	assert.False(t, hostable("npu-1-2", "vir02"))
+	assert.True(t, hostable("npu-1-2", "vir01"))
+
+	attrs := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{}
+	templates := map[string]*VnpuTemplate{
+		"vir02":      {Name: "vir02"},
+		"vir-02":     {Name: "vir-02"},
+		"2c.1cpu":    {Name: "2c.1cpu"},
+		"vir02_1c_x": {Name: "vir02_1c_x", Attributes: VnpuTemplateAttribute{AICORE: 2}},
+	}
+	addTemplateAttributes(attrs, templates, map[string]*VnpuTemplate{"vir02": templates["vir02"]})
+	assert.Len(t, attrs, 4)
+	assert.True(t, *attrs[common.TemplateHostableAttributeDomain+"/vir02"].BoolValue)
+	assert.False(t, *attrs[common.TemplateHostableAttributeDomain+"/vir02_1c_x"].BoolValue)
+}
+
+func preparedSlice(deviceName, templateName string, vdevID *uint32) *PreparedDevice {
+	return &PreparedDevice{
+		Device:       drapbv1.Device{DeviceName: deviceName},
+		TemplateName: templateName,
+		VDevID:       vdevID,
+	}
+}
+
+func TestOnChipAdoptions(t *testing.T) {
+	vdevID := uint32(101)
+	legacy := preparedSlice("npu-1-3", "", nil)
+	legacy.ContainerEdits = &cdiapi.ContainerEdits{ContainerEdits: &cdispec.ContainerEdits{
+		Env: []string{"ASCEND_VISIBLE_DEVICES=1", "ASCEND_VNPU_SPECS=vir04"},
+	}}
+	admin := preparedSlice("npu-0-1", "vir02", nil)
+	admin.AdminAccess = true
+
+	adoptions := onChipAdoptions(PreparedClaims{
+		"claim-a": {preparedSlice("npu-0-1", "vir02", &vdevID), preparedSlice("npu-1-2", "vir01", nil)},
+		"claim-b": {legacy, preparedSlice("npu-0-0", "", nil)},
+		"monitor": {admin},
+	})
+
+	assert.Equal(t, []onChipAdoption{
+		{ClaimUID: "claim-a", SliceID: "npu-0-1", TemplateName: "vir02", VDevID: &vdevID},
+	}, adoptions[0])
+	assert.ElementsMatch(t, []onChipAdoption{
+		{ClaimUID: "claim-a", SliceID: "npu-1-2", TemplateName: "vir01"},
+		{ClaimUID: "claim-b", SliceID: "npu-1-3", TemplateName: "vir04"},
+	}, adoptions[1])
+}
+
+func TestEnumerateCarvedCard(t *testing.T) {
+	backend := &fakeBackend{
+		devices: twoCards(),
+		vdevs: map[int32][]VirtualDevice{1: {
+			{VDevID: 100, TemplateName: "vir02"},
+			{VDevID: 101, TemplateName: "vir02"},
+			{VDevID: 102, TemplateName: "vir01"},
+		}},
+	}
+	prepared := PreparedClaims{
+		"claim-a": {preparedSlice("npu-1-1", "vir02", ptr.To(uint32(101)))},
+		"claim-b": {preparedSlice("npu-1-2", "vir02", ptr.To(uint32(100)))},
+	}
+
+	allocatable, vnpuManager, _, err := enumerateAllPossibleDevices(backendOf(backend, nil), nil, prepared)
+	require.NoError(t, err)
+
+	// The adopted slices keep their IDs and are published next to the
+	// remainder of the card, the unowned vNPU and the whole card are not.
+	assert.ElementsMatch(t, []string{"npu-0-0", "npu-1-1", "npu-1-2", "npu-1-4"}, slices.Collect(maps.Keys(allocatable)))
+
+	npu := vnpuManager.PhysicalNpus["npu-1-0"]
+	require.NotNil(t, npu)
+	owners := make(map[string]string)
+	for _, slice := range npu.AllocatedSlices {
+		owners[slice.SliceID] = fmt.Sprintf("%s/%d", slice.ClaimUID, slice.VDevID)
+	}
+	assert.Equal(t, map[string]string{
+		"npu-1-1": "claim-a/101",
+		"npu-1-2": "claim-b/100",
+		"npu-1-3": "/102",
+	}, owners)
+	assert.Equal(t, map[string]*VnpuTemplate{"vir01": vnpuManager.Templates["vir01"]}, npu.SupportTemplates)
+	assert.Equal(t, "vNPU", *allocatable["npu-1-4"].Basic.Attributes[DriverDomain+"type"].StringValue)
+}
+
+func TestPrepareRecordsOnChipVDevID(t *testing.T) {
+	config := newTestConfig(t)
+	config.flags.vnpuLayoutFile = filepath.Join(t.TempDir(), "layout.yaml")
+	require.NoError(t, os.WriteFile(config.flags.vnpuLayoutFile, []byte(`
+nodes:
+  node-1:
+    cards:
+    - logicID: 0
+      templates: [vir02, vir02]
+`), 0o600))
+	state, err := newDeviceState(config, t.TempDir(), backendOf(&fakeBackend{devices: twoCards()}, nil))
+	require.NoError(t, err)
+
+	_, err = state.Prepare(newTestClaim("claim", []string{"npu-0-2", "npu-1-0"}))
+	require.NoError(t, err)
+
+	vdevIDs := make(map[string]*uint32)
+	for _, device := range state.checkpoint.V1.PreparedClaims["claim"] {
+		vdevIDs[device.DeviceName] = device.VDevID
+	}
+	assert.Equal(t, map[string]*uint32{"npu-0-2": ptr.To(uint32(102)), "npu-1-0": nil}, vdevIDs)
+}
+
+func TestRestartReservesPreparedSlices(t *testing.T) {
+	config := newTestConfig(t)
+	config.flags.maxTimeSlicedClaims = 2
+	checkpointDir := t.TempDir()
+	state, err := newDeviceState(config, checkpointDir, backendOf(&fakeBackend{devices: cards(3)}, nil))
+	require.NoError(t, err)
+	_, err = state.Prepare(newTestClaim("whole", []string{"npu-0-0"}))
+	require.NoError(t, err)
+	_, err = state.Prepare(newTestClaim("vnpu", []string{"npu-1-0"}, vnpuConfig(`{"template":"vir01"}`)))
+	require.NoError(t, err)
+	_, err = state.Prepare(newTestClaim("shared", []string{"npu-2-0"}, sharingConfig(configapi.TimeSlicingStrategy)))
+	require.NoError(t, err)
+	published := publishedNames(state)
+
+	// None of the slices exist on the chip, the restarted plugin reserves
+	// them from the checkpoint before publishing its devices.
+	state, err = newDeviceState(config, checkpointDir, backendOf(&fakeBackend{devices: cards(3)}, nil))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, published, publishedNames(state))
+	for deviceName, claimUID := range map[string]string{"npu-0-0": "whole", "npu-1-0": "vnpu", "npu-2-0": "shared"} {
+		_, _, slice, err := state.vnpuManager.findAllocatedSlice(deviceName)
+		require.NoError(t, err, deviceName)
+		assert.Equal(t, claimUID, slice.ClaimUID, deviceName)
+	}
+	assert.True(t, state.vnpuManager.IsTimeSliced("npu-2-0"))
+
+	_, err = state.Prepare(newTestClaim("other", []string{"npu-0-0"}))
+	assert.ErrorContains(t, err, "npu-0-0")
+	_, err = state.Prepare(newTestClaim("other", []string{"npu-1-1"}, vnpuConfig(`{"template":"vir01"}`)))
+	require.NoError(t, err, "the remainder of the vNPU card is free")
+	_, err = state.Prepare(newTestClaim("time-sliced", []string{"npu-2-1"}, sharingConfig(configapi.TimeSlicingStrategy)))
+	require.NoError(t, err, "the time slice of the shared card is free")
+
+	// Releasing the whole card makes it allocatable again.
+	require.NoError(t, state.Unprepare("whole"))
+	_, err = state.Prepare(newTestClaim("next", []string{"npu-0-0"}))
+	require.NoError(t, err)
+}
+
+func TestRestartFailsForUnreservableClaim(t *testing.T) {
+	writeLayout := func(path string, templates string) {
+		require.NoError(t, os.WriteFile(path, []byte(`
+nodes:
+  node-1:
+    cards:
+    - logicID: 0
+      templates: `+templates+`
+`), 0o600))
+	}
+	config := newTestConfig(t)
+	config.flags.vnpuLayoutFile = filepath.Join(t.TempDir(), "layout.yaml")
+	writeLayout(config.flags.vnpuLayoutFile, "[vir02, vir02]")
+	checkpointDir := t.TempDir()
+	state, err := newDeviceState(config, checkpointDir, backendOf(&fakeBackend{devices: twoCards()}, nil))
+	require.NoError(t, err)
+	_, err = state.Prepare(newTestClaim("claim", []string{"npu-0-2"}))
+	require.NoError(t, err)
+
+	// The new layout no longer has the slice of the prepared claim.
+	writeLayout(config.flags.vnpuLayoutFile, "[vir04]")
+	_, err = newDeviceState(config, checkpointDir, backendOf(&fakeBackend{devices: twoCards()}, nil))
+	assert.ErrorContains(t, err, "prepared devices not allocated to their claims: npu-0-2 of claim claim")
+}
@@ -19,10 +19,11 @@ package main
 import (
 	"context"
 	"fmt"
+	"sync"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	coreclientset "k8s.io/client-go/kubernetes"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	"k8s.io/dynamic-resource-allocation/resourceslice"
 	"k8s.io/klog/v2"
 
 	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
@@ -31,14 +32,29 @@ import (
 var _ drapbv1.DRAPluginServer = &driver{}
 
 type driver struct {
-	client coreclientset.Interface
-	plugin kubeletplugin.DRAPlugin
-	state  *DeviceState
+	claims    *claimCache
+	plugin    kubeletplugin.DRAPlugin
+	publisher *resourcePublisher
+	state     *DeviceState
+	debug     *debugServer
+	cancel    context.CancelFunc
+	status    *npuDriverStatus
+	reporter  *nodeStatusReporter
+
+	nodeName        string
+	publisherClient coreclientset.Interface
+	// sliceController publishes the ResourceSlices once the devices have
+	// been published for the first time.
+	sliceController *resourceslice.Controller
 }
 
 func NewDriver(ctx context.Context, config *Config) (*driver, error) {
 	driver := &driver{
-		client: config.coreclient,
+		claims:          newClaimCache(config.clientSets.Core, config.flags.nodeName),
+		status:          &npuDriverStatus{state: npuDriverInitializing},
+		reporter:        newNodeStatusReporter(config.clientSets.Core, config.flags.nodeName),
+		nodeName:        config.flags.nodeName,
+		publisherClient: config.clientSets.Publisher,
 	}
 
 	state, err := NewDeviceState(config)
@@ -46,11 +62,13 @@ func NewDriver(ctx context.Context, config *Config) (*driver, error) {
 		return nil, err
 	}
 	driver.state = state
+	driver.publisher = newResourcePublisher(driver.publishSlices, state.publishableDevices,
+		config.flags.nodeName, config.flags.publishDelay)
+	state.onChange = driver.publisher.Notify
 
 	plugin, err := kubeletplugin.Start(
 		ctx,
 		[]any{driver},
-		kubeletplugin.KubeClient(config.coreclient),
 		kubeletplugin.NodeName(config.flags.nodeName),
 		kubeletplugin.DriverName(DriverName),
 		kubeletplugin.RegistrarSocketPath(PluginRegistrationPath),
@@ -61,55 +79,118 @@ func NewDriver(ctx context.Context, config *Config) (*driver, error) {
 	}
 	driver.plugin = plugin
 
-	var resources kubeletplugin.Resources
-	for _, device := range state.allocatable {
-		resources.Devices = append(resources.Devices, device)
+	// Without a usable NPU driver an empty ResourceSlice is published, so
+	// that no stale devices get scheduled, until the driver recovers. The
+	// ResourceSlice controller keeps running with ctx.
+	if err := driver.publisher.Publish(ctx); err != nil {
+		return nil, err
 	}
 
-	if err := plugin.PublishResources(ctx, resources); err != nil {
-		return nil, err
+	loopCtx, cancel := context.WithCancel(ctx)
+	driver.cancel = cancel
+	driver.claims.Start(loopCtx)
+	go driver.publisher.Run(loopCtx)
+	if state.Ready() {
+		driver.setNpuDriverState(ctx, npuDriverReady, "")
+	} else {
+		go driver.recoverNpuDriver(loopCtx, npuDriverBackoff)
+	}
+	if config.flags.rediscoveryInterval > 0 {
+		go driver.runRediscovery(loopCtx, config.flags.rediscoveryInterval, npuDriverBackoff)
+	}
+
+	if config.flags.httpEndpoint != "" {
+		debug, err := startDebugServer(ctx, config.flags.httpEndpoint, driver)
+		if err != nil {
+			return nil, fmt.Errorf("start debug server: %w", err)
+		}
+		driver.debug = debug
 	}
 
 	return driver, nil
 }
 
 func (d *driver) Shutdown(ctx context.Context) error {
+	if d.cancel != nil {
+		d.cancel()
+		d.claims.Stop()
+	}
+	if d.debug != nil {
+		if err := d.debug.Stop(ctx); err != nil {
+			klog.FromContext(ctx).Error(err, "Unable to stop debug server")
+		}
+	}
+	d.reporter.Stop()
 	d.plugin.Stop()
+	if d.sliceController != nil {
+		d.sliceController.Stop()
+	}
 	return nil
 }
 
-func (d *driver) NodePrepareResources(ctx context.Context, req *drapbv1.NodePrepareResourcesRequest) (*drapbv1.NodePrepareResourcesResponse, error) {
-	klog.Infof("NodePrepareResource is called: number of claims: %d", len(req.Claims))
-	preparedResources := &drapbv1.NodePrepareResourcesResponse{Claims: map[string]*drapbv1.NodePrepareResourceResponse{}}
-
-	for _, claim := range req.Claims {
-		preparedResources.Claims[claim.UID] = d.nodePrepareResource(ctx, claim)
+// publishSlices hands the pools to publish to the ResourceSlice controller,
+// starting it with ctx the first time. The kubelet plugin library only
+// publishes a single pool, so the driver runs the controller itself.
+func (d *driver) publishSlices(ctx context.Context, resources *resourceslice.DriverResources) error {
+	if d.sliceController != nil {
+		d.sliceController.Update(resources)
+		return nil
 	}
+	controller, err := resourceslice.StartController(ctx, resourceslice.Options{
+		DriverName: DriverName,
+		KubeClient: d.publisherClient,
+		Owner: &resourceslice.Owner{
+			APIVersion: "v1",
+			Kind:       "Node",
+			Name:       d.nodeName,
+		},
+		Resources: resources,
+	})
+	if err != nil {
+		return fmt.Errorf("start ResourceSlice controller: %w", err)
+	}
+	d.sliceController = controller
+	return nil
+}
 
-	d.syncAllocatable()
-
-	var resources kubeletplugin.Resources
-	for _, deviceName := range d.state.allocatable {
-		resources.Devices = append(resources.Devices, deviceName)
+// forEachClaim handles all claims concurrently and returns the responses by
+// claim UID. The device state serializes claims sharing a physical NPU.
+func forEachClaim[T any](claims []*drapbv1.Claim, handle func(claim *drapbv1.Claim) T) map[string]T {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	responses := make(map[string]T, len(claims))
+	for _, claim := range claims {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response := handle(claim)
+			mu.Lock()
+			defer mu.Unlock()
+			responses[claim.UID] = response
+		}()
 	}
+	wg.Wait()
+	return responses
+}
 
-	if err := d.plugin.PublishResources(ctx, resources); err != nil {
-		klog.Errorf("Failed to publish resources after preparing claims: %v", err)
-	} else {
-		klog.Infof("Successfully published updated resources after preparing %d claims", len(req.Claims))
+func (d *driver) NodePrepareResources(ctx context.Context, req *drapbv1.NodePrepareResourcesRequest) (*drapbv1.NodePrepareResourcesResponse, error) {
+	klog.Infof("NodePrepareResource is called: number of claims: %d", len(req.Claims))
+	preparedResources := &drapbv1.NodePrepareResourcesResponse{
+		Claims: forEachClaim(req.Claims, func(claim *drapbv1.Claim) *drapbv1.NodePrepareResourceResponse {
+			return d.nodePrepareResource(ctx, claim)
+		}),
 	}
 
+	// Whole cards and the slices of carved ones change without being added.
+	d.publisher.Notify()
 	return preparedResources, nil
 }
 
 func (d *driver) nodePrepareResource(ctx context.Context, claim *drapbv1.Claim) *drapbv1.NodePrepareResourceResponse {
-	resourceClaim, err := d.client.ResourceV1beta1().ResourceClaims(claim.Namespace).Get(
-		ctx,
-		claim.Name,
-		metav1.GetOptions{})
+	resourceClaim, err := d.claims.Get(ctx, claim.Namespace, claim.Name, claim.UID)
 	if err != nil {
 		return &drapbv1.NodePrepareResourceResponse{
-			Error: fmt.Sprintf("failed to fetch ResourceClaim %s in namespace %s", claim.Name, claim.Namespace),
+			Error: fmt.Sprintf("failed to fetch ResourceClaim %s in namespace %s: %v", claim.Name, claim.Namespace, err),
 		}
 	}
 
@@ -126,25 +207,13 @@ func (d *driver) nodePrepareResource(ctx context.Context, claim *drapbv1.Claim)
 
 func (d *driver) NodeUnprepareResources(ctx context.Context, req *drapbv1.NodeUnprepareResourcesRequest) (*drapbv1.NodeUnprepareResourcesResponse, error) {
 	klog.Infof("NodeUnPrepareResource is called: number of claims: %d", len(req.Claims))
-	unpreparedResources := &drapbv1.NodeUnprepareResourcesResponse{Claims: map[string]*drapbv1.NodeUnprepareResourceResponse{}}
-
-	for _, claim := range req.Claims {
-		unpreparedResources.Claims[claim.UID] = d.nodeUnprepareResource(ctx, claim)
-	}
-
-	d.syncAllocatable()
-
-	var resources kubeletplugin.Resources
-	for _, device := range d.state.allocatable {
-		resources.Devices = append(resources.Devices, device)
-	}
-
-	if err := d.plugin.PublishResources(ctx, resources); err != nil {
-		klog.Errorf("Failed to publish resources after unpreparing claims: %v", err)
-	} else {
-		klog.Infof("Successfully published updated resources after unpreparing %d claims", len(req.Claims))
+	unpreparedResources := &drapbv1.NodeUnprepareResourcesResponse{
+		Claims: forEachClaim(req.Claims, func(claim *drapbv1.Claim) *drapbv1.NodeUnprepareResourceResponse {
+			return d.nodeUnprepareResource(ctx, claim)
+		}),
 	}
 
+	d.publisher.Notify()
 	return unpreparedResources, nil
 }
 
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+)
+
+// prepareJournal records the side effects of preparing a claim, so that a
+// Prepare that fails part way can undo them and leave the node as it was.
+type prepareJournal struct {
+	entries []journalEntry
+}
+
+type journalEntry struct {
+	action string
+	undo   func() error
+}
+
+// record adds an action that has been carried out, along with the function
+// undoing it.
+func (j *prepareJournal) record(action string, undo func() error) {
+	j.entries = append(j.entries, journalEntry{action: action, undo: undo})
+}
+
+// rollback undoes the recorded actions in reverse order. An action that
+// cannot be undone does not stop the others from being undone.
+func (j *prepareJournal) rollback() error {
+	var errs []error
+	for _, entry := range slices.Backward(j.entries) {
+		if err := entry.undo(); err != nil {
+			errs = append(errs, fmt.Errorf("undo %s: %w", entry.action, err))
+			continue
+		}
+		log.Printf("Rolled back %s", entry.action)
+	}
+	j.entries = nil
+	return errors.Join(errs...)
+}
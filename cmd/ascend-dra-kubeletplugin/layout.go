@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+)
+
+// VnpuLayoutFile is the administrator-provided file declaring static vNPU
+// layouts, keyed by node name.
+type VnpuLayoutFile struct {
+	Nodes map[string]NodeVnpuLayout `json:"nodes"`
+}
+
+// NodeVnpuLayout declares the static vNPU layout of the cards on one node.
+// Cards that are not listed keep being split on demand.
+type NodeVnpuLayout struct {
+	Cards []CardVnpuLayout `json:"cards"`
+}
+
+// CardVnpuLayout declares the vNPUs to carve from one physical NPU. A card
+// without templates is published as a fixed whole card.
+type CardVnpuLayout struct {
+	LogicID   int32    `json:"logicID"`
+	Templates []string `json:"templates,omitempty"`
+}
+
+// LoadVnpuLayout reads the static vNPU layout declared for nodeName. It
+// returns nil if path is empty or the file has no entry for the node.
+func LoadVnpuLayout(path, nodeName string) (*NodeVnpuLayout, error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read vNPU layout file: %w", err)
+	}
+	var file VnpuLayoutFile
+	if err := yaml.UnmarshalStrict(content, &file); err != nil {
+		return nil, fmt.Errorf("parse vNPU layout file %s: %w", path, err)
+	}
+	layout, ok := file.Nodes[nodeName]
+	if !ok {
+		return nil, nil
+	}
+	return &layout, nil
+}
+
+// Validate ensures that the layout only references known templates and
+// declares every card at most once.
+func (l *NodeVnpuLayout) Validate(templates map[string]*VnpuTemplate) error {
+	seen := make(map[int32]bool)
+	for _, card := range l.Cards {
+		if seen[card.LogicID] {
+			return fmt.Errorf("card %d is declared more than once", card.LogicID)
+		}
+		seen[card.LogicID] = true
+		for _, name := range card.Templates {
+			if _, ok := templates[name]; !ok {
+				return fmt.Errorf("card %d: unknown vNPU template %q", card.LogicID, name)
+			}
+		}
+	}
+	return nil
+}
+
+// Card returns the declared layout of the card with the given logic ID.
+func (l *NodeVnpuLayout) Card(logicID int32) (*CardVnpuLayout, bool) {
+	if l == nil {
+		return nil, false
+	}
+	for i := range l.Cards {
+		if l.Cards[i].LogicID == logicID {
+			return &l.Cards[i], true
+		}
+	}
+	return nil, false
+}
+
+// carveStaticLayout makes sure the vNPUs declared for a card exist on the
+// chip, creating them if the card is not carved yet and adopting them if an
+// identical layout is already present.
+func carveStaticLayout(mgr NpuBackend, card *CardVnpuLayout) ([]VirtualDevice, error) {
+	existing, err := mgr.GetVirtualDevices(card.LogicID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(existing) > 0 {
+		var names []string
+		for _, vdev := range existing {
+			names = append(names, vdev.TemplateName)
+		}
+		want := slices.Clone(card.Templates)
+		sort.Strings(names)
+		sort.Strings(want)
+		if !slices.Equal(names, want) {
+			return nil, fmt.Errorf("card %d is already carved as %v, which does not match the declared layout %v",
+				card.LogicID, names, card.Templates)
+		}
+		return existing, nil
+	}
+
+	var created []VirtualDevice
+	for _, name := range card.Templates {
+		vdevID, err := mgr.CreateVirtualDevice(card.LogicID, name)
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, VirtualDevice{VDevID: vdevID, TemplateName: name})
+	}
+	return created, nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resourceapi "k8s.io/api/resource/v1beta1"
+
+	"Ascend-dra-driver/pkg/common"
+)
+
+func TestLoadVnpuLayout(t *testing.T) {
+	tests := map[string]struct {
+		content  string
+		noFile   bool
+		expected *NodeVnpuLayout
+		err      string
+	}{
+		"no file": {
+			noFile: true,
+		},
+		"node": {
+			content: `
+nodes:
+  node-1:
+    cards:
+    - logicID: 0
+      templates: [vir02, vir02]
+    - logicID: 1
+  node-2:
+    cards:
+    - logicID: 0
+`,
+			expected: &NodeVnpuLayout{Cards: []CardVnpuLayout{
+				{LogicID: 0, Templates: []string{"vir02", "vir02"}},
+				{LogicID: 1},
+			}},
+		},
+		"other node": {
+			content: `
+nodes:
+  node-2:
+    cards:
+    - logicID: 0
+`,
+		},
+		"unknown field": {
+			content: `
+nodes:
+  node-1:
+    cards:
+    - logicID: 0
+      template: vir02
+`,
+			err: "parse vNPU layout file",
+		},
+		"invalid logic ID": {
+			content: `
+nodes:
+  node-1:
+    cards:
+    - logicID: first
+`,
+			err: "parse vNPU layout file",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := ""
+			if !test.noFile {
+				path = filepath.Join(t.TempDir(), "layout.yaml")
+				require.NoError(t, os.WriteFile(path, []byte(test.content), 0o600))
+			}
+			layout, err := LoadVnpuLayout(path, testNodeName)
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, layout)
+		})
+	}
+
+	_, err := LoadVnpuLayout(filepath.Join(t.TempDir(), "missing.yaml"), testNodeName)
+	assert.ErrorContains(t, err, "read vNPU layout file")
+}
+
+func TestNodeVnpuLayoutValidate(t *testing.T) {
+	tests := map[string]struct {
+		layout *NodeVnpuLayout
+		err    string
+	}{
+		"valid": {
+			layout: &NodeVnpuLayout{Cards: []CardVnpuLayout{
+				{LogicID: 0, Templates: []string{"vir02", "vir04"}},
+				{LogicID: 1},
+			}},
+		},
+		"card declared twice": {
+			layout: &NodeVnpuLayout{Cards: []CardVnpuLayout{{LogicID: 1}, {LogicID: 1, Templates: []string{"vir02"}}}},
+			err:    "card 1 is declared more than once",
+		},
+		"unknown template": {
+			layout: &NodeVnpuLayout{Cards: []CardVnpuLayout{{LogicID: 0, Templates: []string{"vir02", "vir03"}}}},
+			err:    `card 0: unknown vNPU template "vir03"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.layout.Validate(templates310P())
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCarveStaticLayout(t *testing.T) {
+	driverErr := errors.New("dcmi error")
+	card := &CardVnpuLayout{LogicID: 0, Templates: []string{"vir04", "vir02"}}
+
+	tests := map[string]struct {
+		backend         *fakeBackend
+		expected        []VirtualDevice
+		expectedCreated []string
+		err             string
+	}{
+		"not carved": {
+			backend:         &fakeBackend{},
+			expected:        []VirtualDevice{{VDevID: 101, TemplateName: "vir04"}, {VDevID: 102, TemplateName: "vir02"}},
+			expectedCreated: []string{"vir04", "vir02"},
+		},
+		"carved as declared": {
+			backend: &fakeBackend{vdevs: map[int32][]VirtualDevice{
+				0: {{VDevID: 7, TemplateName: "vir02"}, {VDevID: 8, TemplateName: "vir04"}},
+			}},
+			expected: []VirtualDevice{{VDevID: 7, TemplateName: "vir02"}, {VDevID: 8, TemplateName: "vir04"}},
+		},
+		"carved differently": {
+			backend: &fakeBackend{vdevs: map[int32][]VirtualDevice{
+				0: {{VDevID: 7, TemplateName: "vir02"}, {VDevID: 8, TemplateName: "vir02"}},
+			}},
+			err: "card 0 is already carved as [vir02 vir02], which does not match the declared layout [vir04 vir02]",
+		},
+		"on-chip vNPUs cannot be queried": {
+			backend: &fakeBackend{vdevErr: driverErr},
+			err:     "dcmi error",
+		},
+		"vNPUs cannot be created": {
+			backend: &fakeBackend{createErr: driverErr},
+			err:     "dcmi error",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			vdevs, err := carveStaticLayout(test.backend, card)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, vdevs)
+			assert.Equal(t, test.expectedCreated, test.backend.created)
+		})
+	}
+}
+
+func TestEnumerateStaticCard(t *testing.T) {
+	layout := &NodeVnpuLayout{Cards: []CardVnpuLayout{
+		{LogicID: 0, Templates: []string{"vir02", "vir01"}},
+		{LogicID: 1},
+		// Cards the driver does not report are ignored.
+		{LogicID: 5, Templates: []string{"vir02"}},
+	}}
+	allocatable, vnpuManager, _, err := enumerateAllPossibleDevices(backendOf(&fakeBackend{devices: twoCards()}, nil), layout, nil)
+	require.NoError(t, err)
+
+	attribute := func(device, name string) resourceapi.DeviceAttribute {
+		return allocatable[device].Basic.Attributes[resourceapi.QualifiedName(DriverDomain+name)]
+	}
+	hostable := func(device, template string) bool {
+		return *allocatable[device].Basic.Attributes[resourceapi.QualifiedName(common.TemplateHostableAttributeDomain+"/"+template)].BoolValue
+	}
+
+	// The vNPUs of the first card are fixed devices hosting only their own
+	// template.
+	assert.ElementsMatch(t, []string{"npu-0-1", "npu-0-2", "npu-1-0"}, slices.Collect(maps.Keys(allocatable)))
+	assert.Equal(t, "vNPU", *attribute("npu-0-1", "type").StringValue)
+	assert.Equal(t, "vir02", *attribute("npu-0-1", "template").StringValue)
+	assert.Equal(t, int64(8), *attribute("npu-0-1", "aicore").IntValue)
+	assert.Equal(t, int64(12), *attribute("npu-0-1", "memory").IntValue)
+	assert.True(t, hostable("npu-0-1", "vir02"))
+	assert.False(t, hostable("npu-0-1", "vir01"))
+	assert.Equal(t, "vir01", *attribute("npu-0-2", "template").StringValue)
+	assert.True(t, hostable("npu-0-2", "vir01"))
+
+	// The second card is a fixed whole card with the capacity of the chip.
+	assert.Equal(t, "NPU", *attribute("npu-1-0", "type").StringValue)
+	assert.Nil(t, attribute("npu-1-0", "template").StringValue)
+	assert.Equal(t, int64(8), *attribute("npu-1-0", "aicore").IntValue)
+	assert.Equal(t, int64(21), *attribute("npu-1-0", "memory").IntValue)
+
+	for _, name := range []string{"npu-0-0", "npu-1-0"} {
+		npu := vnpuManager.PhysicalNpus[name]
+		require.NotNil(t, npu, name)
+		assert.True(t, npu.Static, name)
+		assert.Empty(t, npu.AllocatedSlices, name)
+	}
+	assert.NotContains(t, vnpuManager.PhysicalNpus, "npu-5-0")
+}
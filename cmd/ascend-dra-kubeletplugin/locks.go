@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// npuLocks serializes the preparation of claims per physical NPU, so that
+// claims on different physical NPUs are prepared concurrently.
+type npuLocks struct {
+	sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock locks the physical NPUs the given devices belong to and returns the
+// function unlocking them. The NPUs are locked in a fixed order, so that
+// claims sharing some of them cannot deadlock.
+func (l *npuLocks) lock(deviceNames []string) func() {
+	keys := make([]string, 0, len(deviceNames))
+	for _, name := range deviceNames {
+		keys = append(keys, physicalNpuKey(name))
+	}
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	l.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*sync.Mutex)
+	}
+	held := make([]*sync.Mutex, 0, len(keys))
+	for _, key := range keys {
+		mu, ok := l.locks[key]
+		if !ok {
+			mu = &sync.Mutex{}
+			l.locks[key] = mu
+		}
+		held = append(held, mu)
+	}
+	l.Unlock()
+
+	for _, mu := range held {
+		mu.Lock()
+	}
+	return func() {
+		for _, mu := range slices.Backward(held) {
+			mu.Unlock()
+		}
+	}
+}
+
+// physicalNpuKey returns the key of the physical NPU a device or slice
+// belongs to. Devices with unknown names are their own key.
+func physicalNpuKey(deviceName string) string {
+	if logicID, _, ok := parseSliceID(deviceName); ok {
+		return fmt.Sprintf("npu-%d", logicID)
+	}
+	return deviceName
+}
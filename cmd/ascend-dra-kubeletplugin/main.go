@@ -22,10 +22,10 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/urfave/cli/v2"
 
-	coreclientset "k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 
 	"Ascend-dra-driver/pkg/flags"
@@ -46,13 +46,23 @@ type Flags struct {
 	kubeClientConfig flags.KubeClientConfig
 	loggingConfig    *flags.LoggingConfig
 
-	nodeName string
-	cdiRoot  string
+	nodeName          string
+	cdiRoot           string
+	httpEndpoint      string
+	reclaimEmptyCards bool
+	vnpuLayoutFile    string
+
+	maxTimeSlicedClaims   int
+	sharedClaimStrategies []string
+	runtimeEnvAllowlist   []string
+	runtimeMountRoots     []string
+	rediscoveryInterval   time.Duration
+	publishDelay          time.Duration
 }
 
 type Config struct {
 	flags      *Flags
-	coreclient coreclientset.Interface
+	clientSets flags.ClientSets
 }
 
 func main() {
@@ -81,8 +91,66 @@ func newApp() *cli.App {
 			Destination: &flags.cdiRoot,
 			EnvVars:     []string{"CDI_ROOT"},
 		},
+		&cli.StringFlag{
+			Name:        "http-endpoint",
+			Usage:       "The TCP network `address` where the HTTP server for metrics and debugging endpoints will listen (example: `:8080`). The default is the empty string, which means the server is disabled.",
+			Destination: &flags.httpEndpoint,
+			EnvVars:     []string{"HTTP_ENDPOINT"},
+		},
+		&cli.BoolFlag{
+			Name:        "reclaim-empty-cards",
+			Usage:       "Reset a carved NPU and republish it as a whole card as soon as its last vNPU slice is released.",
+			Value:       true,
+			Destination: &flags.reclaimEmptyCards,
+			EnvVars:     []string{"RECLAIM_EMPTY_CARDS"},
+		},
+		&cli.StringFlag{
+			Name:        "vnpu-layout-file",
+			Usage:       "Path to a file declaring static vNPU layouts per node. Declared cards are carved at startup and publish fixed vNPUs instead of being split on demand.",
+			Destination: &flags.vnpuLayoutFile,
+			EnvVars:     []string{"VNPU_LAYOUT_FILE"},
+		},
+		&cli.IntFlag{
+			Name:        "max-time-sliced-claims",
+			Usage:       "The number of ResourceClaims that can time-slice a whole NPU. Above 1, an NPU allocated to a claim with the TimeSlicing strategy is published as a TimeSlice device again for the next claim.",
+			Value:       1,
+			Destination: &flags.maxTimeSlicedClaims,
+			EnvVars:     []string{"MAX_TIME_SLICED_CLAIMS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "shared-claim-strategies",
+			Usage:   "The sharing strategies a ResourceClaim reserved for several pods can be configured with to be prepared. An empty value prevents pods from sharing claims.",
+			Value:   cli.NewStringSlice(defaultSharedClaimStrategies...),
+			EnvVars: []string{"SHARED_CLAIM_STRATEGIES"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "runtime-env-allowlist",
+			Usage:   "The CANN and HCCL environment variables the runtime settings of an NpuConfig can set. An empty value prevents claims from setting any.",
+			Value:   cli.NewStringSlice(defaultRuntimeEnvAllowlist...),
+			EnvVars: []string{"RUNTIME_ENV_ALLOWLIST"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "runtime-mount-roots",
+			Usage:   "The host directories below which the runtime settings of an NpuConfig can mount directories, e.g. to collect profiling output. Claims cannot mount anything unless set.",
+			EnvVars: []string{"RUNTIME_MOUNT_ROOTS"},
+		},
+		&cli.DurationFlag{
+			Name:        "rediscovery-interval",
+			Usage:       "How often to rediscover NPUs to pick up cards that were added, removed or came back. A zero value disables periodic rediscovery; it can still be triggered on the /debug/rediscover endpoint.",
+			Value:       time.Minute,
+			Destination: &flags.rediscoveryInterval,
+			EnvVars:     []string{"REDISCOVERY_INTERVAL"},
+		},
+		&cli.DurationFlag{
+			Name:        "publish-delay",
+			Usage:       "How long to collect changes of the devices before publishing them at once in the ResourceSlice.",
+			Value:       200 * time.Millisecond,
+			Destination: &flags.publishDelay,
+			EnvVars:     []string{"PUBLISH_DELAY"},
+		},
 	}
 	cliFlags = append(cliFlags, flags.kubeClientConfig.Flags()...)
+	cliFlags = append(cliFlags, flags.kubeClientConfig.PublisherFlags()...)
 	cliFlags = append(cliFlags, flags.loggingConfig.Flags()...)
 
 	app := &cli.App{
@@ -91,6 +159,9 @@ func newApp() *cli.App {
 		Flags: cliFlags,
 		Action: func(c *cli.Context) error {
 			ctx := c.Context
+			flags.sharedClaimStrategies = c.StringSlice("shared-claim-strategies")
+			flags.runtimeEnvAllowlist = c.StringSlice("runtime-env-allowlist")
+			flags.runtimeMountRoots = c.StringSlice("runtime-mount-roots")
 			clientSets, err := flags.kubeClientConfig.NewClientSets()
 			if err != nil {
 				return fmt.Errorf("create client: %v", err)
@@ -98,7 +169,7 @@ func newApp() *cli.App {
 
 			config := &Config{
 				flags:      flags,
-				coreclient: clientSets.Core,
+				clientSets: clientSets,
 			}
 
 			return StartPlugin(ctx, config)
@@ -38,7 +38,7 @@ type Device struct {
 }
 
 type AscendManager struct {
-	mgr *devmanager.DeviceManager
+	mgr deviceManager
 	//nodeName string
 	devs []*Device
 }
@@ -142,6 +142,56 @@ func (am *AscendManager) getVirtualDevice(logicID int32) (npuCommon.VirtualDevIn
 	return virtualDevInfos, nil
 }
 
+// VirtualDevice is a vNPU that exists on a physical NPU.
+type VirtualDevice struct {
+	VDevID       uint32
+	TemplateName string
+}
+
+// GetVirtualDevices lists the vNPUs that have been created on the given physical NPU.
+func (am *AscendManager) GetVirtualDevices(logicID int32) ([]VirtualDevice, error) {
+	vDevInfos, err := am.getVirtualDevice(logicID)
+	if err != nil {
+		return nil, err
+	}
+	var vdevs []VirtualDevice
+	for _, info := range vDevInfos.VDevInfo {
+		vdevs = append(vdevs, VirtualDevice{
+			VDevID:       info.VDevID,
+			TemplateName: info.QueryInfo.Name,
+		})
+	}
+	return vdevs, nil
+}
+
+// CreateVirtualDevice creates a vNPU from the given template and returns its ID.
+func (am *AscendManager) CreateVirtualDevice(logicID int32, templateName string) (uint32, error) {
+	out, err := am.mgr.CreateVirtualDevice(logicID, npuCommon.CgoCreateVDevRes{
+		VDevID:       common.DefaultIDForCreateVNPU,
+		VfgID:        common.DefaultIDForCreateVNPU,
+		TemplateName: templateName,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("create vNPU with template %s on device %d: %w", templateName, logicID, err)
+	}
+	return out.VDevID, nil
+}
+
+// GetChipName returns the chip name of the given physical NPU.
+func (am *AscendManager) GetChipName(logicID int32) (string, error) {
+	chipInfo, err := am.mgr.GetChipInfo(logicID)
+	if err != nil {
+		return "", err
+	}
+	return chipInfo.Name, nil
+}
+
+// GetPhysicIDFromLogicID returns the physical ID of the given physical NPU,
+// which numbers its device node.
+func (am *AscendManager) GetPhysicIDFromLogicID(logicID int32) (int32, error) {
+	return am.mgr.GetPhysicIDFromLogicID(logicID)
+}
+
 func (am *AscendManager) assemblePhyDevices(devType string, davinCiDev common.DavinCiDev,
 	devices *[]common.NpuDevice,
 ) {
@@ -196,23 +246,28 @@ func (am *AscendManager) NewHwDevManager() (common.NpuAllInfo, error) {
 	if err != nil {
 		return common.NpuAllInfo{}, err
 	}
+	if int(devNum) > len(devList) {
+		return common.NpuAllInfo{}, fmt.Errorf("driver reported %d devices but listed %d", devNum, len(devList))
+	}
 	var allDevices []common.NpuDevice
 	var chipType = ""
 	for i := int32(0); i < devNum; i++ {
 		davinCiDev, err := am.getDavinCiDev(devList[i])
 		if err != nil {
-			return common.NpuAllInfo{}, err
+			return common.NpuAllInfo{}, fmt.Errorf("get IDs of device %d: %w", devList[i], err)
 		}
 		if chipType == "" {
 			chipInfo, err := am.mgr.GetChipInfo(davinCiDev.LogicID)
 			if err != nil {
-				return common.NpuAllInfo{}, nil
+				return common.NpuAllInfo{}, fmt.Errorf("get chip info of device %d: %w", davinCiDev.LogicID, err)
 			}
 			chipType = chipInfo.Name
 		}
 		vDevInfos, err := am.getVirtualDevice(devList[i])
 		if err != nil {
-
+			// Chips without virtualization support do not report vNPUs.
+			am.assemblePhyDevices(chipType, davinCiDev, &allDevices)
+			continue
 		}
 		if vDevInfos.TotalResource.VDevNum > common.MaxVirtualDeviceNum {
 			return common.NpuAllInfo{}, fmt.Errorf("invalid virtual device count")
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	npuCommon "huawei.com/npu-exporter/v5/devmanager/common"
+
+	"Ascend-dra-driver/pkg/common"
+)
+
+// fakeDeviceManager is a devmanager whose cards and failures are set by tests.
+type fakeDeviceManager struct {
+	logicIDs     []int32
+	devNum       int32
+	vdevs        map[int32]npuCommon.VirtualDevInfo
+	listErr      error
+	physicIDErr  error
+	cardIDErr    error
+	chipInfoErr  error
+	vdevInfoErr  error
+	createVDevID uint32
+	createErr    error
+}
+
+func (f *fakeDeviceManager) GetDeviceList() (int32, []int32, error) {
+	if f.listErr != nil {
+		return 0, nil, f.listErr
+	}
+	if f.devNum != 0 {
+		return f.devNum, f.logicIDs, nil
+	}
+	return int32(len(f.logicIDs)), f.logicIDs, nil
+}
+
+func (f *fakeDeviceManager) GetPhysicIDFromLogicID(logicID int32) (int32, error) {
+	return logicID, f.physicIDErr
+}
+
+func (f *fakeDeviceManager) GetCardIDDeviceID(logicID int32) (int32, int32, error) {
+	return logicID, 0, f.cardIDErr
+}
+
+func (f *fakeDeviceManager) GetChipInfo(logicID int32) (*npuCommon.ChipInfo, error) {
+	if f.chipInfoErr != nil {
+		return nil, f.chipInfoErr
+	}
+	return &npuCommon.ChipInfo{Name: "310P3"}, nil
+}
+
+func (f *fakeDeviceManager) GetVirtualDeviceInfo(logicID int32) (npuCommon.VirtualDevInfo, error) {
+	if f.vdevInfoErr != nil {
+		return npuCommon.VirtualDevInfo{}, f.vdevInfoErr
+	}
+	return f.vdevs[logicID], nil
+}
+
+func (f *fakeDeviceManager) CreateVirtualDevice(logicID int32, vDevInfo npuCommon.CgoCreateVDevRes) (npuCommon.CgoCreateVDevOut, error) {
+	return npuCommon.CgoCreateVDevOut{VDevID: f.createVDevID}, f.createErr
+}
+
+func TestNewHwDevManager(t *testing.T) {
+	driverErr := errors.New("dcmi error")
+	carved := npuCommon.VirtualDevInfo{
+		TotalResource: npuCommon.CgoSocTotalResource{VDevNum: 2},
+		VDevInfo: []npuCommon.CgoVDevQueryStru{
+			{VDevID: 100, QueryInfo: npuCommon.CgoVDevQueryInfo{Name: common.Vir02, Computing: npuCommon.CgoComputingResource{Aic: 2}}},
+			{VDevID: 101, QueryInfo: npuCommon.CgoVDevQueryInfo{Name: common.Vir04, Computing: npuCommon.CgoComputingResource{Aic: 4}}},
+		},
+	}
+
+	tests := map[string]struct {
+		mgr         *fakeDeviceManager
+		expected    []string
+		expectedErr bool
+	}{
+		"device list fails": {
+			mgr:         &fakeDeviceManager{listErr: driverErr},
+			expectedErr: true,
+		},
+		"device count exceeds the listed devices": {
+			mgr:         &fakeDeviceManager{logicIDs: []int32{0}, devNum: 2},
+			expectedErr: true,
+		},
+		"physical ID lookup fails": {
+			mgr:         &fakeDeviceManager{logicIDs: []int32{0}, physicIDErr: driverErr},
+			expectedErr: true,
+		},
+		"card ID lookup fails": {
+			mgr:         &fakeDeviceManager{logicIDs: []int32{0}, cardIDErr: driverErr},
+			expectedErr: true,
+		},
+		"chip info fails": {
+			mgr:         &fakeDeviceManager{logicIDs: []int32{0}, chipInfoErr: driverErr},
+			expectedErr: true,
+		},
+		"too many virtual devices": {
+			mgr: &fakeDeviceManager{
+				logicIDs: []int32{0},
+				vdevs: map[int32]npuCommon.VirtualDevInfo{
+					0: {TotalResource: npuCommon.CgoSocTotalResource{VDevNum: common.MaxVirtualDeviceNum + 1}},
+				},
+			},
+			expectedErr: true,
+		},
+		"no devices": {
+			mgr: &fakeDeviceManager{},
+		},
+		"virtualization not supported": {
+			mgr:      &fakeDeviceManager{logicIDs: []int32{0, 1}, vdevInfoErr: driverErr},
+			expected: []string{"310P3-0", "310P3-1"},
+		},
+		"whole and carved cards": {
+			mgr: &fakeDeviceManager{
+				logicIDs: []int32{0, 1},
+				vdevs:    map[int32]npuCommon.VirtualDevInfo{1: carved},
+			},
+			expected: []string{"310P3-0", "310P3-" + common.Core2 + "-100-1", "310P3-" + common.Core4 + "-101-1"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			am := &AscendManager{mgr: test.mgr}
+			allInfo, err := am.NewHwDevManager()
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			var names []string
+			for _, dev := range allInfo.AllDevs {
+				names = append(names, dev.DeviceName)
+			}
+			assert.Equal(t, test.expected, names)
+		})
+	}
+}
+
+func TestAscendManagerVirtualDevices(t *testing.T) {
+	driverErr := errors.New("dcmi error")
+
+	am := &AscendManager{mgr: &fakeDeviceManager{vdevInfoErr: driverErr}}
+	_, err := am.GetVirtualDevices(0)
+	assert.Error(t, err)
+
+	am = &AscendManager{mgr: &fakeDeviceManager{createErr: driverErr}}
+	_, err = am.CreateVirtualDevice(0, common.Vir02)
+	assert.ErrorIs(t, err, driverErr)
+
+	am = &AscendManager{mgr: &fakeDeviceManager{chipInfoErr: driverErr}}
+	_, err = am.GetChipName(0)
+	assert.ErrorIs(t, err, driverErr)
+
+	am = &AscendManager{mgr: &fakeDeviceManager{createVDevID: 105}}
+	vdevID, err := am.CreateVirtualDevice(0, common.Vir02)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(105), vdevID)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const metricsNamespace = "ascend_dra"
+
+var (
+	cardFreeAicore = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "defrag",
+			Name:           "card_free_aicore",
+			Help:           "Number of AI Cores not used by any allocated vNPU slice of a physical NPU.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"device", "model"},
+	)
+	cardFreeMemory = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "defrag",
+			Name:           "card_free_memory_gigabytes",
+			Help:           "Memory in GB not used by any allocated vNPU slice of a physical NPU.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"device", "model"},
+	)
+	cardReclaimable = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "defrag",
+			Name:           "card_reclaimable",
+			Help:           "1 if a physical NPU has no allocated slices but is still carved, 0 otherwise.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"device", "model"},
+	)
+	cardBlockedTemplates = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "defrag",
+			Name:           "card_blocked_template",
+			Help:           "1 for each template that fits the free capacity of a physical NPU but is blocked by its layout.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"device", "model", "template"},
+	)
+	cardBlockingClaims = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "defrag",
+			Name:           "card_blocking_claims",
+			Help:           "Number of claims that have to be released before a physical NPU can be restored to a whole card.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"device", "model"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(
+		cardFreeAicore,
+		cardFreeMemory,
+		cardReclaimable,
+		cardBlockedTemplates,
+		cardBlockingClaims,
+	)
+}
+
+// recordDefragMetrics replaces the defragmentation metrics with the given report.
+func recordDefragMetrics(report *DefragReport) {
+	cardFreeAicore.Reset()
+	cardFreeMemory.Reset()
+	cardReclaimable.Reset()
+	cardBlockedTemplates.Reset()
+	cardBlockingClaims.Reset()
+
+	for _, card := range report.Cards {
+		cardFreeAicore.WithLabelValues(card.DeviceName, card.Model).Set(float64(card.FreeAicore))
+		cardFreeMemory.WithLabelValues(card.DeviceName, card.Model).Set(float64(card.FreeMemory))
+		reclaimable := 0.0
+		if card.Reclaimable {
+			reclaimable = 1
+		}
+		cardReclaimable.WithLabelValues(card.DeviceName, card.Model).Set(reclaimable)
+		for _, tpl := range card.BlockedTemplates {
+			cardBlockedTemplates.WithLabelValues(card.DeviceName, card.Model, tpl).Set(1)
+		}
+		cardBlockingClaims.WithLabelValues(card.DeviceName, card.Model).Set(float64(len(card.BlockingClaims)))
+	}
+}
+
+// metricsHandler refreshes the defragmentation metrics from the vNPU manager
+// before serving every scrape.
+func metricsHandler(state *DeviceState) http.Handler {
+	handler := legacyregistry.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if vnpuManager := state.VnpuManager(); vnpuManager != nil {
+			recordDefragMetrics(vnpuManager.PlanDefragmentation())
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
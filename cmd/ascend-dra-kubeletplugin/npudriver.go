@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreclientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+)
+
+// NpuDriverCondition is the Node condition reporting whether the plugin can
+// use the NPU driver.
+const NpuDriverCondition corev1.NodeConditionType = "AscendNPUDriverReady"
+
+// npuDriverState is the startup state of the NPU driver as seen by the plugin.
+type npuDriverState string
+
+const (
+	// npuDriverInitializing means the driver has not been probed yet.
+	npuDriverInitializing npuDriverState = "Initializing"
+	// npuDriverUnavailable means the driver could not be used; no devices
+	// are published while initialization is retried.
+	npuDriverUnavailable npuDriverState = "Unavailable"
+	// npuDriverReady means the devices have been discovered and published.
+	npuDriverReady npuDriverState = "Ready"
+)
+
+// npuDriverBackoff is how often initialization of an unavailable NPU driver is retried.
+var npuDriverBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    math.MaxInt32,
+	Cap:      5 * time.Minute,
+}
+
+// npuDriverStatus tracks the state of the NPU driver.
+type npuDriverStatus struct {
+	sync.Mutex
+	state   npuDriverState
+	message string
+}
+
+// Get returns the current state and the message explaining it.
+func (s *npuDriverStatus) Get() (npuDriverState, string) {
+	s.Lock()
+	defer s.Unlock()
+	return s.state, s.message
+}
+
+// Set updates the state and reports whether it changed.
+func (s *npuDriverStatus) Set(state npuDriverState, message string) bool {
+	s.Lock()
+	defer s.Unlock()
+	changed := s.state != state
+	s.state, s.message = state, message
+	return changed
+}
+
+// nodeStatusReporter surfaces the NPU driver state on the Node as a condition
+// and as Events.
+type nodeStatusReporter struct {
+	client      coreclientset.Interface
+	nodeName    string
+	broadcaster record.EventBroadcaster
+	recorder    record.EventRecorder
+}
+
+func newNodeStatusReporter(client coreclientset.Interface, nodeName string) *nodeStatusReporter {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	return &nodeStatusReporter{
+		client:      client,
+		nodeName:    nodeName,
+		broadcaster: broadcaster,
+		recorder:    broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: DriverName, Host: nodeName}),
+	}
+}
+
+// Report records an Event for a state transition and sets the Node condition.
+func (r *nodeStatusReporter) Report(ctx context.Context, state npuDriverState, message string) error {
+	node := &corev1.ObjectReference{Kind: "Node", Name: r.nodeName, UID: types.UID(r.nodeName)}
+	status, eventType := corev1.ConditionFalse, corev1.EventTypeWarning
+	if state == npuDriverReady {
+		status, eventType = corev1.ConditionTrue, corev1.EventTypeNormal
+		message = "NPU devices have been discovered and published"
+	}
+	reason := "NPUDriver" + string(state)
+	r.recorder.Event(node, eventType, reason, message)
+
+	now := metav1.Now()
+	patch, err := json.Marshal(map[string]any{
+		"status": map[string]any{
+			"conditions": []corev1.NodeCondition{{
+				Type:               NpuDriverCondition,
+				Status:             status,
+				LastHeartbeatTime:  now,
+				LastTransitionTime: now,
+				Reason:             reason,
+				Message:            message,
+			}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := r.client.CoreV1().Nodes().PatchStatus(ctx, r.nodeName, patch); err != nil {
+		return fmt.Errorf("set %s condition on node %s: %w", NpuDriverCondition, r.nodeName, err)
+	}
+	return nil
+}
+
+// Stop flushes and stops the Event broadcaster.
+func (r *nodeStatusReporter) Stop() {
+	r.broadcaster.Shutdown()
+}
+
+// setNpuDriverState records a new NPU driver state and reports transitions.
+func (d *driver) setNpuDriverState(ctx context.Context, state npuDriverState, message string) {
+	if !d.status.Set(state, message) {
+		return
+	}
+	logger := klog.FromContext(ctx)
+	if state == npuDriverReady {
+		logger.Info("NPU driver is ready")
+	} else {
+		logger.Info("NPU driver is not ready", "state", state, "reason", message)
+	}
+	if d.reporter == nil {
+		return
+	}
+	if err := d.reporter.Report(ctx, state, message); err != nil {
+		logger.Error(err, "Unable to report NPU driver state")
+	}
+}
+
+// recoverNpuDriver retries discovering the devices with backoff until the NPU
+// driver becomes usable or ctx is done, then publishes them.
+func (d *driver) recoverNpuDriver(ctx context.Context, backoff wait.Backoff) {
+	logger := klog.FromContext(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff.Step()):
+		}
+
+		if err := d.state.DiscoverDevices(); err != nil {
+			if !isNpuDriverUnavailable(err) {
+				logger.Error(err, "Unable to discover NPU devices")
+			}
+			d.setNpuDriverState(ctx, npuDriverUnavailable, err.Error())
+			continue
+		}
+		if err := d.publisher.Publish(ctx); err != nil {
+			logger.Error(err, "Unable to publish resources after the NPU driver recovered")
+		}
+		d.setNpuDriverState(ctx, npuDriverReady, "")
+		return
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+	"k8s.io/dynamic-resource-allocation/resourceslice"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
+)
+
+const testNodeName = "node-1"
+
+// fakePlugin records the devices published by the driver.
+type fakePlugin struct {
+	kubeletplugin.DRAPlugin
+	published []kubeletplugin.Resources
+}
+
+func (p *fakePlugin) publish(ctx context.Context, resources *resourceslice.DriverResources) error {
+	p.published = append(p.published, kubeletplugin.Resources{Devices: poolDevices(resources)})
+	return nil
+}
+
+// flakyBackend fails to connect to the NPU driver the given number of times.
+func flakyBackend(failures int, backend NpuBackend) backendFactory {
+	return func() (NpuBackend, error) {
+		if failures > 0 {
+			failures--
+			return nil, errors.New("dcmi not loaded")
+		}
+		return backend, nil
+	}
+}
+
+func newTestConfig(t *testing.T) *Config {
+	return &Config{
+		flags: &Flags{
+			nodeName: testNodeName,
+			cdiRoot:  t.TempDir(),
+
+			sharedClaimStrategies: defaultSharedClaimStrategies,
+			runtimeEnvAllowlist:   defaultRuntimeEnvAllowlist,
+		},
+	}
+}
+
+func newTestDriver(t *testing.T, newBackend backendFactory) (*driver, *fakePlugin, *fake.Clientset, *record.FakeRecorder) {
+	state, err := newDeviceState(newTestConfig(t), t.TempDir(), newBackend)
+	require.NoError(t, err)
+
+	client := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: testNodeName}})
+	recorder := record.NewFakeRecorder(10)
+	plugin := &fakePlugin{}
+	d := &driver{
+		claims:    newClaimCache(client, testNodeName),
+		plugin:    plugin,
+		publisher: newResourcePublisher(plugin.publish, state.publishableDevices, testNodeName, time.Millisecond),
+		state:     state,
+		status:    &npuDriverStatus{state: npuDriverInitializing},
+		reporter: &nodeStatusReporter{
+			client:      client,
+			nodeName:    testNodeName,
+			broadcaster: record.NewBroadcaster(),
+			recorder:    recorder,
+		},
+	}
+	return d, plugin, client, recorder
+}
+
+func nodeCondition(t *testing.T, client *fake.Clientset) *corev1.NodeCondition {
+	node, err := client.CoreV1().Nodes().Get(context.Background(), testNodeName, metav1.GetOptions{})
+	require.NoError(t, err)
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == NpuDriverCondition {
+			return &node.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func TestNewDeviceState(t *testing.T) {
+	state, err := newDeviceState(newTestConfig(t), t.TempDir(), backendOf(nil, errors.New("dcmi not loaded")))
+	require.NoError(t, err)
+	assert.False(t, state.Ready())
+	assert.Empty(t, state.allocatable)
+
+	state, err = newDeviceState(newTestConfig(t), t.TempDir(), backendOf(&fakeBackend{devices: twoCards()}, nil))
+	require.NoError(t, err)
+	assert.True(t, state.Ready())
+	assert.Len(t, state.allocatable, 2)
+
+	config := newTestConfig(t)
+	config.flags.vnpuLayoutFile = "does-not-exist.yaml"
+	_, err = newDeviceState(config, t.TempDir(), backendOf(&fakeBackend{devices: twoCards()}, nil))
+	assert.Error(t, err)
+}
+
+func TestRecoverNpuDriver(t *testing.T) {
+	d, plugin, client, recorder := newTestDriver(t, flakyBackend(3, &fakeBackend{devices: twoCards()}))
+	// The first attempt happened while creating the state.
+	require.False(t, d.state.Ready())
+
+	d.recoverNpuDriver(context.Background(), wait.Backoff{Duration: time.Millisecond, Steps: 10})
+
+	state, _ := d.status.Get()
+	assert.Equal(t, npuDriverReady, state)
+	assert.True(t, d.state.Ready())
+	require.Len(t, plugin.published, 1)
+	assert.Len(t, plugin.published[0].Devices, 2)
+
+	condition := nodeCondition(t, client)
+	require.NotNil(t, condition)
+	assert.Equal(t, corev1.ConditionTrue, condition.Status)
+
+	assert.Equal(t, []string{
+		"Warning NPUDriverUnavailable " + errNpuDriverUnavailable.Error() + ": dcmi not loaded",
+		"Normal NPUDriverReady NPU devices have been discovered and published",
+	}, drainEvents(recorder))
+}
+
+func TestRecoverNpuDriverUntilCancelled(t *testing.T) {
+	d, plugin, client, _ := newTestDriver(t, backendOf(&fakeBackend{listErr: errors.New("device busy")}, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	d.recoverNpuDriver(ctx, wait.Backoff{Duration: time.Millisecond, Steps: 10})
+
+	state, message := d.status.Get()
+	assert.Equal(t, npuDriverUnavailable, state)
+	assert.Contains(t, message, "device busy")
+	assert.Empty(t, plugin.published)
+
+	condition := nodeCondition(t, client)
+	require.NotNil(t, condition)
+	assert.Equal(t, corev1.ConditionFalse, condition.Status)
+	assert.Equal(t, "NPUDriverUnavailable", condition.Reason)
+}
+
+func TestRediscoveryReportsLostDriver(t *testing.T) {
+	backend := &fakeBackend{devices: twoCards()}
+	d, _, client, _ := newTestDriver(t, backendOf(backend, nil))
+	d.setNpuDriverState(context.Background(), npuDriverReady, "")
+
+	backend.listErr = errors.New("driver unloaded")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	d.runRediscovery(ctx, time.Millisecond, wait.Backoff{Duration: time.Millisecond, Steps: 10})
+
+	state, _ := d.status.Get()
+	assert.Equal(t, npuDriverUnavailable, state)
+	assert.Equal(t, corev1.ConditionFalse, nodeCondition(t, client).Status)
+}
+
+func TestRediscoveryRecoversLostDriver(t *testing.T) {
+	// The driver is lost after the devices were discovered, and is back on
+	// the second attempt to recover it.
+	connects := 0
+	d, plugin, client, _ := newTestDriver(t, func() (NpuBackend, error) {
+		connects++
+		if connects == 2 || connects == 3 {
+			return nil, errors.New("dcmi not loaded")
+		}
+		return &fakeBackend{devices: twoCards()}, nil
+	})
+	d.setNpuDriverState(context.Background(), npuDriverReady, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	d.runRediscovery(ctx, time.Millisecond, wait.Backoff{Duration: time.Millisecond, Steps: 10})
+
+	state, _ := d.status.Get()
+	assert.Equal(t, npuDriverReady, state)
+	assert.Greater(t, connects, 4, "rediscovery resumes after the recovery")
+	require.Len(t, plugin.published, 1)
+	assert.Len(t, plugin.published[0].Devices, 2)
+	assert.Equal(t, corev1.ConditionTrue, nodeCondition(t, client).Status)
+}
+
+func TestRediscoveryWhileDiscovering(t *testing.T) {
+	d, _, _, _ := newTestDriver(t, backendOf(&fakeBackend{devices: twoCards()}, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.runRediscovery(ctx, time.Microsecond, wait.Backoff{Duration: time.Millisecond, Steps: 10})
+	}()
+	// Discovering the devices again replaces the vNPU manager the periodic
+	// rediscovery uses.
+	for range 50 {
+		require.NoError(t, d.state.DiscoverDevices())
+		time.Sleep(100 * time.Microsecond)
+	}
+	cancel()
+	<-done
+
+	assert.True(t, d.state.Ready())
+	assert.NotNil(t, d.state.VnpuManager())
+}
+
+func TestReadyz(t *testing.T) {
+	d, _, _, _ := newTestDriver(t, backendOf(nil, errors.New("dcmi not loaded")))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server, err := startDebugServer(ctx, "127.0.0.1:0", d)
+	require.NoError(t, err)
+	handler := server.server.Handler
+
+	get := func() int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return w.Code
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, get())
+	d.setNpuDriverState(ctx, npuDriverReady, "")
+	assert.Equal(t, http.StatusOK, get())
+	assert.NoError(t, server.Stop(ctx))
+}
+
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestNodePrepareResourcesConcurrently(t *testing.T) {
+	ctx := context.Background()
+	d, plugin, client, _ := newTestDriver(t, backendOf(&fakeBackend{devices: twoCards()}, nil))
+	vir02 := vnpuConfig(`{"template":"vir02"}`)
+	var claims []*drapbv1.Claim
+	for uid, device := range map[string]string{"claim-0": "npu-0-0", "claim-1": "npu-1-0", "claim-2": "npu-0-0"} {
+		claim, err := client.ResourceV1beta1().ResourceClaims("default").Create(ctx, newTestClaim(uid, []string{device}, vir02), metav1.CreateOptions{})
+		require.NoError(t, err)
+		claims = append(claims, &drapbv1.Claim{Namespace: claim.Namespace, Name: claim.Name, UID: string(claim.UID)})
+	}
+
+	prepared, err := d.NodePrepareResources(ctx, &drapbv1.NodePrepareResourcesRequest{Claims: claims})
+	require.NoError(t, err)
+	require.Len(t, prepared.Claims, 3)
+	assert.Empty(t, prepared.Claims["claim-1"].Error)
+	// The claims allocated the same card are serialized, and only one of
+	// them gets it.
+	assert.NotEqual(t, prepared.Claims["claim-0"].Error == "", prepared.Claims["claim-2"].Error == "")
+	// The devices are published in the background once the claims are
+	// prepared.
+	assert.Empty(t, plugin.published)
+	assert.Len(t, d.publisher.changes, 1, "pending change")
+	require.NoError(t, d.publisher.Publish(ctx))
+	require.Len(t, plugin.published, 1)
+	assert.ElementsMatch(t, d.state.allocatableDevices(), plugin.published[0].Devices)
+
+	unprepared, err := d.NodeUnprepareResources(ctx, &drapbv1.NodeUnprepareResourcesRequest{Claims: claims})
+	require.NoError(t, err)
+	require.Len(t, unprepared.Claims, 3)
+	for uid, response := range unprepared.Claims {
+		assert.Empty(t, response.Error, uid)
+	}
+	assert.Empty(t, d.state.checkpoint.V1.PreparedClaims)
+}
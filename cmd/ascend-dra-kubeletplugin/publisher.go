@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/dynamic-resource-allocation/resourceslice"
+	"k8s.io/klog/v2"
+)
+
+// resourcePublisher publishes the allocatable devices in the background.
+// Changes are collected for a short delay and published at once, and devices
+// are only published when they differ from what was published last.
+//
+// Each physical NPU is published as a pool of its own, so that carving one
+// card does not bump the generation of, and rewrite, the slices of the others.
+type resourcePublisher struct {
+	sync.Mutex
+	publish  func(ctx context.Context, resources *resourceslice.DriverResources) error
+	devices  func() []resourceapi.Device
+	nodeName string
+	delay    time.Duration
+	changes  chan struct{}
+
+	last *resourceslice.DriverResources
+}
+
+func newResourcePublisher(
+	publish func(ctx context.Context, resources *resourceslice.DriverResources) error,
+	devices func() []resourceapi.Device,
+	nodeName string,
+	delay time.Duration,
+) *resourcePublisher {
+	return &resourcePublisher{
+		publish:  publish,
+		devices:  devices,
+		nodeName: nodeName,
+		delay:    delay,
+		changes:  make(chan struct{}, 1),
+	}
+}
+
+// Notify tells the publisher that the devices may have changed. It never
+// blocks, so it can be called with any lock held.
+func (p *resourcePublisher) Notify() {
+	select {
+	case p.changes <- struct{}{}:
+	default:
+	}
+}
+
+// Run publishes the devices after each change until ctx is done. Failed
+// publishes are retried after the delay.
+func (p *resourcePublisher) Run(ctx context.Context) {
+	logger := klog.FromContext(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.changes:
+		}
+
+		// Let a burst of changes, like the claims of a pod, settle first.
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.delay):
+		}
+		if err := p.Publish(ctx); err != nil {
+			logger.Error(err, "Failed to publish resources, will retry")
+			p.Notify()
+		}
+	}
+}
+
+// Publish publishes the devices now, unless they are the ones published
+// last.
+func (p *resourcePublisher) Publish(ctx context.Context) error {
+	p.Lock()
+	defer p.Unlock()
+
+	resources := driverResources(p.nodeName, p.devices())
+	if p.last != nil && apiequality.Semantic.DeepEqual(resources, p.last) {
+		return nil
+	}
+	if err := p.publish(ctx, resources); err != nil {
+		return err
+	}
+	p.last = resources
+	klog.FromContext(ctx).V(4).Info("Published resources", "pools", len(resources.Pools))
+	return nil
+}
+
+// driverResources groups devices into one pool per physical NPU, split into
+// slices of at most the maximum number of devices of a ResourceSlice. The
+// devices are sorted by name, so that their order does not depend on the
+// order of the allocatable map. Without devices, an empty pool named after
+// the node shows that the driver is running.
+func driverResources(nodeName string, devices []resourceapi.Device) *resourceslice.DriverResources {
+	slices.SortFunc(devices, func(a, b resourceapi.Device) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	resources := &resourceslice.DriverResources{Pools: make(map[string]resourceslice.Pool)}
+	if len(devices) == 0 {
+		resources.Pools[nodeName] = resourceslice.Pool{Slices: []resourceslice.Slice{{}}}
+		return resources
+	}
+
+	byPool := make(map[string][]resourceapi.Device)
+	for _, device := range devices {
+		name := poolName(nodeName, device.Name)
+		byPool[name] = append(byPool[name], device)
+	}
+	for name, devices := range byPool {
+		var pool resourceslice.Pool
+		for chunk := range slices.Chunk(devices, resourceapi.ResourceSliceMaxDevices) {
+			pool.Slices = append(pool.Slices, resourceslice.Slice{Devices: chunk})
+		}
+		resources.Pools[name] = pool
+	}
+	return resources
+}
+
+// poolName returns the name of the pool deviceName is published in.
+func poolName(nodeName, deviceName string) string {
+	return nodeName + "/" + physicalNpuKey(deviceName)
+}
+
+// isNodePool reports whether pool holds devices of nodeName. Devices used to
+// be published in a single pool named after the node.
+func isNodePool(nodeName, pool string) bool {
+	return pool == nodeName || strings.HasPrefix(pool, nodeName+"/")
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resourceapi "k8s.io/api/resource/v1beta1"
+	"k8s.io/dynamic-resource-allocation/resourceslice"
+)
+
+// recordingPublisher records the names of the published devices.
+type recordingPublisher struct {
+	sync.Mutex
+	err       error
+	published [][]string
+}
+
+func (r *recordingPublisher) publish(ctx context.Context, resources *resourceslice.DriverResources) error {
+	r.Lock()
+	defer r.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	var names []string
+	for _, device := range poolDevices(resources) {
+		names = append(names, device.Name)
+	}
+	r.published = append(r.published, names)
+	return nil
+}
+
+func (r *recordingPublisher) get() [][]string {
+	r.Lock()
+	defer r.Unlock()
+	return r.published
+}
+
+func (r *recordingPublisher) setErr(err error) {
+	r.Lock()
+	defer r.Unlock()
+	r.err = err
+}
+
+// poolDevices returns the devices of all pools, ordered by pool.
+func poolDevices(resources *resourceslice.DriverResources) []resourceapi.Device {
+	var devices []resourceapi.Device
+	for _, name := range slices.Sorted(maps.Keys(resources.Pools)) {
+		for _, slice := range resources.Pools[name].Slices {
+			devices = append(devices, slice.Devices...)
+		}
+	}
+	return devices
+}
+
+// devicesOf returns a device source with the named devices.
+func devicesOf(mu *sync.Mutex, names *[]string) func() []resourceapi.Device {
+	return func() []resourceapi.Device {
+		mu.Lock()
+		defer mu.Unlock()
+		var devices []resourceapi.Device
+		for _, name := range *names {
+			devices = append(devices, resourceapi.Device{Name: name})
+		}
+		return devices
+	}
+}
+
+func TestResourcePublisherPublish(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	names := []string{}
+	recorder := &recordingPublisher{}
+	publisher := newResourcePublisher(recorder.publish, devicesOf(&mu, &names), testNodeName, time.Millisecond)
+
+	// Even without devices the first publish happens, so that no stale
+	// devices are left published.
+	require.NoError(t, publisher.Publish(ctx))
+	require.NoError(t, publisher.Publish(ctx))
+	assert.Equal(t, [][]string{nil}, recorder.get())
+
+	names = []string{"npu-1-0", "npu-0-1", "npu-0-0"}
+	require.NoError(t, publisher.Publish(ctx))
+	names = []string{"npu-0-0", "npu-1-0", "npu-0-1"}
+	require.NoError(t, publisher.Publish(ctx))
+	assert.Equal(t, [][]string{nil, {"npu-0-0", "npu-0-1", "npu-1-0"}}, recorder.get(), "devices are sorted and published on changes only")
+
+	recorder.setErr(errors.New("conflict"))
+	names = []string{"npu-0-0"}
+	require.EqualError(t, publisher.Publish(ctx), "conflict")
+	recorder.setErr(nil)
+	require.NoError(t, publisher.Publish(ctx))
+	assert.Equal(t, []string{"npu-0-0"}, recorder.get()[2], "a failed publish is not taken as published")
+}
+
+func TestResourcePublisherRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	names := []string{"npu-0-0"}
+	recorder := &recordingPublisher{err: errors.New("conflict")}
+	publisher := newResourcePublisher(recorder.publish, devicesOf(&mu, &names), testNodeName, 10*time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		publisher.Run(ctx)
+	}()
+
+	// A burst of changes is published at once, and retried until it is
+	// published.
+	for range 10 {
+		publisher.Notify()
+	}
+	time.Sleep(30 * time.Millisecond)
+	assert.Empty(t, recorder.get())
+	recorder.setErr(nil)
+	require.Eventually(t, func() bool { return len(recorder.get()) == 1 }, time.Second, time.Millisecond)
+
+	mu.Lock()
+	names = append(names, "npu-0-1")
+	mu.Unlock()
+	publisher.Notify()
+	require.Eventually(t, func() bool { return len(recorder.get()) == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, [][]string{{"npu-0-0"}, {"npu-0-0", "npu-0-1"}}, recorder.get())
+
+	cancel()
+	<-done
+}
+
+func TestDriverResources(t *testing.T) {
+	devices := func(names ...string) []resourceapi.Device {
+		var devices []resourceapi.Device
+		for _, name := range names {
+			devices = append(devices, resourceapi.Device{Name: name})
+		}
+		return devices
+	}
+	var many []string
+	for i := range resourceapi.ResourceSliceMaxDevices + 1 {
+		many = append(many, fmt.Sprintf("npu-2-%03d", i))
+	}
+
+	tests := map[string]struct {
+		devices  []resourceapi.Device
+		expected map[string][][]string
+	}{
+		"no devices": {
+			expected: map[string][][]string{testNodeName: {nil}},
+		},
+		"one pool per card": {
+			devices: devices("npu-1-0", "npu-0-2", "npu-0-1"),
+			expected: map[string][][]string{
+				testNodeName + "/npu-0": {{"npu-0-1", "npu-0-2"}},
+				testNodeName + "/npu-1": {{"npu-1-0"}},
+			},
+		},
+		"more devices than fit into a slice": {
+			devices: devices(many...),
+			expected: map[string][][]string{
+				testNodeName + "/npu-2": {many[:resourceapi.ResourceSliceMaxDevices], many[resourceapi.ResourceSliceMaxDevices:]},
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			resources := driverResources(testNodeName, test.devices)
+			actual := make(map[string][][]string)
+			for pool, content := range resources.Pools {
+				for _, slice := range content.Slices {
+					var names []string
+					for _, device := range slice.Devices {
+						names = append(names, device.Name)
+					}
+					actual[pool] = append(actual[pool], names)
+				}
+			}
+			assert.Equal(t, test.expected, actual)
+			for pool := range resources.Pools {
+				assert.True(t, isNodePool(testNodeName, pool), pool)
+			}
+		})
+	}
+	assert.False(t, isNodePool(testNodeName, "node-10/npu-0"))
+}
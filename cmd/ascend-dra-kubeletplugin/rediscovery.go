@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"Ascend-dra-driver/pkg/common"
+)
+
+// Rediscover connects to the NPU driver again and diffs the cards it reports
+// against the known physical NPUs. New cards are added, cards that vanished are marked
+// unavailable while their prepared slices are kept, and cards that came back
+// are published again. It reports whether the set of devices changed.
+func (s *DeviceState) Rediscover() (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.mgr == nil {
+		return false, errNpuDriverUnavailable
+	}
+	if s.vnpuManager == nil {
+		return false, fmt.Errorf("rediscovery requires the vNPU manager")
+	}
+
+	// The NPU driver may have been reloaded since the devices were
+	// discovered, which leaves the previous connection unusable.
+	mgr, err := s.newBackend()
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", errNpuDriverUnavailable, err)
+	}
+	allInfo, err := mgr.NewHwDevManager()
+	if err != nil {
+		return false, fmt.Errorf("%w: error listing NPU devices: %v", errNpuDriverUnavailable, err)
+	}
+	s.mgr = mgr
+
+	present := make(map[string]common.NpuDevice)
+	for _, dev := range allInfo.AllDevs {
+		deviceName := fmt.Sprintf("npu-%d-0", dev.LogicID)
+		if _, ok := present[deviceName]; !ok {
+			present[deviceName] = dev
+		}
+	}
+
+	changed := false
+	for deviceName, physicalNpu := range s.vnpuManager.PhysicalNpus {
+		_, available := present[deviceName]
+		if !s.vnpuManager.SetAvailability(deviceName, available) {
+			continue
+		}
+		changed = true
+		if available {
+			// Free slices of vanished cards are dropped by syncAllocatable,
+			// so publish them again once the card is back.
+			for _, slice := range physicalNpu.AvailableSlices {
+				s.UpdateAllocatableDevice(slice.SliceID, physicalNpu)
+			}
+		}
+	}
+
+	added := make(AllocatableDevices)
+	for deviceName, dev := range present {
+		if _, known := s.vnpuManager.PhysicalNpus[deviceName]; known {
+			continue
+		}
+		if err := enumerateCard(s.mgr, s.vnpuManager, s.layout, dev, nil, added); err != nil {
+			return changed, fmt.Errorf("error adding device %s: %v", deviceName, err)
+		}
+	}
+	s.allocatableMu.Lock()
+	for name, device := range added {
+		s.allocatable[name] = device
+		changed = true
+	}
+	s.allocatableMu.Unlock()
+	if len(added) > 0 {
+		s.changed()
+	}
+
+	return changed, nil
+}
+
+// rediscover runs a rediscovery and republishes the devices if they changed.
+func (d *driver) rediscover(ctx context.Context) (bool, error) {
+	changed, err := d.state.Rediscover()
+	if err != nil || !changed {
+		return changed, err
+	}
+
+	if err := d.publisher.Publish(ctx); err != nil {
+		return changed, fmt.Errorf("error publishing resources after rediscovery: %v", err)
+	}
+	klog.FromContext(ctx).Info("Published resources after rediscovery")
+	return changed, nil
+}
+
+// runRediscovery periodically rediscovers the devices until ctx is done. If
+// the NPU driver became unavailable, the devices are discovered again with
+// backoff once it recovers, before rediscovery resumes.
+func (d *driver) runRediscovery(ctx context.Context, interval time.Duration, backoff wait.Backoff) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// The NPU driver recovery loop owns discovery until the driver is up.
+			if !d.state.Ready() || d.state.VnpuManager() == nil {
+				continue
+			}
+			_, err := d.rediscover(ctx)
+			switch {
+			case isNpuDriverUnavailable(err):
+				d.setNpuDriverState(ctx, npuDriverUnavailable, err.Error())
+				d.recoverNpuDriver(ctx, backoff)
+			case err != nil:
+				klog.FromContext(ctx).Error(err, "Periodic rediscovery failed")
+			default:
+				d.setNpuDriverState(ctx, npuDriverReady, "")
+			}
+		}
+	}
+}
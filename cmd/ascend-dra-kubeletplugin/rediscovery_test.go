@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"Ascend-dra-driver/pkg/common"
+)
+
+// publishedNames returns the names of the devices state would publish.
+func publishedNames(state *DeviceState) []string {
+	var names []string
+	for _, device := range state.publishableDevices() {
+		names = append(names, device.Name)
+	}
+	return names
+}
+
+func TestRediscoverVanishedCard(t *testing.T) {
+	backend := &fakeBackend{devices: twoCards()}
+	state, err := newDeviceState(newTestConfig(t), t.TempDir(), backendOf(backend, nil))
+	require.NoError(t, err)
+	_, err = state.Prepare(newTestClaim("claim", []string{"npu-1-0"}, vnpuConfig(`{"template":"vir02"}`)))
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"npu-0-0", "npu-1-0", "npu-1-1"}, publishedNames(state))
+
+	// The free remainder of the vanished card is no longer published, but
+	// the slice prepared on it is kept.
+	backend.devices = twoCards()[:1]
+	changed, err := state.Rediscover()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	npu := state.vnpuManager.PhysicalNpus["npu-1-0"]
+	assert.True(t, npu.Unavailable)
+	require.Len(t, npu.AllocatedSlices, 1)
+	assert.Equal(t, "claim", npu.AllocatedSlices[0].ClaimUID)
+	assert.ElementsMatch(t, []string{"npu-0-0", "npu-1-0"}, publishedNames(state))
+	_, err = state.Prepare(newTestClaim("other", []string{"npu-1-1"}, vnpuConfig(`{"template":"vir01"}`)))
+	assert.ErrorContains(t, err, "requested NPU is not allocatable: npu-1-1")
+
+	changed, err = state.Rediscover()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// Once the card is back, its remainder is published again.
+	backend.devices = twoCards()
+	changed, err = state.Rediscover()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.False(t, npu.Unavailable)
+	assert.ElementsMatch(t, []string{"npu-0-0", "npu-1-0", "npu-1-1"}, publishedNames(state))
+	_, err = state.Prepare(newTestClaim("other", []string{"npu-1-1"}, vnpuConfig(`{"template":"vir01"}`)))
+	assert.NoError(t, err)
+	require.NoError(t, state.Unprepare("claim"))
+}
+
+func TestRediscoverAddedCard(t *testing.T) {
+	backend := &fakeBackend{devices: twoCards()}
+	state, err := newDeviceState(newTestConfig(t), t.TempDir(), backendOf(backend, nil))
+	require.NoError(t, err)
+	changed, err := state.Rediscover()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	backend.devices = append(twoCards(), common.NpuDevice{DevType: "310P3", DeviceName: "310P3-2", LogicID: 2})
+	changed, err = state.Rediscover()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.ElementsMatch(t, []string{"npu-0-0", "npu-1-0", "npu-2-0"}, publishedNames(state))
+	npu := state.vnpuManager.PhysicalNpus["npu-2-0"]
+	require.NotNil(t, npu)
+	assert.Equal(t, 8, npu.TotalAicore)
+	assert.Empty(t, npu.AllocatedSlices)
+
+	// A card that comes with vNPUs on its chip is imported without owners.
+	backend.devices = append(backend.devices, common.NpuDevice{DevType: "310P3", DeviceName: "310P3-3", LogicID: 3})
+	backend.vdevs = map[int32][]VirtualDevice{3: {{VDevID: 100, TemplateName: "vir02"}}}
+	changed, err = state.Rediscover()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.ElementsMatch(t, []string{"npu-0-0", "npu-1-0", "npu-2-0", "npu-3-2"}, publishedNames(state))
+	carved := state.vnpuManager.PhysicalNpus["npu-3-0"]
+	require.Len(t, carved.AllocatedSlices, 1)
+	assert.Empty(t, carved.AllocatedSlices[0].ClaimUID)
+
+	_, err = state.Prepare(newTestClaim("claim", []string{"npu-2-0"}, vnpuConfig(`{"template":"vir02"}`)))
+	assert.NoError(t, err)
+}
+
+func TestRediscoverLostDriver(t *testing.T) {
+	backend := &fakeBackend{devices: twoCards()}
+	state, err := newDeviceState(newTestConfig(t), t.TempDir(), backendOf(backend, nil))
+	require.NoError(t, err)
+
+	backend.listErr = errors.New("driver unloaded")
+	changed, err := state.Rediscover()
+	assert.True(t, isNpuDriverUnavailable(err), "expected driver unavailable, got %v", err)
+	assert.False(t, changed)
+	assert.ElementsMatch(t, []string{"npu-0-0", "npu-1-0"}, publishedNames(state))
+}
+
+func TestRediscoverReloadedDriver(t *testing.T) {
+	stale := &fakeBackend{devices: twoCards()}
+	backend := stale
+	state, err := newDeviceState(newTestConfig(t), t.TempDir(), func() (NpuBackend, error) { return backend, nil })
+	require.NoError(t, err)
+
+	// After the driver was reloaded, the previous connection is unusable.
+	stale.listErr = errors.New("driver reloaded")
+	backend = &fakeBackend{devices: twoCards()}
+	changed, err := state.Rediscover()
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Same(t, backend, state.mgr)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+
+	configapi "Ascend-dra-driver/api/example.com/resource/npu"
+)
+
+// defaultRuntimeEnvAllowlist are the CANN and HCCL settings claims can make,
+// unless configured otherwise.
+var defaultRuntimeEnvAllowlist = []string{
+	"HCCL_CONNECT_TIMEOUT",
+	"HCCL_EXEC_TIMEOUT",
+	"HCCL_SOCKET_IFNAME",
+	"ASCEND_GLOBAL_LOG_LEVEL",
+	"ASCEND_GLOBAL_EVENT_ENABLE",
+	"ASCEND_SLOG_PRINT_TO_STDOUT",
+	"ASCEND_RT_VISIBLE_DEVICES",
+}
+
+// reservedRuntimeEnv are set by the driver to select the NPUs of a claim, so
+// claims can never set them.
+var reservedRuntimeEnv = []string{
+	"ASCEND_VISIBLE_DEVICES",
+	"ASCEND_VNPU_SPECS",
+	"ASCEND_RUNTIME_OPTIONS",
+}
+
+var runtimeEnvNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// runtimePolicy holds the runtime settings the cluster administrator allows
+// claims to make.
+type runtimePolicy struct {
+	// env are the environment variables claims can set.
+	env []string
+	// mountRoots are the host directories below which claims can mount
+	// directories. Without any, claims cannot mount anything.
+	mountRoots []string
+}
+
+// parseRuntimePolicy parses the environment variables claims can set and the
+// host directories below which they can mount directories. Empty values are
+// ignored, so that both can be disabled with an empty list.
+func parseRuntimePolicy(env, mountRoots []string) (*runtimePolicy, error) {
+	policy := &runtimePolicy{}
+	for _, name := range env {
+		switch {
+		case name == "":
+			continue
+		case !runtimeEnvNameRegexp.MatchString(name):
+			return nil, fmt.Errorf("invalid environment variable name %q", name)
+		case slices.Contains(reservedRuntimeEnv, name):
+			return nil, fmt.Errorf("environment variable %s is set by the driver and cannot be allowed", name)
+		}
+		policy.env = append(policy.env, name)
+	}
+	for _, root := range mountRoots {
+		switch {
+		case root == "":
+			continue
+		case !filepath.IsAbs(root):
+			return nil, fmt.Errorf("mount root %q is not absolute", root)
+		}
+		policy.mountRoots = append(policy.mountRoots, filepath.Clean(root))
+	}
+	return policy, nil
+}
+
+// containerEdits returns the container edits of the runtime settings of a
+// config applied to the given number of devices. Settings the cluster
+// administrator does not allow are rejected rather than dropped, so that
+// claims do not silently run without them.
+func (p *runtimePolicy) containerEdits(runtime *configapi.RuntimeConfig, devices int) (*cdispec.ContainerEdits, error) {
+	edits := &cdispec.ContainerEdits{}
+	if runtime == nil {
+		return edits, nil
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(runtime.Env)) {
+		value := runtime.Env[name]
+		if !slices.Contains(p.env, name) {
+			return nil, fmt.Errorf("environment variable %s is not allowed by the cluster administrator (allowed: %v)", name, p.env)
+		}
+		if name == "ASCEND_RT_VISIBLE_DEVICES" {
+			indices, err := configapi.ParseRuntimeVisibleDevices(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of environment variable %s: %v", name, err)
+			}
+			// The containers only see the devices of the claim, numbered
+			// from zero.
+			if index := slices.Max(indices); index >= devices {
+				return nil, fmt.Errorf("%s refers to device %d, but the config only applies to %d devices", name, index, devices)
+			}
+		}
+		edits.Env = append(edits.Env, fmt.Sprintf("%s=%s", name, value))
+	}
+
+	for _, mount := range runtime.Mounts {
+		hostPath, err := p.resolveMount(mount.HostPath)
+		if err != nil {
+			return nil, err
+		}
+		options := []string{"rbind", "nosuid", "nodev", "rw"}
+		if mount.ReadOnly {
+			options[len(options)-1] = "ro"
+		}
+		edits.Mounts = append(edits.Mounts, &cdispec.Mount{
+			HostPath:      hostPath,
+			ContainerPath: mount.ContainerPath,
+			Type:          "bind",
+			Options:       options,
+		})
+	}
+	return edits, nil
+}
+
+// resolveMount resolves the symbolic links of hostPath and returns the
+// resulting path if it is one of the mount roots or below one, once their
+// symbolic links are resolved as well. Links below a root thus cannot point
+// a mount elsewhere, and the resolved path is mounted so that they cannot be
+// changed to do so later.
+func (p *runtimePolicy) resolveMount(hostPath string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(hostPath)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve host path %s: %v", hostPath, err)
+	}
+	for _, root := range p.mountRoots {
+		root, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			continue
+		}
+		if resolved == root || strings.HasPrefix(resolved, strings.TrimSuffix(root, "/")+"/") {
+			return resolved, nil
+		}
+	}
+	if resolved != filepath.Clean(hostPath) {
+		return "", fmt.Errorf("host path %s resolves to %s, which is not below a directory the cluster administrator allows mounting (%v)", hostPath, resolved, p.mountRoots)
+	}
+	return "", fmt.Errorf("host path %s is not below a directory the cluster administrator allows mounting (%v)", hostPath, p.mountRoots)
+}
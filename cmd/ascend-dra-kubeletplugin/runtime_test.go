@@ -0,0 +1,159 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func runtimeConfig(runtime string) string {
+	return `{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"NpuConfig","runtime":` + runtime + `}`
+}
+
+func TestParseRuntimePolicy(t *testing.T) {
+	tests := map[string]struct {
+		env        []string
+		mountRoots []string
+		expected   *runtimePolicy
+		err        string
+	}{
+		"defaults": {
+			env:      defaultRuntimeEnvAllowlist,
+			expected: &runtimePolicy{env: defaultRuntimeEnvAllowlist},
+		},
+		"empty": {
+			env:        []string{""},
+			mountRoots: []string{""},
+			expected:   &runtimePolicy{},
+		},
+		"mount roots": {
+			mountRoots: []string{"/var/log/npu/", "/data//profiling"},
+			expected:   &runtimePolicy{mountRoots: []string{"/var/log/npu", "/data/profiling"}},
+		},
+		"invalid name": {
+			env: []string{"HCCL-TIMEOUT"},
+			err: `invalid environment variable name "HCCL-TIMEOUT"`,
+		},
+		"reserved name": {
+			env: []string{"ASCEND_GLOBAL_LOG_LEVEL", "ASCEND_VISIBLE_DEVICES"},
+			err: "environment variable ASCEND_VISIBLE_DEVICES is set by the driver and cannot be allowed",
+		},
+		"relative mount root": {
+			mountRoots: []string{"profiling"},
+			err:        `mount root "profiling" is not absolute`,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy, err := parseRuntimePolicy(test.env, test.mountRoots)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, policy)
+		})
+	}
+}
+
+func TestPrepareRuntimeSettings(t *testing.T) {
+	tests := map[string]struct {
+		runtime        string
+		expectedEnv    []string
+		expectedMounts []*cdispec.Mount
+		err            string
+	}{
+		"env": {
+			runtime:     `{"env":{"HCCL_CONNECT_TIMEOUT":"600","ASCEND_GLOBAL_LOG_LEVEL":"1","ASCEND_RT_VISIBLE_DEVICES":"1,0"}}`,
+			expectedEnv: []string{"ASCEND_GLOBAL_LOG_LEVEL=1", "ASCEND_RT_VISIBLE_DEVICES=1,0", "HCCL_CONNECT_TIMEOUT=600"},
+		},
+		"mounts": {
+			runtime: `{"mounts":[{"hostPath":"$HOST/log/npu/profiling","containerPath":"/profiling"},{"hostPath":"$HOST/log/npu","containerPath":"/logs","readOnly":true}]}`,
+			expectedMounts: []*cdispec.Mount{
+				{HostPath: "$HOST/log/npu/profiling", ContainerPath: "/profiling", Type: "bind", Options: []string{"rbind", "nosuid", "nodev", "rw"}},
+				{HostPath: "$HOST/log/npu", ContainerPath: "/logs", Type: "bind", Options: []string{"rbind", "nosuid", "nodev", "ro"}},
+			},
+		},
+		"symlink within the root": {
+			runtime: `{"mounts":[{"hostPath":"$HOST/log/npu/latest","containerPath":"/profiling"}]}`,
+			expectedMounts: []*cdispec.Mount{
+				{HostPath: "$HOST/log/npu/profiling", ContainerPath: "/profiling", Type: "bind", Options: []string{"rbind", "nosuid", "nodev", "rw"}},
+			},
+		},
+		"env not allowed": {
+			runtime: `{"env":{"LD_PRELOAD":"/tmp/hook.so"}}`,
+			err:     "environment variable LD_PRELOAD is not allowed by the cluster administrator",
+		},
+		"invalid env value": {
+			runtime: `{"env":{"ASCEND_GLOBAL_LOG_LEVEL":"verbose"}}`,
+			err:     `invalid value of environment variable ASCEND_GLOBAL_LOG_LEVEL: "verbose"`,
+		},
+		"visible device out of range": {
+			runtime: `{"env":{"ASCEND_RT_VISIBLE_DEVICES":"0,2"}}`,
+			err:     "ASCEND_RT_VISIBLE_DEVICES refers to device 2, but the config only applies to 2 devices",
+		},
+		"mount outside the roots": {
+			runtime: `{"mounts":[{"hostPath":"$HOST/log/npu-other","containerPath":"/profiling"}]}`,
+			err:     "host path $HOST/log/npu-other is not below a directory the cluster administrator allows mounting ([$HOST/log/npu])",
+		},
+		"symlink escaping the root": {
+			runtime: `{"mounts":[{"hostPath":"$HOST/log/npu/escape","containerPath":"/profiling"}]}`,
+			err:     "host path $HOST/log/npu/escape resolves to $HOST/secret, which is not below a directory the cluster administrator allows mounting ([$HOST/log/npu])",
+		},
+		"missing host path": {
+			runtime: `{"mounts":[{"hostPath":"$HOST/log/npu/missing","containerPath":"/profiling"}]}`,
+			err:     "cannot resolve host path $HOST/log/npu/missing",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// The host has a mount root with a link to a directory below it
+			// and one to a directory outside of it.
+			host, err := filepath.EvalSymlinks(t.TempDir())
+			require.NoError(t, err)
+			for _, dir := range []string{"log/npu/profiling", "log/npu-other", "secret"} {
+				require.NoError(t, os.MkdirAll(filepath.Join(host, dir), 0o755))
+			}
+			require.NoError(t, os.Symlink("profiling", filepath.Join(host, "log/npu/latest")))
+			require.NoError(t, os.Symlink(filepath.Join(host, "secret"), filepath.Join(host, "log/npu/escape")))
+			onHost := func(s string) string {
+				return strings.ReplaceAll(s, "$HOST", host)
+			}
+
+			config := newTestConfig(t)
+			config.flags.runtimeMountRoots = []string{filepath.Join(host, "log/npu")}
+			newBackend := backendOf(&fakeBackend{devices: twoCards()}, nil)
+			state, err := newDeviceState(config, t.TempDir(), newBackend)
+			require.NoError(t, err)
+			npus := npuStates(t, state)
+
+			_, err = state.Prepare(newTestClaim("claim", []string{"npu-0-0", "npu-1-0"}, runtimeConfig(onHost(test.runtime))))
+			if test.err != "" {
+				assert.ErrorContains(t, err, onHost(test.err))
+				assert.Empty(t, state.checkpoint.V1.PreparedClaims)
+				assert.Equal(t, npus, npuStates(t, state))
+				return
+			}
+			require.NoError(t, err)
+			prepared := state.checkpoint.V1.PreparedClaims["claim"]
+			require.Len(t, prepared, 2)
+			for _, device := range prepared {
+				for _, env := range test.expectedEnv {
+					assert.Contains(t, device.ContainerEdits.Env, env)
+				}
+				var mounts []*cdispec.Mount
+				for _, mount := range test.expectedMounts {
+					expected := *mount
+					expected.HostPath = onHost(mount.HostPath)
+					mounts = append(mounts, &expected)
+				}
+				assert.Equal(t, mounts, device.ContainerEdits.Mounts)
+			}
+		})
+	}
+}
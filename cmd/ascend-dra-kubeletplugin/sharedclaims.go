@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	resourceapi "k8s.io/api/resource/v1beta1"
+
+	configapi "Ascend-dra-driver/api/example.com/resource/npu"
+)
+
+// defaultSharedClaimStrategies are the sharing strategies claims can be
+// shared by several pods with, unless configured otherwise. Claims whose
+// configs leave the sharing strategy to the default are never shared.
+var defaultSharedClaimStrategies = []string{
+	string(configapi.TimeSlicingStrategy),
+	string(configapi.SpacePartitioningStrategy),
+}
+
+// parseSharedClaimStrategies parses the sharing strategies claims can be
+// shared by several pods with. Empty values are ignored, so that sharing
+// claims can be disabled with an empty list.
+func parseSharedClaimStrategies(values []string) ([]configapi.NpuSharingStrategy, error) {
+	var strategies []configapi.NpuSharingStrategy
+	for _, value := range values {
+		strategy := configapi.NpuSharingStrategy(value)
+		switch strategy {
+		case "":
+			continue
+		case configapi.TimeSlicingStrategy, configapi.SpacePartitioningStrategy:
+			strategies = append(strategies, strategy)
+		default:
+			return nil, fmt.Errorf("unknown sharing strategy %q", value)
+		}
+	}
+	return strategies, nil
+}
+
+// claimConsumers returns the sorted UIDs of the pods claim is reserved for.
+func claimConsumers(claim *resourceapi.ResourceClaim) []string {
+	var consumers []string
+	for _, ref := range claim.Status.ReservedFor {
+		if ref.APIGroup == "" && ref.Resource == "pods" {
+			consumers = append(consumers, string(ref.UID))
+		}
+	}
+	slices.Sort(consumers)
+	return slices.Compact(consumers)
+}
+
+// checkSharedClaim returns an error if claim is reserved for several pods,
+// but one of its devices is not explicitly configured with a sharing strategy
+// claims can be shared with. Devices allocated for administrative access are
+// not computed on and can always be shared.
+func (s *DeviceState) checkSharedClaim(claim *resourceapi.ResourceClaim, configs []*OpaqueDeviceConfig) error {
+	consumers := claimConsumers(claim)
+	if len(consumers) <= 1 {
+		return nil
+	}
+	for _, result := range claim.Status.Allocation.Devices.Results {
+		if isAdminAccess(&result) {
+			continue
+		}
+		sharing := explicitSharing(result.Request, configs)
+		if sharing == nil || !slices.Contains(s.sharedClaimStrategies, sharing.Strategy) {
+			return fmt.Errorf("claim is reserved for %d pods, but device %s does not use a sharing strategy claims can be shared with (%v)",
+				len(consumers), result.Device, s.sharedClaimStrategies)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resourceapi "k8s.io/api/resource/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+
+	configapi "Ascend-dra-driver/api/example.com/resource/npu"
+)
+
+// reserveFor reserves claim for pods with the given UIDs.
+func reserveFor(claim *resourceapi.ResourceClaim, pods ...string) *resourceapi.ResourceClaim {
+	for _, pod := range pods {
+		claim.Status.ReservedFor = append(claim.Status.ReservedFor, resourceapi.ResourceClaimConsumerReference{
+			Resource: "pods",
+			Name:     pod,
+			UID:      types.UID(pod),
+		})
+	}
+	return claim
+}
+
+func sharingConfig(strategy configapi.NpuSharingStrategy) string {
+	return `{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"NpuConfig","sharing":{"strategy":"` + string(strategy) + `"}}`
+}
+
+func TestParseSharedClaimStrategies(t *testing.T) {
+	tests := map[string]struct {
+		values   []string
+		expected []configapi.NpuSharingStrategy
+		err      string
+	}{
+		"defaults": {
+			values:   defaultSharedClaimStrategies,
+			expected: []configapi.NpuSharingStrategy{configapi.TimeSlicingStrategy, configapi.SpacePartitioningStrategy},
+		},
+		"empty": {
+			values: []string{""},
+		},
+		"unknown": {
+			values: []string{"TimeSlicing", "Exclusive"},
+			err:    `unknown sharing strategy "Exclusive"`,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			strategies, err := parseSharedClaimStrategies(test.values)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, strategies)
+		})
+	}
+}
+
+func TestPrepareSharedClaim(t *testing.T) {
+	tests := map[string]struct {
+		strategies []string
+		claim      *resourceapi.ResourceClaim
+		err        string
+	}{
+		"single pod without shared strategies": {
+			claim: reserveFor(newTestClaim("claim", []string{"npu-0-0"}), "pod-a"),
+		},
+		"several pods with an explicit strategy": {
+			strategies: defaultSharedClaimStrategies,
+			claim:      reserveFor(newTestClaim("claim", []string{"npu-0-0"}, sharingConfig(configapi.TimeSlicingStrategy)), "pod-a", "pod-b"),
+		},
+		"several pods with the default strategy": {
+			strategies: defaultSharedClaimStrategies,
+			claim:      reserveFor(newTestClaim("claim", []string{"npu-0-0"}), "pod-a", "pod-b"),
+			err:        "claim is reserved for 2 pods, but device npu-0-0 does not use a sharing strategy claims can be shared with ([TimeSlicing SpacePartitioning])",
+		},
+		"several pods with an allowed strategy": {
+			strategies: []string{string(configapi.SpacePartitioningStrategy)},
+			claim:      reserveFor(newTestClaim("claim", []string{"npu-0-0"}, sharingConfig(configapi.SpacePartitioningStrategy)), "pod-a", "pod-b"),
+		},
+		"several pods with another strategy": {
+			strategies: []string{string(configapi.SpacePartitioningStrategy)},
+			claim:      reserveFor(newTestClaim("claim", []string{"npu-0-0"}, sharingConfig(configapi.TimeSlicingStrategy)), "pod-a", "pod-b"),
+			err:        "claim is reserved for 2 pods, but device npu-0-0 does not use a sharing strategy claims can be shared with ([SpacePartitioning])",
+		},
+		"several pods without shared strategies": {
+			claim: reserveFor(newTestClaim("claim", []string{"npu-0-0"}), "pod-a", "pod-b"),
+			err:   "claim is reserved for 2 pods",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := newTestConfig(t)
+			config.flags.sharedClaimStrategies = test.strategies
+			newBackend := backendOf(&fakeBackend{devices: twoCards()}, nil)
+			state, err := newDeviceState(config, t.TempDir(), newBackend)
+			require.NoError(t, err)
+
+			_, err = state.Prepare(test.claim)
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				assert.Empty(t, state.checkpoint.V1.PreparedClaims)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, state.checkpoint.V1.PreparedClaims, "claim")
+		})
+	}
+}
+
+func TestPrepareSharedClaimForTwoPods(t *testing.T) {
+	config := newTestConfig(t)
+	newBackend := backendOf(&fakeBackend{devices: twoCards()}, nil)
+	state, err := newDeviceState(config, t.TempDir(), newBackend)
+	require.NoError(t, err)
+
+	params := `{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"NpuConfig","sharing":{"strategy":"TimeSlicing"},"vnpu":{"template":"vir02"}}`
+	claim := reserveFor(newTestClaim("claim", []string{"npu-0-0"}, params), "pod-a")
+	first, err := state.Prepare(claim)
+	require.NoError(t, err)
+	allocated := npuStates(t, state)
+	require.Len(t, state.vnpuManager.PhysicalNpus["npu-0-0"].AllocatedSlices, 1)
+
+	// kubelet prepares the claim again for the second pod on the node, which
+	// gets the same devices without allocating anything.
+	second, err := state.Prepare(reserveFor(claim.DeepCopy(), "pod-b"))
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, allocated, npuStates(t, state))
+
+	// kubelet unprepares the claim once neither pod uses it anymore, which
+	// releases its devices.
+	require.NoError(t, state.Unprepare("claim"))
+	assert.Empty(t, state.vnpuManager.PhysicalNpus["npu-0-0"].AllocatedSlices)
+	assert.Empty(t, state.checkpoint.V1.PreparedClaims)
+	checkpoint := newCheckpoint()
+	require.NoError(t, state.checkpointManager.GetCheckpoint(DriverPluginCheckpointFile, checkpoint))
+	assert.Empty(t, checkpoint.V1.PreparedClaims)
+	require.NoError(t, state.Unprepare("claim"))
+}
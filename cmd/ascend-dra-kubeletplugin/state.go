@@ -1,29 +1,26 @@
 package main
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"log"
-	"math"
+	"maps"
 	"os"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 
 	resourceapi "k8s.io/api/resource/v1beta1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1beta1"
 	"k8s.io/kubernetes/pkg/kubelet/checkpointmanager"
 	"k8s.io/utils/ptr"
 
-	configapi "Ascend-dra-driver/api/example.com/resource/gpu/v1alpha1"
+	configapi "Ascend-dra-driver/api/example.com/resource/npu"
+	configinstall "Ascend-dra-driver/api/example.com/resource/npu/install"
 
-	"k8s.io/apimachinery/pkg/api/errors"
 	cdiapi "tags.cncf.io/container-device-interface/pkg/cdi"
 	cdispec "tags.cncf.io/container-device-interface/specs-go"
 )
@@ -38,11 +35,17 @@ type (
 type OpaqueDeviceConfig struct {
 	Requests []string
 	Config   runtime.Object
+	// SetsSharing is set if the parameters of the config set a sharing
+	// strategy, rather than leaving the default one to defaulting.
+	SetsSharing bool
 }
 
 type VnpuTemplateAttribute struct {
 	AICORE int
 	Memory int
+	AICPU  int
+	// DVPP is set if the template includes media processing units.
+	DVPP bool
 }
 
 type VnpuTemplate struct {
@@ -55,6 +58,13 @@ type VnpuSlice struct {
 	TemplateName string
 	Allocated    bool
 	Type         string
+	ClaimUID     string
+	// OnChip is set for vNPUs that already exist on the chip, identified by VDevID.
+	OnChip bool
+	VDevID uint32
+	// TimeSliced is set for whole-card slices allocated to claims that
+	// time-slice the card with other claims.
+	TimeSliced bool
 }
 
 type PhysicalNpuState struct {
@@ -62,18 +72,36 @@ type PhysicalNpuState struct {
 	PhysicalDeviceID string
 	LogicID          int32
 	ModelName        string
+	TotalAicore      int
+	TotalMemory      int
 	AvailableSlices  []*VnpuSlice
 	AllocatedSlices  []*VnpuSlice
 	SupportTemplates map[string]*VnpuTemplate
 	NextSliceIndex   int
+	// Static cards have a layout declared by the administrator and are never
+	// split on demand.
+	Static bool
+	// Unavailable cards are no longer reported by the NPU driver. Their free
+	// slices are not published, but prepared slices are kept.
+	Unavailable bool
+	// PartitionTemplate is the template of the PartitionCount equal vNPUs
+	// the card is partitioned into by claims using space partitioning, if any.
+	PartitionTemplate string
+	PartitionCount    int
 }
 
 type DeviceUpdateCallback func(deviceName string, physicalNpu *PhysicalNpuState)
 
 type VnpuManager struct {
 	sync.Mutex
-	PhysicalNpus         map[string]*PhysicalNpuState
-	Templates            map[string]*VnpuTemplate
+	PhysicalNpus map[string]*PhysicalNpuState
+	Templates    map[string]*VnpuTemplate
+	// ReclaimEmptyCards resets a carved card to a single whole-card device
+	// as soon as its last vNPU slice is released.
+	ReclaimEmptyCards bool
+	// MaxTimeSlicedClaims is the number of claims that can time-slice a
+	// whole card. Cards are not shared between claims if it is less than 2.
+	MaxTimeSlicedClaims  int
 	deviceUpdateCallback DeviceUpdateCallback
 }
 
@@ -86,6 +114,17 @@ func (m *VnpuManager) SetDeviceUpdateCallback(callback DeviceUpdateCallback) {
 type PreparedDevice struct {
 	drapbv1.Device
 	ContainerEdits *cdiapi.ContainerEdits
+	// TemplateName is the vNPU template the device was carved with, if any.
+	TemplateName string `json:"templateName,omitempty"`
+	// VDevID is the ID of the on-chip vNPU backing the device, if it already
+	// existed on the chip when the device was prepared.
+	VDevID *uint32 `json:"vdevID,omitempty"`
+	// TimeSliced is set for whole cards that the claim time-slices with
+	// other claims.
+	TimeSliced bool `json:"timeSliced,omitempty"`
+	// AdminAccess is set for devices prepared for administrative access.
+	// Their slices belong to the claims of the workloads using them.
+	AdminAccess bool `json:"adminAccess,omitempty"`
 }
 
 func (pds PreparedDevices) GetDevices() []*drapbv1.Device {
@@ -96,18 +135,76 @@ func (pds PreparedDevices) GetDevices() []*drapbv1.Device {
 	return devices
 }
 
+// DeviceState is the state of the devices of the node. Claims are prepared
+// and unprepared under the read lock, concurrently as long as they are on
+// different physical NPUs, while discovery replaces the devices under the
+// write lock.
 type DeviceState struct {
-	sync.Mutex
-	cdi               *CDIHandler
-	allocatable       AllocatableDevices
+	sync.RWMutex
+	cdi *CDIHandler
+	// allocatableMu guards allocatable, which the vNPU manager updates while
+	// it is locked.
+	allocatableMu sync.Mutex
+	allocatable   AllocatableDevices
+	// checkpointMu guards checkpoint, the authoritative copy of the
+	// checkpoint file, which every change is written through to.
+	checkpointMu      sync.Mutex
+	checkpoint        *Checkpoint
 	checkpointManager checkpointmanager.CheckpointManager
+	npuLocks          npuLocks
+	// onChange is called, possibly with locks held, whenever devices are
+	// added to or restored in allocatable.
+	onChange          func()
 	vnpuManager       *VnpuManager
+	mgr               NpuBackend
+	layout            *NodeVnpuLayout
+	newBackend        backendFactory
+	reclaimEmptyCards bool
+	// maxTimeSlicedClaims is the number of claims that can time-slice a card.
+	maxTimeSlicedClaims int
+	// sharedClaimStrategies are the sharing strategies claims reserved for
+	// several pods can be prepared with.
+	sharedClaimStrategies []configapi.NpuSharingStrategy
+	// runtimePolicy holds the runtime settings claims can make.
+	runtimePolicy *runtimePolicy
 }
 
+// NewDeviceState creates the device state. If the NPU driver cannot be used
+// yet, the state starts without devices and DiscoverDevices has to be retried.
 func NewDeviceState(config *Config) (*DeviceState, error) {
-	allocatable, vnpuManager, err := enumerateAllPossibleDevices()
+	return newDeviceState(config, DriverPluginPath, newAscendBackend)
+}
+
+func newDeviceState(config *Config, checkpointDir string, newBackend backendFactory) (*DeviceState, error) {
+	layout, err := LoadVnpuLayout(config.flags.vnpuLayoutFile, config.flags.nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("error loading static vNPU layout: %v", err)
+	}
+
+	sharedClaimStrategies, err := parseSharedClaimStrategies(config.flags.sharedClaimStrategies)
 	if err != nil {
-		return nil, fmt.Errorf("error enumerating all possible devices: %v", err)
+		return nil, fmt.Errorf("invalid shared claim strategies: %v", err)
+	}
+
+	runtimePolicy, err := parseRuntimePolicy(config.flags.runtimeEnvAllowlist, config.flags.runtimeMountRoots)
+	if err != nil {
+		return nil, fmt.Errorf("invalid runtime settings policy: %v", err)
+	}
+
+	checkpointManager, err := checkpointmanager.NewCheckpointManager(checkpointDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create checkpoint manager: %v", err)
+	}
+
+	checkpoints, err := checkpointManager.ListCheckpoints()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list checkpoints: %v", err)
+	}
+
+	if !slices.Contains(checkpoints, DriverPluginCheckpointFile) {
+		if err := checkpointManager.CreateCheckpoint(DriverPluginCheckpointFile, newCheckpoint()); err != nil {
+			return nil, fmt.Errorf("unable to sync to checkpoint: %v", err)
+		}
 	}
 
 	cdi, err := NewCDIHandler(config)
@@ -120,103 +217,256 @@ func NewDeviceState(config *Config) (*DeviceState, error) {
 		return nil, fmt.Errorf("unable to create CDI spec file for common edits: %v", err)
 	}
 
-	checkpointManager, err := checkpointmanager.NewCheckpointManager(DriverPluginPath)
-	if err != nil {
-		return nil, fmt.Errorf("unable to create checkpoint manager: %v", err)
+	checkpoint := newCheckpoint()
+	if err := checkpointManager.GetCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
+		return nil, fmt.Errorf("unable to sync from checkpoint: %v", err)
+	}
+	if err := rollBackInterruptedPrepares(checkpointManager, checkpoint, cdi); err != nil {
+		return nil, fmt.Errorf("unable to roll back interrupted claim preparations: %v", err)
 	}
 
 	state := &DeviceState{
 		cdi:               cdi,
-		allocatable:       allocatable,
+		allocatable:       make(AllocatableDevices),
+		checkpoint:        checkpoint.clone(),
 		checkpointManager: checkpointManager,
-		vnpuManager:       vnpuManager,
-	}
+		layout:            layout,
+		newBackend:        newBackend,
+		reclaimEmptyCards: config.flags.reclaimEmptyCards,
 
-	if vnpuManager != nil {
-		vnpuManager.SetDeviceUpdateCallback(func(deviceName string, physicalNpu *PhysicalNpuState) {
-			if added := state.UpdateAllocatableDevice(deviceName, physicalNpu); added {
-				log.Printf("Added new device %s to allocatable devices", deviceName)
-			}
-		})
+		maxTimeSlicedClaims:   config.flags.maxTimeSlicedClaims,
+		sharedClaimStrategies: sharedClaimStrategies,
+		runtimePolicy:         runtimePolicy,
 	}
 
-	checkpoints, err := state.checkpointManager.ListCheckpoints()
-	if err != nil {
-		return nil, fmt.Errorf("unable to list checkpoints: %v", err)
-	}
-
-	for _, c := range checkpoints {
-		if c == DriverPluginCheckpointFile {
-			if vnpuManager != nil {
-				if err := CreatePredefinedDeviceClasses(vnpuManager); err != nil {
-					log.Printf("Failed to create predefined DeviceClasses: %v", err)
-				}
-			}
-			return state, nil
+	if err := state.DiscoverDevices(); err != nil {
+		if !isNpuDriverUnavailable(err) {
+			return nil, fmt.Errorf("error enumerating all possible devices: %v", err)
 		}
+		log.Printf("Starting without devices: %v", err)
 	}
+	return state, nil
+}
 
-	checkpoint := newCheckpoint()
-	if err := state.checkpointManager.CreateCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
-		return nil, fmt.Errorf("unable to sync to checkpoint: %v", err)
+// Ready reports whether the devices have been discovered through the NPU driver.
+func (s *DeviceState) Ready() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.mgr != nil
+}
+
+// VnpuManager returns the vNPU manager, or nil if the devices have not been
+// discovered or cards cannot be split. It is replaced whenever the devices
+// are discovered again.
+func (s *DeviceState) VnpuManager() *VnpuManager {
+	s.RLock()
+	defer s.RUnlock()
+	return s.vnpuManager
+}
+
+// DiscoverDevices enumerates the devices through the NPU driver, adopting the
+// vNPUs of the claims recorded in the checkpoint and reserving the other
+// slices of those claims. It returns an error wrapping
+// errNpuDriverUnavailable if the driver cannot be used yet, and an error if
+// the devices of those claims cannot all be allocated to them again.
+func (s *DeviceState) DiscoverDevices() error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.checkpointMu.Lock()
+	preparedClaims := s.checkpoint.clone().V1.PreparedClaims
+	s.checkpointMu.Unlock()
+
+	allocatable, vnpuManager, mgr, err := enumerateAllPossibleDevices(s.newBackend, s.layout, preparedClaims)
+	if err != nil {
+		return err
 	}
 	if vnpuManager != nil {
-		go func() {
-			if err := CreatePredefinedDeviceClasses(vnpuManager); err != nil {
-				log.Printf("Failed to create predefined DeviceClasses: %v", err)
+		vnpuManager.ReclaimEmptyCards = s.reclaimEmptyCards
+		vnpuManager.MaxTimeSlicedClaims = s.maxTimeSlicedClaims
+		// The slices of prepared claims that were not adopted from the chip
+		// are reserved again before the devices are published.
+		for _, npu := range vnpuManager.ReservePreparedSlices(preparedClaims) {
+			republishCard(allocatable, npu, vnpuManager)
+		}
+		if err := vnpuManager.CheckPreparedClaims(preparedClaims); err != nil {
+			return err
+		}
+		vnpuManager.SetDeviceUpdateCallback(func(deviceName string, physicalNpu *PhysicalNpuState) {
+			if physicalNpu.Unavailable {
+				return
 			}
-		}()
+			if added := s.UpdateAllocatableDevice(deviceName, physicalNpu); added {
+				log.Printf("Added new device %s to allocatable devices", deviceName)
+			}
+		})
 	}
-	return state, nil
+	s.allocatableMu.Lock()
+	s.allocatable = allocatable
+	s.allocatableMu.Unlock()
+	s.changed()
+	s.vnpuManager = vnpuManager
+	s.mgr = mgr
+	return nil
 }
 
+// Prepare prepares the devices allocated to claim. Claims on different
+// physical NPUs are prepared concurrently.
 func (s *DeviceState) Prepare(claim *resourceapi.ResourceClaim) ([]*drapbv1.Device, error) {
-	s.Lock()
-	defer s.Unlock()
+	s.RLock()
+	defer s.RUnlock()
 
 	claimUID := string(claim.UID)
+	if claim.Status.Allocation == nil {
+		return nil, fmt.Errorf("prepare failed: claim not yet allocated")
+	}
+	var deviceNames []string
+	for _, result := range claim.Status.Allocation.Devices.Results {
+		deviceNames = append(deviceNames, result.Device)
+	}
+	defer s.npuLocks.lock(deviceNames)()
 
-	checkpoint := newCheckpoint()
-	if err := s.checkpointManager.GetCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
-		return nil, fmt.Errorf("unable to sync from checkpoint: %v", err)
+	configs, err := claimConfigs(claim)
+	if err != nil {
+		return nil, fmt.Errorf("prepare failed: %v", err)
+	}
+	if err := s.checkSharedClaim(claim, configs); err != nil {
+		return nil, fmt.Errorf("prepare failed: %v", err)
 	}
-	preparedClaims := checkpoint.V1.PreparedClaims
 
-	if preparedClaims[claimUID] != nil {
-		return preparedClaims[claimUID].GetDevices(), nil
+	// A claim shared by several pods is prepared once. Preparing it again
+	// returns the same devices.
+	if prepared := s.preparedClaim(claimUID); prepared != nil {
+		return prepared.GetDevices(), nil
 	}
 
-	preparedDevices, err := s.prepareDevices(claim)
+	// Preparing a claim is all or nothing: until the checkpoint records the
+	// claim as prepared, every side effect is journaled and undone on failure.
+	journal := &prepareJournal{}
+	preparedDevices, err := s.prepareDevices(claim, configs, journal)
 	if err != nil {
-		return nil, fmt.Errorf("prepare failed: %v", err)
+		return nil, rollbackPrepare(claimUID, journal, fmt.Errorf("prepare failed: %v", err))
 	}
 
 	if err = s.cdi.CreateClaimSpecFile(claimUID, preparedDevices); err != nil {
-		return nil, fmt.Errorf("unable to create CDI spec file for claim: %v", err)
+		return nil, rollbackPrepare(claimUID, journal, fmt.Errorf("unable to create CDI spec file for claim: %v", err))
+	}
+	journal.record("CDI spec file of claim "+claimUID, func() error {
+		return s.cdi.DeleteClaimSpecFile(claimUID)
+	})
+
+	// Commit: the claim turns from being prepared into prepared at once.
+	err = s.updateCheckpoint(func(checkpoint *CheckpointV1) {
+		delete(checkpoint.PreparingClaims, claimUID)
+		checkpoint.PreparedClaims[claimUID] = preparedDevices
+	})
+	if err != nil {
+		return nil, rollbackPrepare(claimUID, journal, fmt.Errorf("unable to sync to checkpoint: %v", err))
 	}
 
-	preparedClaims[claimUID] = preparedDevices
+	return preparedDevices.GetDevices(), nil
+}
+
+// preparedClaim returns the devices the checkpoint records as prepared for
+// claimUID, or nil.
+func (s *DeviceState) preparedClaim(claimUID string) PreparedDevices {
+	s.checkpointMu.Lock()
+	defer s.checkpointMu.Unlock()
+	return s.checkpoint.V1.PreparedClaims[claimUID]
+}
+
+// updateCheckpoint applies update to a copy of the checkpoint and writes it.
+// The copy only replaces the checkpoint once it has been written, so that
+// the checkpoint never records what the file does not.
+func (s *DeviceState) updateCheckpoint(update func(checkpoint *CheckpointV1)) error {
+	s.checkpointMu.Lock()
+	defer s.checkpointMu.Unlock()
+
+	checkpoint := s.checkpoint.clone()
+	update(checkpoint.V1)
 	if err := s.checkpointManager.CreateCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
-		return nil, fmt.Errorf("unable to sync to checkpoint: %v", err)
+		return err
+	}
+	s.checkpoint = checkpoint
+	return nil
+}
+
+// beginPrepare records in the checkpoint that claimUID is about to be
+// prepared on the devices of intent, before anything is done for it. The
+// record is removed again when the journal is rolled back.
+func (s *DeviceState) beginPrepare(claimUID string, intent *PreparingClaim, journal *prepareJournal) error {
+	err := s.updateCheckpoint(func(checkpoint *CheckpointV1) {
+		checkpoint.PreparingClaims[claimUID] = intent
+	})
+	if err != nil {
+		return fmt.Errorf("unable to record claim as being prepared in checkpoint: %v", err)
+	}
+	journal.record("checkpoint record of claim "+claimUID, func() error {
+		return s.updateCheckpoint(func(checkpoint *CheckpointV1) {
+			delete(checkpoint.PreparingClaims, claimUID)
+			delete(checkpoint.PreparedClaims, claimUID)
+		})
+	})
+	return nil
+}
+
+// rollBackInterruptedPrepares rolls back the claims that checkpoint records as
+// being prepared, whose Prepare was interrupted by a crash or a failed
+// rollback. Their vNPU slices were lost with the memory of the plugin, and
+// vNPUs are only created on the chip once a container using a prepared claim
+// starts, so what is left to undo is the CDI spec file. kubelet prepares the
+// claims again since their Prepare never succeeded.
+func rollBackInterruptedPrepares(checkpointManager checkpointmanager.CheckpointManager, checkpoint *Checkpoint, cdi *CDIHandler) error {
+	if len(checkpoint.V1.PreparingClaims) == 0 {
+		return nil
 	}
+	for claimUID, intent := range checkpoint.V1.PreparingClaims {
+		if err := cdi.DeleteClaimSpecFile(claimUID); err != nil {
+			return fmt.Errorf("unable to delete CDI spec file of claim %s: %v", claimUID, err)
+		}
+		delete(checkpoint.V1.PreparingClaims, claimUID)
+		log.Printf("Rolled back interrupted Prepare of claim %s on %v", claimUID, intent.Devices)
+	}
+	if err := checkpointManager.CreateCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
+		return fmt.Errorf("unable to sync to checkpoint: %v", err)
+	}
+	return nil
+}
 
-	return preparedClaims[claimUID].GetDevices(), nil
+// rollbackPrepare undoes what journal recorded while preparing a claim that
+// failed with err, and returns err along with any error of the rollback.
+func rollbackPrepare(claimUID string, journal *prepareJournal, err error) error {
+	log.Printf("Preparing claim %s failed, rolling back: %v", claimUID, err)
+	if rollbackErr := journal.rollback(); rollbackErr != nil {
+		return fmt.Errorf("%w; rollback failed: %v", err, rollbackErr)
+	}
+	return err
 }
 
+// Unprepare releases the devices prepared for claimUID. Claims on different
+// physical NPUs are unprepared concurrently. kubelet only unprepares a claim
+// once no pod of the node uses it anymore, and all pods a claim is reserved
+// for run on the node of its devices, so a claim shared by several pods is
+// released with its last consumer.
 func (s *DeviceState) Unprepare(claimUID string) error {
-	s.Lock()
-	defer s.Unlock()
+	s.RLock()
+	defer s.RUnlock()
 
-	checkpoint := newCheckpoint()
-	if err := s.checkpointManager.GetCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
-		return fmt.Errorf("unable to sync from checkpoint: %v", err)
+	prepared := s.preparedClaim(claimUID)
+	if prepared == nil {
+		return nil
+	}
+	var deviceNames []string
+	for _, device := range prepared {
+		deviceNames = append(deviceNames, device.DeviceName)
 	}
-	preparedClaims := checkpoint.V1.PreparedClaims
-	if preparedClaims[claimUID] == nil {
+	defer s.npuLocks.lock(deviceNames)()
+	// The claim may have been unprepared while waiting for the locks.
+	if prepared = s.preparedClaim(claimUID); prepared == nil {
 		return nil
 	}
 
-	if err := s.unprepareDevices(claimUID, preparedClaims[claimUID]); err != nil {
+	if err := s.unprepareDevices(claimUID, prepared); err != nil {
 		return fmt.Errorf("unprepare failed: %v", err)
 	}
 
@@ -225,22 +475,22 @@ func (s *DeviceState) Unprepare(claimUID string) error {
 		return fmt.Errorf("unable to delete CDI spec file for claim: %v", err)
 	}
 
-	delete(preparedClaims, claimUID)
-	if err := s.checkpointManager.CreateCheckpoint(DriverPluginCheckpointFile, checkpoint); err != nil {
+	err = s.updateCheckpoint(func(checkpoint *CheckpointV1) {
+		delete(checkpoint.PreparedClaims, claimUID)
+	})
+	if err != nil {
 		return fmt.Errorf("unable to sync to checkpoint: %v", err)
 	}
 
 	return nil
 }
 
-func (s *DeviceState) prepareDevices(claim *resourceapi.ResourceClaim) (PreparedDevices, error) {
-	if claim.Status.Allocation == nil {
-		return nil, fmt.Errorf("claim not yet allocated")
-	}
-
+// claimConfigs returns the validated device configs of claim for the driver,
+// in increasing order of precedence, starting with the default NPU config.
+func claimConfigs(claim *resourceapi.ResourceClaim) ([]*OpaqueDeviceConfig, error) {
 	// Retrieve the full set of device configs for the driver.
 	configs, err := GetOpaqueDeviceConfigs(
-		configapi.Decoder,
+		configinstall.Decoder,
 		DriverName,
 		claim.Status.Allocation.Devices.Config,
 	)
@@ -248,30 +498,108 @@ func (s *DeviceState) prepareDevices(claim *resourceapi.ResourceClaim) (Prepared
 		return nil, fmt.Errorf("error getting opaque device configs: %v", err)
 	}
 
-	// Add the default GPU Config to the front of the config list with the
+	// Validate the configs before anything is allocated for them.
+	for _, c := range configs {
+		config, ok := c.Config.(*configapi.NpuConfig)
+		if !ok {
+			return nil, fmt.Errorf("runtime object is not a recognized configuration: %T", c.Config)
+		}
+		if err := config.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid NPU config for requests %v: %w", c.Requests, err)
+		}
+		if config.Sharing.IsTimeSlicing() && config.Sharing.TimeSlicingConfig != nil &&
+			config.Sharing.TimeSlicingConfig.Interval != configapi.DefaultTimeSlice {
+			log.Printf("Warning: ignoring time-slice interval %v of the NPU config for requests %v, Ascend NPUs only support %v",
+				config.Sharing.TimeSlicingConfig.Interval, c.Requests, configapi.DefaultTimeSlice)
+		}
+	}
+
+	// Add the default NPU Config to the front of the config list with the
 	// lowest precedence. This guarantees there will be at least one config in
 	// the list with len(Requests) == 0 for the lookup below.
-	configs = slices.Insert(configs, 0, &OpaqueDeviceConfig{
+	return slices.Insert(configs, 0, &OpaqueDeviceConfig{
 		Requests: []string{},
-		Config:   configapi.DefaultGpuConfig(),
-	})
+		Config:   configapi.DefaultNpuConfig(),
+	}), nil
+}
+
+// appliedConfig returns the config with the highest precedence that applies
+// to request.
+func appliedConfig(request string, configs []*OpaqueDeviceConfig) *OpaqueDeviceConfig {
+	for _, c := range slices.Backward(configs) {
+		if len(c.Requests) == 0 || slices.Contains(c.Requests, request) {
+			return c
+		}
+	}
+	return nil
+}
+
+// npuConfig returns the config with the highest precedence that applies to
+// request.
+func npuConfig(request string, configs []*OpaqueDeviceConfig) *configapi.NpuConfig {
+	if c := appliedConfig(request, configs); c != nil {
+		config, _ := c.Config.(*configapi.NpuConfig)
+		return config
+	}
+	return nil
+}
+
+// explicitSharing returns the sharing the config applied to request sets, or
+// nil if the config leaves it to the default.
+func explicitSharing(request string, configs []*OpaqueDeviceConfig) *configapi.NpuSharing {
+	c := appliedConfig(request, configs)
+	if c == nil || !c.SetsSharing {
+		return nil
+	}
+	if config, ok := c.Config.(*configapi.NpuConfig); ok {
+		return config.Sharing
+	}
+	return nil
+}
+
+// prepareDevices allocates the devices of claim and computes their container
+// edits with the given configs. Before allocating anything, the claim is
+// recorded in the checkpoint as being prepared. Both are recorded in journal.
+// The physical NPUs of the claim have to be locked.
+func (s *DeviceState) prepareDevices(claim *resourceapi.ResourceClaim, configs []*OpaqueDeviceConfig, journal *prepareJournal) (PreparedDevices, error) {
+	intent := &PreparingClaim{}
+	for _, result := range claim.Status.Allocation.Devices.Results {
+		device := PreparingDevice{DeviceName: result.Device}
+		if !isAdminAccess(&result) {
+			device.TemplateName = vnpuRequirements(result.Request, configs).Template
+		}
+		intent.Devices = append(intent.Devices, device)
+	}
+	if err := s.beginPrepare(string(claim.UID), intent, journal); err != nil {
+		return nil, err
+	}
 
 	// Look through the configs and figure out which one will be applied to
 	// each device allocation result based on their order of precedence.
 	configResultsMap := make(map[runtime.Object][]*resourceapi.DeviceRequestAllocationResult)
+	var adminResults []*resourceapi.DeviceRequestAllocationResult
 	for _, result := range claim.Status.Allocation.Devices.Results {
 		origDevice := result.Device
+		if !s.isAllocatable(origDevice) {
+			return nil, fmt.Errorf("requested NPU is not allocatable: %v", origDevice)
+		}
 
-		// If vnpuManager is available, try to allocate vNPU slices first
+		// Devices allocated for administrative access are in use by the
+		// claims of workloads, so nothing is allocated for them and the
+		// configs for the workloads do not apply.
+		if isAdminAccess(&result) {
+			adminResults = append(adminResults, &result)
+			continue
+		}
+
+		// If vnpuManager is available, allocate the vNPU slice, or the whole
+		// card, the configs ask for.
 		if s.vnpuManager != nil {
-			if err := s.allocateVnpuSlice(&result, configs, origDevice); err != nil {
-				log.Printf("Warning: failed to allocate vNPU slice: %v, attempting to use full card allocation", err)
+			if err := s.allocateVnpuSlice(string(claim.UID), &result, configs, origDevice, journal); err != nil {
+				return nil, fmt.Errorf("cannot allocate %s for request %s: %w", origDevice, result.Request, err)
 			}
 		}
 
-		if _, ok := s.allocatable[origDevice]; !ok {
-			return nil, fmt.Errorf("requested NPU is not allocatable: %v", origDevice)
-		}
 		// Find matching config
 		for _, c := range slices.Backward(configs) {
 			if len(c.Requests) == 0 || slices.Contains(c.Requests, result.Request) {
@@ -281,34 +609,18 @@ func (s *DeviceState) prepareDevices(claim *resourceapi.ResourceClaim) (Prepared
 		}
 	}
 
-	// Normalize, validate, and apply all configs associated with devices that
-	// need to be prepared. Track container edits generated from applying the
-	// config to the set of device allocation results.
+	// Apply all configs associated with devices that need to be prepared.
+	// The decoder has already defaulted them and they were validated above.
+	// Track container edits generated from applying the config to the set of
+	// device allocation results.
 	perDeviceCDIContainerEdits := make(PerDeviceCDIContainerEdits)
 	for c, results := range configResultsMap {
-		// Cast the opaque config to a GpuConfig
-		var config *configapi.GpuConfig
-		switch castConfig := c.(type) {
-		case *configapi.GpuConfig:
-			config = castConfig
-		default:
-			return nil, fmt.Errorf("runtime object is not a regognized configuration")
-		}
-
-		// Normalize the config to set any implied defaults.
-		if err := config.Normalize(); err != nil {
-			return nil, fmt.Errorf("error normalizing GPU config: %w", err)
-		}
-
-		// Validate the config to ensure its integrity.
-		if err := config.Validate(); err != nil {
-			return nil, fmt.Errorf("error validating GPU config: %w", err)
-		}
+		config := c.(*configapi.NpuConfig)
 
 		// Apply the config to the list of results associated with it.
 		containerEdits, err := s.applyConfig(config, results)
 		if err != nil {
-			return nil, fmt.Errorf("error applying GPU config: %w", err)
+			return nil, fmt.Errorf("error applying NPU config: %w", err)
 		}
 
 		// Merge any new container edits with the overall per device map.
@@ -331,45 +643,134 @@ func (s *DeviceState) prepareDevices(claim *resourceapi.ResourceClaim) (Prepared
 				},
 				ContainerEdits: perDeviceCDIContainerEdits[result.Device],
 			}
+			if s.vnpuManager != nil {
+				device.TemplateName, _ = s.vnpuManager.GetVnpuSpecsEnv(result.Device)
+				if vdevID, ok := s.vnpuManager.GetOnChipVDevID(result.Device); ok {
+					device.VDevID = &vdevID
+				}
+				device.TimeSliced = s.vnpuManager.IsTimeSliced(result.Device)
+			}
 			preparedDevices = append(preparedDevices, device)
 		}
 	}
+	for _, result := range adminResults {
+		logicID, _, ok := parseSliceID(result.Device)
+		if !ok {
+			return nil, fmt.Errorf("device %s is not a slice of a physical NPU", result.Device)
+		}
+		phyID, err := s.mgr.GetPhysicIDFromLogicID(logicID)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get the physical ID of device %s: %w", result.Device, err)
+		}
+		preparedDevices = append(preparedDevices, &PreparedDevice{
+			Device: drapbv1.Device{
+				RequestNames: []string{result.Request},
+				PoolName:     result.Pool,
+				DeviceName:   result.Device,
+				CDIDeviceIDs: s.cdi.GetClaimDevices(string(claim.UID), []string{result.Device}),
+			},
+			ContainerEdits: adminContainerEdits(result.Device, phyID),
+			AdminAccess:    true,
+		})
+	}
 
 	return preparedDevices, nil
 }
 
-// allocateVnpuSlice tries to allocate a vNPU slice based on user requirements
+// isAdminAccess reports whether result was allocated for administrative
+// access, like monitoring the NPU of a running workload.
+func isAdminAccess(result *resourceapi.DeviceRequestAllocationResult) bool {
+	return ptr.Deref(result.AdminAccess, false)
+}
+
+// adminContainerEdits exposes the device nodes of the physical NPU of
+// deviceName, whose device node is numbered by its physical ID phyID, that
+// tools like npu-smi and profilers use. The container is not given the NPU to
+// compute on, so ASCEND_VISIBLE_DEVICES is left to the workload.
+func adminContainerEdits(deviceName string, phyID int32) *cdiapi.ContainerEdits {
+	// Device names contain hyphens, which environment variable names cannot.
+	envName := strings.ReplaceAll(strings.TrimPrefix(deviceName, "npu-"), "-", "_")
+	edits := &cdispec.ContainerEdits{
+		Env: []string{fmt.Sprintf("NPU_DEVICE_%s_ADMIN_ACCESS=true", envName)},
+		DeviceNodes: []*cdispec.DeviceNode{
+			{Path: "/dev/davinci_manager"},
+			{Path: "/dev/devmm_svm"},
+			{Path: "/dev/hisi_hdc"},
+			{Path: fmt.Sprintf("/dev/davinci%d", phyID)},
+		},
+	}
+	return &cdiapi.ContainerEdits{ContainerEdits: edits}
+}
+
+// allocateVnpuSlice allocates the vNPU slice requested by the configs that
+// apply to result, or the whole card if none of them asks for a vNPU. The
+// allocation is recorded in journal along with the previous state of the
+// physical NPU and of its allocatable devices.
 func (s *DeviceState) allocateVnpuSlice(
+	claimUID string,
 	result *resourceapi.DeviceRequestAllocationResult,
 	configs []*OpaqueDeviceConfig,
 	origDevice string,
+	journal *prepareJournal,
 ) error {
-	var requestedAicore, requestedMemory int
-	var templateName string
-	for _, oc := range configs {
-		if gpuConfig, ok := oc.Config.(*configapi.GpuConfig); ok {
-			if gpuConfig.VnpuSpec != nil && gpuConfig.VnpuSpec.TemplateName != "" {
-				templateName = gpuConfig.VnpuSpec.TemplateName
-				if tpl, found := s.vnpuManager.Templates[templateName]; found {
-					requestedAicore = tpl.Attributes.AICORE
-					requestedMemory = tpl.Attributes.Memory
-					log.Printf("Obtained resource requirements from template %s: AICORE=%d, Memory=%dGB",
-						templateName, requestedAicore, requestedMemory)
-					break
-				}
-			}
-		}
+	req := vnpuRequirements(result.Request, configs)
+	if !req.WholeCard() {
+		log.Printf("Obtained vNPU requirements for request %s: %v", result.Request, req)
 	}
-	slice, err := s.vnpuManager.AllocateSlice(origDevice, requestedAicore, requestedMemory)
+	restoreNpu := s.vnpuManager.saveNpuState(origDevice)
+	restoreAllocatable := s.saveAllocatable(origDevice)
+	slice, err := s.vnpuManager.AllocateSlice(claimUID, origDevice, req)
 	if err != nil {
 		return err
 	}
+	journal.record("vNPU slice "+slice.SliceID+" of claim "+claimUID, func() error {
+		restoreNpu()
+		restoreAllocatable()
+		return nil
+	})
 	result.Device = slice.SliceID
-	log.Printf("Successfully allocated vNPU slice for device %s: %s (template: %s, AICORE: %d, Memory: %dGB)",
-		origDevice, slice.SliceID, templateName, requestedAicore, requestedMemory)
+	log.Printf("Successfully allocated vNPU slice for device %s: %s (template: %s, %v)",
+		origDevice, slice.SliceID, slice.TemplateName, req)
 	return nil
 }
 
+// vnpuRequirements returns the vNPU requested by the highest-precedence
+// config that applies to request and sets one, along with how the config
+// applied to request explicitly shares the card.
+func vnpuRequirements(request string, configs []*OpaqueDeviceConfig) VnpuRequirements {
+	var sharing VnpuRequirements
+	if config := npuConfig(request, configs); config != nil {
+		sharing.Partitions = config.Sharing.Partitions()
+	}
+	// Only claims asking for time slicing share a card, not those leaving
+	// sharing to the default.
+	sharing.TimeSlicing = explicitSharing(request, configs).IsTimeSlicing()
+	if sharing.Partitions > 1 {
+		return sharing
+	}
+	for _, c := range slices.Backward(configs) {
+		if len(c.Requests) != 0 && !slices.Contains(c.Requests, request) {
+			continue
+		}
+		config, ok := c.Config.(*configapi.NpuConfig)
+		if !ok || config.Vnpu == nil {
+			continue
+		}
+		if config.Vnpu.Template != "" {
+			return VnpuRequirements{Template: config.Vnpu.Template}
+		}
+		if res := config.Vnpu.Resources; res != nil {
+			return VnpuRequirements{
+				Aicore: res.Aicore,
+				Memory: res.MemoryGiB,
+				Aicpu:  res.Aicpu,
+				Dvpp:   res.Dvpp,
+			}
+		}
+	}
+	return sharing
+}
+
 // unprepareDevices reclaims devices under the specified ClaimUID
 func (s *DeviceState) unprepareDevices(claimUID string, devices PreparedDevices) error {
 	log.Printf("Starting to release devices, claimUID: %s", claimUID)
@@ -377,6 +778,9 @@ func (s *DeviceState) unprepareDevices(claimUID string, devices PreparedDevices)
 		return nil
 	}
 	for _, dev := range devices {
+		if dev.AdminAccess {
+			continue
+		}
 		if err := s.vnpuManager.ReleaseSlice(dev.Device.DeviceName); err != nil {
 			log.Printf("Warning: failed to release vNPU slice %s: %v", dev.Device.DeviceName, err)
 		} else {
@@ -388,20 +792,27 @@ func (s *DeviceState) unprepareDevices(claimUID string, devices PreparedDevices)
 
 // applyConfig applies a configuration to a set of device allocation results.
 //
-// In this example driver there is no actual configuration applied. We simply
-// define a set of environment variables to be injected into the containers
-// that include a given device. A real driver would likely need to do some sort
-// of hardware configuration as well, based on the config passed in.
-func (s *DeviceState) applyConfig(config *configapi.GpuConfig, results []*resourceapi.DeviceRequestAllocationResult) (PerDeviceCDIContainerEdits, error) {
+// The sharing strategy of the config is enforced when the devices are
+// allocated. What is left are the environment variables injected into the
+// containers, telling the Ascend runtime which NPU or vNPU to expose and how
+// to set up HCCL, and the runtime settings of the config that the cluster
+// administrator allows.
+func (s *DeviceState) applyConfig(config *configapi.NpuConfig, results []*resourceapi.DeviceRequestAllocationResult) (PerDeviceCDIContainerEdits, error) {
 	perDeviceEdits := make(PerDeviceCDIContainerEdits)
 
+	runtimeEdits, err := s.runtimePolicy.containerEdits(config.Runtime, len(results))
+	if err != nil {
+		return nil, fmt.Errorf("invalid runtime settings: %w", err)
+	}
+
 	for _, result := range results {
 		envs := buildBaseEnv(result.Device)
 		if s.vnpuManager != nil {
 			envs = s.addVnpuEnvIfSlice(envs, result.Device)
 		}
-		envs = addSharingStrategyEnv(envs, config, result.Device)
-		edits := &cdispec.ContainerEdits{Env: envs}
+		envs = addHcclEnv(envs, config.Hccl)
+		envs = append(envs, runtimeEdits.Env...)
+		edits := &cdispec.ContainerEdits{Env: envs, Mounts: runtimeEdits.Mounts}
 		perDeviceEdits[result.Device] = &cdiapi.ContainerEdits{ContainerEdits: edits}
 	}
 	return perDeviceEdits, nil
@@ -420,6 +831,12 @@ func (s *DeviceState) addVnpuEnvIfSlice(envs []string, deviceID string) []string
 	if !r.MatchString(deviceID) {
 		return envs
 	}
+	if vdevID, ok := s.vnpuManager.GetOnChipVDevID(deviceID); ok {
+		// vNPUs that already exist on the chip are exposed by their own ID
+		// instead of being created by the runtime from ASCEND_VNPU_SPECS.
+		log.Printf("Set on-chip vNPU %d for device %s", vdevID, deviceID)
+		return replaceEnv(envs, "ASCEND_VISIBLE_DEVICES", strconv.FormatUint(uint64(vdevID), 10))
+	}
 	vnpuSpec, err := s.vnpuManager.GetVnpuSpecsEnv(deviceID)
 	if err != nil {
 		log.Printf("Warning: failed to get vNPU specs: %v", err)
@@ -432,23 +849,30 @@ func (s *DeviceState) addVnpuEnvIfSlice(envs []string, deviceID string) []string
 	return envs
 }
 
-// addSharingStrategyEnv adds environment variables for the sharing strategy
-func addSharingStrategyEnv(envs []string, config *configapi.GpuConfig, deviceName string) []string {
-	if config.Sharing == nil {
+// replaceEnv sets the value of an environment variable, adding it if missing
+func replaceEnv(envs []string, name, value string) []string {
+	for i, env := range envs {
+		if strings.HasPrefix(env, name+"=") {
+			envs[i] = fmt.Sprintf("%s=%s", name, value)
+			return envs
+		}
+	}
+	return append(envs, fmt.Sprintf("%s=%s", name, value))
+}
+
+// addHcclEnv adds the environment variables for the HCCL settings
+func addHcclEnv(envs []string, hccl *configapi.HcclConfig) []string {
+	if hccl == nil {
 		return envs
 	}
-	envs = append(envs, fmt.Sprintf("NPU_DEVICE_%s_SHARING_STRATEGY=%s", deviceName[4:], config.Sharing.Strategy))
-	switch {
-	case config.Sharing.IsTimeSlicing():
-		tsconfig, _ := config.Sharing.GetTimeSlicingConfig()
-		if tsconfig != nil {
-			envs = append(envs, fmt.Sprintf("NPU_DEVICE_%s_TIMESLICE_INTERVAL=%v", deviceName[4:], tsconfig.Interval))
-		}
-	case config.Sharing.IsSpacePartitioning():
-		spconfig, _ := config.Sharing.GetSpacePartitioningConfig()
-		if spconfig != nil {
-			envs = append(envs, fmt.Sprintf("NPU_DEVICE_%s_PARTITION_COUNT=%v", deviceName[4:], spconfig.PartitionCount))
-		}
+	if hccl.ConnectTimeoutSeconds > 0 {
+		envs = append(envs, fmt.Sprintf("HCCL_CONNECT_TIMEOUT=%d", hccl.ConnectTimeoutSeconds))
+	}
+	if hccl.ExecTimeoutSeconds > 0 {
+		envs = append(envs, fmt.Sprintf("HCCL_EXEC_TIMEOUT=%d", hccl.ExecTimeoutSeconds))
+	}
+	if hccl.SocketIfname != "" {
+		envs = append(envs, fmt.Sprintf("HCCL_SOCKET_IFNAME=%s", hccl.SocketIfname))
 	}
 	return envs
 }
@@ -484,6 +908,7 @@ func GetOpaqueDeviceConfigs(
 		}
 	}
 	candidateConfigs = append(candidateConfigs, classConfigs...)
+	candidateConfigs = append(candidateConfigs, claimConfigs...)
 
 	// Decode all configs that are relevant for the driver.
 	var resultConfigs []*OpaqueDeviceConfig
@@ -508,8 +933,9 @@ func GetOpaqueDeviceConfigs(
 		}
 
 		resultConfig := &OpaqueDeviceConfig{
-			Requests: config.Requests,
-			Config:   decodedConfig,
+			Requests:    config.Requests,
+			Config:      decodedConfig,
+			SetsSharing: setsSharing(config.DeviceConfiguration.Opaque.Parameters.Raw),
 		}
 
 		resultConfigs = append(resultConfigs, resultConfig)
@@ -518,55 +944,119 @@ func GetOpaqueDeviceConfigs(
 	return resultConfigs, nil
 }
 
+// setsSharing reports whether the raw parameters of a config set its
+// sharing.
+func setsSharing(raw []byte) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return false
+	}
+	sharing, ok := fields["sharing"]
+	return ok && string(sharing) != "null"
+}
+
 // AllocateSlice allocates a vNPU slice based on the requested computational resources
-func (m *VnpuManager) AllocateSlice(deviceName string, requestedAicore, requestedMemory int) (*VnpuSlice, error) {
+// and records claimUID as its owner.
+func (m *VnpuManager) AllocateSlice(claimUID, deviceName string, req VnpuRequirements) (*VnpuSlice, error) {
 	m.Lock()
 	defer m.Unlock()
-	log.Printf("Attempting to allocate vNPU slice, device: %s, requirements: AICORE=%d, Memory=%dGB", deviceName, requestedAicore, requestedMemory)
-	physicalNpu, ok := m.PhysicalNpus[deviceName]
+	log.Printf("Attempting to allocate vNPU slice, device: %s, requirements: %v", deviceName, req)
+	physicalNpu, ok := m.findPhysicalNpu(deviceName)
 	if !ok {
 		return nil, fmt.Errorf("physical NPU not found: %s", deviceName)
 	}
-	if requestedAicore == 0 && requestedMemory == 0 {
-		return m.allocateFullCard(physicalNpu, deviceName)
+	if physicalNpu.Unavailable {
+		return nil, fmt.Errorf("physical NPU %s is currently unavailable", physicalNpu.DeviceName)
+	}
+	if req.Partitions > 1 {
+		tpl, err := m.partitionTemplate(physicalNpu, req.Partitions)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Partitioning %s into %d vNPUs with template %s", physicalNpu.DeviceName, req.Partitions, tpl.Name)
+		req = VnpuRequirements{Template: tpl.Name, Partitions: req.Partitions}
+	}
+	if req.Template != "" {
+		supported := m.TemplatesFor(physicalNpu.ModelName)
+		if _, ok := supported[req.Template]; !ok {
+			return nil, fmt.Errorf("vNPU template %q is not supported by %s NPUs, supported templates are: %s",
+				req.Template, physicalNpu.ModelName, strings.Join(slices.Sorted(maps.Keys(supported)), ", "))
+		}
+	}
+	var slice *VnpuSlice
+	var err error
+	switch {
+	case physicalNpu.Static:
+		slice, err = m.allocateStaticSlice(physicalNpu, deviceName, req)
+	case isTimeSlice(physicalNpu, deviceName):
+		slice, err = m.allocateTimeSlice(physicalNpu, deviceName, req)
+	case req.WholeCard():
+		slice, err = m.allocateFullCard(physicalNpu, deviceName, req.TimeSlicing)
+	default:
+		slice, err = m.allocateSliceByTemplate(physicalNpu, deviceName, req)
 	}
-	return m.allocateSliceByTemplate(physicalNpu, deviceName, requestedAicore, requestedMemory)
+	if err != nil {
+		return nil, err
+	}
+	slice.ClaimUID = claimUID
+	return slice, nil
 }
 
-// allocateFullCard allocates the entire card
-func (m *VnpuManager) allocateFullCard(npu *PhysicalNpuState, deviceName string) (*VnpuSlice, error) {
+// allocateFullCard allocates the entire card. A card allocated for time
+// slicing is offered to further claims that time-slice it.
+func (m *VnpuManager) allocateFullCard(npu *PhysicalNpuState, deviceName string, timeSlicing bool) (*VnpuSlice, error) {
 	for i, slice := range npu.AvailableSlices {
 		if slice.SliceID == deviceName && !slice.Allocated {
 			slice.Allocated = true
 			npu.AllocatedSlices = append(npu.AllocatedSlices, slice)
 			npu.AvailableSlices = append(npu.AvailableSlices[:i], npu.AvailableSlices[i+1:]...)
 			log.Printf("Successfully allocated the full physical NPU slice %s", deviceName)
+			if timeSlicing && m.MaxTimeSlicedClaims > 1 {
+				slice.TimeSliced = true
+				m.offerTimeSlice(npu)
+			}
 			return slice, nil
 		}
 	}
 	return nil, fmt.Errorf("the slice %s has already been allocated", deviceName)
 }
 
-// allocateSliceByTemplate allocates a vNPU slice based on template attributes
-func (m *VnpuManager) allocateSliceByTemplate(
+// allocateStaticSlice allocates one of the fixed slices of a static card as is.
+func (m *VnpuManager) allocateStaticSlice(
 	npu *PhysicalNpuState,
 	deviceName string,
-	requestedAicore, requestedMemory int,
+	req VnpuRequirements,
 ) (*VnpuSlice, error) {
-	var bestTemplate *VnpuTemplate
-	bestDiff := math.MaxInt32
-	for _, template := range npu.SupportTemplates {
-		if template.Attributes.AICORE >= requestedAicore &&
-			template.Attributes.Memory >= requestedMemory {
-			diff := (template.Attributes.AICORE - requestedAicore) + (template.Attributes.Memory - requestedMemory)
-			if diff < bestDiff {
-				bestDiff = diff
-				bestTemplate = template
-			}
+	for i, slice := range npu.AvailableSlices {
+		if slice.SliceID != deviceName || slice.Allocated {
+			continue
 		}
+		if req.Template != "" && slice.TemplateName != req.Template {
+			return nil, fmt.Errorf("static vNPU %s has template %s, not the requested %s",
+				deviceName, slice.TemplateName, req.Template)
+		}
+		if tpl, ok := m.Templates[slice.TemplateName]; ok && !req.SatisfiedBy(tpl) {
+			return nil, fmt.Errorf("static vNPU %s (template %s) does not meet the requirements: %v",
+				deviceName, slice.TemplateName, req)
+		}
+		slice.Allocated = true
+		npu.AllocatedSlices = append(npu.AllocatedSlices, slice)
+		npu.AvailableSlices = append(npu.AvailableSlices[:i], npu.AvailableSlices[i+1:]...)
+		log.Printf("Successfully allocated static slice %s", deviceName)
+		return slice, nil
 	}
+	return nil, fmt.Errorf("the slice %s has already been allocated", deviceName)
+}
+
+// allocateSliceByTemplate allocates a vNPU slice based on template attributes
+func (m *VnpuManager) allocateSliceByTemplate(
+	npu *PhysicalNpuState,
+	deviceName string,
+	req VnpuRequirements,
+) (*VnpuSlice, error) {
+	bestTemplate := resolveTemplate(npu.SupportTemplates, req)
 	if bestTemplate == nil {
-		return nil, fmt.Errorf("no partition scheme found that meets the requirements: AICORE>=%d, Memory>=%dGB", requestedAicore, requestedMemory)
+		return nil, fmt.Errorf("no partition scheme found that meets the requirements: %v", req)
 	}
 
 	var currentSlice *VnpuSlice
@@ -589,6 +1079,11 @@ func (m *VnpuManager) allocateSliceByTemplate(
 	currentSlice.Allocated = true
 
 	npu.AllocatedSlices = append(npu.AllocatedSlices, currentSlice)
+	// A card partitioned into equal vNPUs keeps hosting their template for
+	// the claims taking the other partitions.
+	if req.Partitions > 1 {
+		npu.PartitionTemplate, npu.PartitionCount = bestTemplate.Name, req.Partitions
+	}
 
 	newSliceID := fmt.Sprintf("npu-%d-%d", npu.LogicID, npu.NextSliceIndex)
 	newSlice := &VnpuSlice{
@@ -598,6 +1093,8 @@ func (m *VnpuManager) allocateSliceByTemplate(
 		Type:         "vNPU",
 	}
 	npu.AvailableSlices = append(npu.AvailableSlices, newSlice)
+	// The new slice is published with the templates the card can host now.
+	m.updateSupportTemplates(npu)
 
 	if m.deviceUpdateCallback != nil {
 		m.deviceUpdateCallback(newSliceID, npu)
@@ -605,195 +1102,127 @@ func (m *VnpuManager) allocateSliceByTemplate(
 
 	npu.NextSliceIndex++
 
-	log.Printf("Successfully allocated vNPU slice: %s with template %s (AICORE: %d, Memory: %dGB)",
-		currentSlice.SliceID, bestTemplate.Name, bestTemplate.Attributes.AICORE, bestTemplate.Attributes.Memory)
+	log.Printf("Successfully allocated vNPU slice: %s with template %s (AICORE: %d, Memory: %dGB, AICPU: %d, DVPP: %t)",
+		currentSlice.SliceID, bestTemplate.Name, bestTemplate.Attributes.AICORE, bestTemplate.Attributes.Memory,
+		bestTemplate.Attributes.AICPU, bestTemplate.Attributes.DVPP)
 	log.Printf("Created new available slice: %s representing remaining resources", newSliceID)
 
 	return currentSlice, nil
 }
 
-// CreatePredefinedDeviceClasses idempotently creates/updates DeviceClasses
-func CreatePredefinedDeviceClasses(vnpuManager *VnpuManager) error {
-	log.Printf("Starting to create predefined DeviceClasses...")
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		return fmt.Errorf("failed to get in-cluster config: %v", err)
-	}
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return fmt.Errorf("failed to create Kubernetes client: %v", err)
-	}
-
-	uniqueModels := make(map[string]bool)
-	uniqueTemplates := make(map[string]*VnpuTemplate)
-
-	for _, pNpu := range vnpuManager.PhysicalNpus {
-		modelName := pNpu.ModelName
-		if modelName == "" {
-			modelName = "unknown"
-		}
-		uniqueModels[modelName] = true
-		for _, tpl := range pNpu.SupportTemplates {
-			key := fmt.Sprintf("aicore-%d-mem-%d", tpl.Attributes.AICORE, tpl.Attributes.Memory)
-			if _, ok := uniqueTemplates[key]; !ok {
-				uniqueTemplates[key] = tpl
-			}
-		}
-	}
+func (s *DeviceState) UpdateAllocatableDevice(deviceName string, physicalNpu *PhysicalNpuState) bool {
+	s.allocatableMu.Lock()
+	defer s.allocatableMu.Unlock()
 
-	// Create a full-card DeviceClass for each unique model
-	for modelName := range uniqueModels {
-		if err := createFullCardDeviceClass(clientset, modelName); err != nil {
-			log.Printf("Failed to create/update full-card DeviceClass: %v", err)
-		}
+	_, exists := s.allocatable[deviceName]
+	if exists {
+		return false
 	}
 
-	// Create the corresponding DeviceClass for each unique template and each unique model
-	for _, tpl := range uniqueTemplates {
-		for modelName := range uniqueModels {
-			if err := createMemoryDeviceClass(clientset, modelName, tpl); err != nil {
-				log.Printf("Failed to create/update Memory DeviceClass: %v", err)
-			}
-			if err := createAicoreDeviceClass(clientset, modelName, tpl); err != nil {
-				log.Printf("Failed to create/update AICORE DeviceClass: %v", err)
-			}
+	var sliceType string = "NPU"
+	for _, slice := range physicalNpu.AvailableSlices {
+		if slice.SliceID == deviceName {
+			sliceType = slice.Type
+			break
 		}
 	}
 
-	log.Printf("Predefined DeviceClass creation completed")
-	return nil
-}
-
-// createFullCardDeviceClass creates or updates a "full-card" DeviceClass
-func createFullCardDeviceClass(clientset *kubernetes.Clientset, modelName string) error {
-	safeModel := toSafeModelName(modelName)
-	dcName := fmt.Sprintf("npu-%s.example.com", safeModel)
-	expr := fmt.Sprintf(`device.attributes["%s"].model == "%s" && device.attributes["%s"].type == "NPU"`,
-		DriverDomainName, modelName, DriverDomainName)
-	return upsertDeviceClass(clientset, dcName, expr, "")
+	s.allocatable[deviceName] = newSliceDevice(deviceName, sliceType, physicalNpu, s.vnpuManager)
+	log.Printf("Added new allocatable NPU device: %s, Type: %s, Model: %s", deviceName, sliceType, physicalNpu.ModelName)
+	s.changed()
+	return true
 }
 
-// createMemoryDeviceClass creates or updates a DeviceClass based on memory
-func createMemoryDeviceClass(clientset *kubernetes.Clientset, modelName string, tpl *VnpuTemplate) error {
-	safeModel := toSafeModelName(modelName)
-	dcName := fmt.Sprintf("npu-%s-mem%d.example.com", safeModel, tpl.Attributes.Memory)
-	expr := fmt.Sprintf(`device.attributes["%s"].memory >= %d && device.attributes["%s"].model == "%s"`,
-		DriverDomainName, tpl.Attributes.Memory, DriverDomainName, modelName)
-	return upsertDeviceClass(clientset, dcName, expr, tpl.Name)
+// isAllocatable reports whether deviceName is an allocatable device.
+func (s *DeviceState) isAllocatable(deviceName string) bool {
+	s.allocatableMu.Lock()
+	defer s.allocatableMu.Unlock()
+	_, ok := s.allocatable[deviceName]
+	return ok
 }
 
-// createAicoreDeviceClass creates or updates a DeviceClass based on AICORE
-func createAicoreDeviceClass(clientset *kubernetes.Clientset, modelName string, tpl *VnpuTemplate) error {
-	safeModel := toSafeModelName(modelName)
-	dcName := fmt.Sprintf("npu-%s-aicore%d.example.com", safeModel, tpl.Attributes.AICORE)
-	expr := fmt.Sprintf(`device.attributes["%s"].aicore >= %d && device.attributes["%s"].model == "%s"`,
-		DriverDomainName, tpl.Attributes.AICORE, DriverDomainName, modelName)
-	return upsertDeviceClass(clientset, dcName, expr, tpl.Name)
+// allocatableDevices returns the allocatable devices to publish.
+func (s *DeviceState) allocatableDevices() []resourceapi.Device {
+	s.allocatableMu.Lock()
+	defer s.allocatableMu.Unlock()
+	return slices.Collect(maps.Values(s.allocatable))
 }
 
-// upsertDeviceClass idempotently creates/updates a DeviceClass
-func upsertDeviceClass(clientset *kubernetes.Clientset, name, expr, tpl string) error {
-	want, err := buildDeviceClass(name, expr, tpl)
-	if err != nil {
-		return err
-	}
-
-	got, getErr := clientset.ResourceV1beta1().DeviceClasses().Get(
-		context.TODO(), name, metav1.GetOptions{},
-	)
-	if errors.IsNotFound(getErr) {
-		_, createErr := clientset.ResourceV1beta1().DeviceClasses().Create(
-			context.TODO(), want, metav1.CreateOptions{},
-		)
-		if createErr != nil {
-			return fmt.Errorf("failed to create DeviceClass: %v", createErr)
-		}
-		log.Printf("Successfully created DeviceClass: %s", name)
-		return nil
-	}
-	if getErr != nil {
-		return fmt.Errorf("failed to get DeviceClass: %v", getErr)
-	}
-
-	if !deviceClassEquals(got, want) {
-		want.ObjectMeta.ResourceVersion = got.ObjectMeta.ResourceVersion
-		_, updateErr := clientset.ResourceV1beta1().DeviceClasses().Update(
-			context.TODO(), want, metav1.UpdateOptions{},
-		)
-		if updateErr != nil {
-			return fmt.Errorf("failed to update DeviceClass: %v", updateErr)
+// saveAllocatable returns a function restoring the allocatable devices of the
+// physical NPU deviceName belongs to, so that an allocation on it can be
+// undone without touching the devices of other physical NPUs.
+func (s *DeviceState) saveAllocatable(deviceName string) func() {
+	s.allocatableMu.Lock()
+	defer s.allocatableMu.Unlock()
+
+	key := physicalNpuKey(deviceName)
+	saved := make(AllocatableDevices)
+	for name, device := range s.allocatable {
+		if physicalNpuKey(name) == key {
+			saved[name] = device
 		}
-		log.Printf("Successfully updated DeviceClass: %s", name)
 	}
-
-	return nil
-}
-
-// buildDeviceClass generates the target DeviceClass
-func buildDeviceClass(name, celExpression, tplName string) (*resourceapi.DeviceClass, error) {
-	paramObj := map[string]interface{}{
-		"apiVersion": "gpu.resource.example.com/v1alpha1",
-		"kind":       "GpuConfig",
-		"vnpuSpec": map[string]interface{}{
-			"templateName": tplName,
-		},
-	}
-	raw, err := json.Marshal(paramObj)
-	if err != nil {
-		return nil, err
+	return func() {
+		s.allocatableMu.Lock()
+		defer s.allocatableMu.Unlock()
+		maps.DeleteFunc(s.allocatable, func(name string, _ resourceapi.Device) bool {
+			return physicalNpuKey(name) == key
+		})
+		maps.Copy(s.allocatable, saved)
+		s.changed()
 	}
-	return &resourceapi.DeviceClass{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: name,
-		},
-		Spec: resourceapi.DeviceClassSpec{
-			Selectors: []resourceapi.DeviceSelector{{
-				CEL: &resourceapi.CELDeviceSelector{Expression: celExpression},
-			}},
-			Config: []resourceapi.DeviceClassConfiguration{
-				{
-					DeviceConfiguration: resourceapi.DeviceConfiguration{
-						Opaque: &resourceapi.OpaqueDeviceConfiguration{
-							Driver: DriverName,
-							Parameters: runtime.RawExtension{
-								Raw: raw,
-							},
-						},
-					},
-				},
-			},
-		},
-	}, nil
 }
 
-// deviceClassEquals performs a simple comparison of the Spec fields
-func deviceClassEquals(a, b *resourceapi.DeviceClass) bool {
-	aSpec, _ := json.Marshal(a.Spec)
-	bSpec, _ := json.Marshal(b.Spec)
-	return string(aSpec) == string(bSpec)
+// changed notifies about a change of the allocatable devices.
+func (s *DeviceState) changed() {
+	if s.onChange != nil {
+		s.onChange()
+	}
 }
 
-// toSafeModelName removes extra characters from model and converts to lowercase
-func toSafeModelName(model string) string {
-	model = strings.ReplaceAll(model, " ", "-")
-	model = strings.ReplaceAll(model, "/", "-")
-	return strings.ToLower(model)
+// publishableDevices drops the stale allocatable devices and returns the
+// others, which are to be published.
+func (s *DeviceState) publishableDevices() []resourceapi.Device {
+	s.syncAllocatable()
+	return s.allocatableDevices()
 }
 
-func (s *DeviceState) UpdateAllocatableDevice(deviceName string, physicalNpu *PhysicalNpuState) bool {
-	_, exists := s.allocatable[deviceName]
-	if exists {
-		return false
-	}
+// syncAllocatable drops the allocatable devices that are no longer slices of
+// a physical NPU. The vNPU manager stays locked meanwhile, so that slices it
+// creates concurrently are not dropped.
+func (s *DeviceState) syncAllocatable() {
+	s.RLock()
+	defer s.RUnlock()
 
-	var sliceType string = "NPU"
-	for _, slice := range physicalNpu.AvailableSlices {
-		if slice.SliceID == deviceName {
-			sliceType = slice.Type
-			break
+	deviceNames := make(map[string]struct{})
+	if s.vnpuManager != nil {
+		s.vnpuManager.Lock()
+		defer s.vnpuManager.Unlock()
+		for _, physicalNpu := range s.vnpuManager.PhysicalNpus {
+			if !physicalNpu.Unavailable {
+				for _, slice := range physicalNpu.AvailableSlices {
+					deviceNames[slice.SliceID] = struct{}{}
+				}
+			}
+			for _, slice := range physicalNpu.AllocatedSlices {
+				deviceNames[slice.SliceID] = struct{}{}
+			}
 		}
 	}
 
+	s.allocatableMu.Lock()
+	defer s.allocatableMu.Unlock()
+	maps.DeleteFunc(s.allocatable, func(name string, _ resourceapi.Device) bool {
+		_, ok := deviceNames[name]
+		return !ok
+	})
+}
+
+// newSliceDevice builds the published device for a slice of a physical NPU.
+// With vNPU support, the device advertises the largest AI Core and memory
+// values the physical NPU can still provide, and the templates of its model
+// along with which of them it can still host.
+func newSliceDevice(deviceName, sliceType string, physicalNpu *PhysicalNpuState, vnpuManager *VnpuManager) resourceapi.Device {
 	uuidStr := fmt.Sprintf("%s-%d", os.Getenv("NODE_NAME"), physicalNpu.LogicID)
 
 	devAttributes := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
@@ -803,7 +1232,7 @@ func (s *DeviceState) UpdateAllocatableDevice(deviceName string, physicalNpu *Ph
 		DriverDomain + "type":  {StringValue: ptr.To(sliceType)},
 	}
 
-	if s.vnpuManager != nil {
+	if vnpuManager != nil {
 		maxAicore, maxMemory := 0, 0
 		for _, tpl := range physicalNpu.SupportTemplates {
 			if tpl.Attributes.AICORE > maxAicore {
@@ -813,19 +1242,20 @@ func (s *DeviceState) UpdateAllocatableDevice(deviceName string, physicalNpu *Ph
 				maxMemory = tpl.Attributes.Memory
 			}
 		}
+		// A time slice is a share of the time of the whole card.
+		if sliceType == timeSliceType {
+			maxAicore, maxMemory = physicalNpu.TotalAicore, physicalNpu.TotalMemory
+		}
 
 		devAttributes[DriverDomain+"aicore"] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(maxAicore))}
 		devAttributes[DriverDomain+"memory"] = resourceapi.DeviceAttribute{IntValue: ptr.To(int64(maxMemory))}
+		addTemplateAttributes(devAttributes, vnpuManager.TemplatesFor(physicalNpu.ModelName), physicalNpu.SupportTemplates)
 	}
 
-	device := resourceapi.Device{
+	return resourceapi.Device{
 		Name: deviceName,
 		Basic: &resourceapi.BasicDevice{
 			Attributes: devAttributes,
 		},
 	}
-
-	s.allocatable[deviceName] = device
-	log.Printf("Added new allocatable NPU device: %s, Type: %s, Model: %s", deviceName, sliceType, physicalNpu.ModelName)
-	return true
 }
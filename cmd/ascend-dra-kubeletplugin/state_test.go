@@ -0,0 +1,589 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resourceapi "k8s.io/api/resource/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/kubelet/checkpointmanager"
+	"k8s.io/utils/ptr"
+
+	configapi "Ascend-dra-driver/api/example.com/resource/npu"
+	"Ascend-dra-driver/pkg/common"
+)
+
+// newTestClaim returns a claim allocated the given devices for request
+// "npu", with opaque configs given as raw JSON.
+func newTestClaim(uid string, devices []string, configs ...string) *resourceapi.ResourceClaim {
+	claim := &resourceapi.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: uid, Namespace: "default", UID: types.UID(uid)},
+		Status: resourceapi.ResourceClaimStatus{
+			Allocation: &resourceapi.AllocationResult{},
+		},
+	}
+	for _, device := range devices {
+		claim.Status.Allocation.Devices.Results = append(claim.Status.Allocation.Devices.Results,
+			resourceapi.DeviceRequestAllocationResult{
+				Request: "npu",
+				Driver:  DriverName,
+				Pool:    testNodeName,
+				Device:  device,
+			})
+	}
+	for _, config := range configs {
+		claim.Status.Allocation.Devices.Config = append(claim.Status.Allocation.Devices.Config,
+			resourceapi.DeviceAllocationConfiguration{
+				Source: resourceapi.AllocationConfigSourceClaim,
+				DeviceConfiguration: resourceapi.DeviceConfiguration{
+					Opaque: &resourceapi.OpaqueDeviceConfiguration{
+						Driver:     DriverName,
+						Parameters: runtime.RawExtension{Raw: []byte(config)},
+					},
+				},
+			})
+	}
+	return claim
+}
+
+func vnpuConfig(vnpu string) string {
+	return `{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"NpuConfig","vnpu":` + vnpu + `}`
+}
+
+func TestPrepareVnpuConfigs(t *testing.T) {
+	tests := map[string]struct {
+		configs          []string
+		expectedTemplate string
+		expectedErr      string
+	}{
+		"whole card": {},
+		"template": {
+			configs:          []string{vnpuConfig(`{"template":"vir02"}`)},
+			expectedTemplate: "vir02",
+		},
+		"resources": {
+			configs:          []string{vnpuConfig(`{"resources":{"aicore":5}}`)},
+			expectedTemplate: "vir02",
+		},
+		"template not supported by the chip": {
+			configs:     []string{vnpuConfig(`{"template":"vir08"}`)},
+			expectedErr: `vNPU template "vir08" is not supported by 310P3 NPUs, supported templates are: vir01, vir02, vir04`,
+		},
+		"unknown template": {
+			configs:     []string{vnpuConfig(`{"template":"vir99"}`)},
+			expectedErr: `vNPU template "vir99" is not supported`,
+		},
+		"template and resources": {
+			configs:     []string{vnpuConfig(`{"template":"vir02","resources":{"aicore":2}}`)},
+			expectedErr: "mutually exclusive",
+		},
+		"resources no template provides": {
+			configs:     []string{vnpuConfig(`{"resources":{"aicore":64}}`)},
+			expectedErr: "no partition scheme found",
+		},
+		"later config takes precedence": {
+			configs: []string{
+				vnpuConfig(`{"template":"vir01"}`),
+				vnpuConfig(`{"template":"vir04"}`),
+			},
+			expectedTemplate: "vir04",
+		},
+		"config without vNPU keeps the earlier vNPU": {
+			configs: []string{
+				vnpuConfig(`{"template":"vir01"}`),
+				`{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"NpuConfig","sharing":{"strategy":"SpacePartitioning"}}`,
+			},
+			expectedTemplate: "vir01",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			state, err := newDeviceState(newTestConfig(t), t.TempDir(), backendOf(&fakeBackend{devices: twoCards()}, nil))
+			require.NoError(t, err)
+
+			devices, err := state.Prepare(newTestClaim("claim-1", []string{"npu-0-0"}, test.configs...))
+			if test.expectedErr != "" {
+				assert.ErrorContains(t, err, test.expectedErr)
+				assert.Contains(t, state.allocatable, "npu-0-0", "the whole card must not be handed out")
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, devices, 1)
+			template, _ := state.vnpuManager.GetVnpuSpecsEnv(devices[0].DeviceName)
+			assert.Equal(t, test.expectedTemplate, template)
+		})
+	}
+}
+
+// failingCheckpointManager fails the failWrite-th checkpoint write, counting
+// from 1, or panics instead if crash is set.
+type failingCheckpointManager struct {
+	checkpointmanager.CheckpointManager
+	failWrite int
+	crash     bool
+	writes    int
+}
+
+func (f *failingCheckpointManager) CreateCheckpoint(name string, checkpoint checkpointmanager.Checkpoint) error {
+	f.writes++
+	if f.writes != f.failWrite {
+		return f.CheckpointManager.CreateCheckpoint(name, checkpoint)
+	}
+	if f.crash {
+		panic("crash")
+	}
+	return errors.New("disk full")
+}
+
+// failCheckpointWrite makes the n-th checkpoint write of state fail.
+func failCheckpointWrite(n int) func(t *testing.T, state *DeviceState, cdiRoot string) func() {
+	return func(t *testing.T, state *DeviceState, cdiRoot string) func() {
+		checkpointManager := state.checkpointManager
+		state.checkpointManager = &failingCheckpointManager{CheckpointManager: checkpointManager, failWrite: n}
+		return func() { state.checkpointManager = checkpointManager }
+	}
+}
+
+// npuStates returns the state of the physical NPUs, which cannot be compared
+// with reflect.DeepEqual since the slices are shared.
+func npuStates(t *testing.T, state *DeviceState) string {
+	data, err := json.Marshal(state.vnpuManager.PhysicalNpus)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestPrepareRollback(t *testing.T) {
+	vir02 := vnpuConfig(`{"template":"vir02"}`)
+	tests := map[string]struct {
+		devices     []string
+		fault       func(t *testing.T, state *DeviceState, cdiRoot string) (restore func())
+		expectedErr string
+	}{
+		"later device not allocatable": {
+			devices:     []string{"npu-0-0", "npu-9-0"},
+			expectedErr: "requested NPU is not allocatable: npu-9-0",
+		},
+		"later slice allocation": {
+			devices:     []string{"npu-0-0", "npu-1-0", "npu-0-0"},
+			expectedErr: "cannot allocate npu-0-0",
+		},
+		"CDI spec file": {
+			devices: []string{"npu-0-0", "npu-1-0"},
+			fault: func(t *testing.T, state *DeviceState, cdiRoot string) func() {
+				// The spec directory being a file makes writing specs fail.
+				require.NoError(t, os.RemoveAll(cdiRoot))
+				require.NoError(t, os.WriteFile(cdiRoot, nil, 0o600))
+				return func() {
+					require.NoError(t, os.Remove(cdiRoot))
+					require.NoError(t, os.Mkdir(cdiRoot, 0o700))
+				}
+			},
+			expectedErr: "unable to create CDI spec file for claim",
+		},
+		"checkpoint record of the claim being prepared": {
+			devices:     []string{"npu-0-0", "npu-1-0"},
+			fault:       failCheckpointWrite(1),
+			expectedErr: "unable to record claim as being prepared in checkpoint: disk full",
+		},
+		"checkpoint commit": {
+			devices:     []string{"npu-0-0", "npu-1-0"},
+			fault:       failCheckpointWrite(2),
+			expectedErr: "unable to sync to checkpoint: disk full",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := newTestConfig(t)
+			state, err := newDeviceState(config, t.TempDir(), backendOf(&fakeBackend{devices: twoCards()}, nil))
+			require.NoError(t, err)
+			npus := npuStates(t, state)
+			allocatable := maps.Clone(state.allocatable)
+			restore := func() {}
+			if test.fault != nil {
+				restore = test.fault(t, state, config.flags.cdiRoot)
+			}
+
+			_, err = state.Prepare(newTestClaim("claim-1", test.devices, vir02))
+			require.ErrorContains(t, err, test.expectedErr)
+			restore()
+
+			assert.Equal(t, npus, npuStates(t, state), "vNPU slices")
+			assert.Equal(t, allocatable, state.allocatable, "allocatable devices")
+			specs, err := filepath.Glob(filepath.Join(config.flags.cdiRoot, "*claim-1*"))
+			require.NoError(t, err)
+			assert.Empty(t, specs, "CDI spec files")
+			checkpoint := newCheckpoint()
+			require.NoError(t, state.checkpointManager.GetCheckpoint(DriverPluginCheckpointFile, checkpoint))
+			assert.Empty(t, checkpoint.V1.PreparedClaims)
+			assert.Empty(t, checkpoint.V1.PreparingClaims)
+
+			// Once the fault is gone, the claim can be prepared as if the
+			// failed attempt never happened.
+			devices, err := state.Prepare(newTestClaim("claim-1", []string{"npu-0-0", "npu-1-0"}, vir02))
+			require.NoError(t, err)
+			var names []string
+			for _, device := range devices {
+				names = append(names, device.DeviceName)
+			}
+			assert.ElementsMatch(t, []string{"npu-0-0", "npu-1-0"}, names)
+			assert.Contains(t, state.allocatable, "npu-0-1")
+			assert.Contains(t, state.allocatable, "npu-1-1")
+		})
+	}
+}
+
+func TestPrepareCrashRecovery(t *testing.T) {
+	config := newTestConfig(t)
+	checkpointDir := t.TempDir()
+	newBackend := backendOf(&fakeBackend{devices: twoCards()}, nil)
+	state, err := newDeviceState(config, checkpointDir, newBackend)
+	require.NoError(t, err)
+
+	// The plugin crashes after the CDI spec file was written, before the
+	// claim is committed as prepared.
+	state.checkpointManager = &failingCheckpointManager{CheckpointManager: state.checkpointManager, failWrite: 2, crash: true}
+	assert.Panics(t, func() {
+		_, _ = state.Prepare(newTestClaim("claim-1", []string{"npu-0-0"}, vnpuConfig(`{"template":"vir02"}`)))
+	})
+	specs, err := filepath.Glob(filepath.Join(config.flags.cdiRoot, "*claim-1*"))
+	require.NoError(t, err)
+	require.Len(t, specs, 1)
+	checkpoint := newCheckpoint()
+	require.NoError(t, state.checkpointManager.GetCheckpoint(DriverPluginCheckpointFile, checkpoint))
+	assert.Equal(t, map[string]*PreparingClaim{
+		"claim-1": {Devices: []PreparingDevice{{DeviceName: "npu-0-0", TemplateName: "vir02"}}},
+	}, checkpoint.V1.PreparingClaims)
+
+	// On restart the claim is rolled back, and can be prepared again.
+	state, err = newDeviceState(config, checkpointDir, newBackend)
+	require.NoError(t, err)
+	specs, err = filepath.Glob(filepath.Join(config.flags.cdiRoot, "*claim-1*"))
+	require.NoError(t, err)
+	assert.Empty(t, specs, "CDI spec files")
+	checkpoint = newCheckpoint()
+	require.NoError(t, state.checkpointManager.GetCheckpoint(DriverPluginCheckpointFile, checkpoint))
+	assert.Empty(t, checkpoint.V1.PreparingClaims)
+	assert.Empty(t, checkpoint.V1.PreparedClaims)
+	assert.Contains(t, state.allocatable, "npu-0-0")
+
+	devices, err := state.Prepare(newTestClaim("claim-1", []string{"npu-0-0"}, vnpuConfig(`{"template":"vir02"}`)))
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+}
+
+func TestPrepareJournalRollback(t *testing.T) {
+	var undone []string
+	journal := &prepareJournal{}
+	for _, action := range []string{"first", "second", "third"} {
+		journal.record(action, func() error {
+			undone = append(undone, action)
+			if action == "second" {
+				return errors.New("busy")
+			}
+			return nil
+		})
+	}
+
+	err := journal.rollback()
+	assert.EqualError(t, err, "undo second: busy")
+	assert.Equal(t, []string{"third", "second", "first"}, undone, "every action is undone, in reverse order")
+	assert.NoError(t, journal.rollback(), "actions are undone only once")
+}
+
+// cards returns n 310P3 cards.
+func cards(n int) []common.NpuDevice {
+	var devices []common.NpuDevice
+	for i := range n {
+		devices = append(devices, common.NpuDevice{DevType: "310P3", DeviceName: fmt.Sprintf("310P3-%d", i), LogicID: int32(i)})
+	}
+	return devices
+}
+
+func TestPrepareConcurrently(t *testing.T) {
+	const numCards, rounds = 4, 20
+	config := newTestConfig(t)
+	config.flags.reclaimEmptyCards = true
+	checkpointDir := t.TempDir()
+	newBackend := backendOf(&fakeBackend{devices: cards(numCards)}, nil)
+	state, err := newDeviceState(config, checkpointDir, newBackend)
+	require.NoError(t, err)
+	npus := npuStates(t, state)
+	allocatable := slices.Sorted(maps.Keys(state.allocatable))
+
+	// Every card is carved into two vNPUs and reclaimed over and over, all
+	// cards at the same time.
+	var wg sync.WaitGroup
+	for card := range numCards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for round := range rounds {
+				first := fmt.Sprintf("claim-%d-%d-a", card, round)
+				second := fmt.Sprintf("claim-%d-%d-b", card, round)
+				_, err := state.Prepare(newTestClaim(first, []string{fmt.Sprintf("npu-%d-0", card)}, vnpuConfig(`{"template":"vir02"}`)))
+				assert.NoError(t, err)
+				_, err = state.Prepare(newTestClaim(second, []string{fmt.Sprintf("npu-%d-1", card)}, vnpuConfig(`{"template":"vir01"}`)))
+				assert.NoError(t, err)
+				assert.NoError(t, state.Unprepare(first))
+				assert.NoError(t, state.Unprepare(second))
+				state.syncAllocatable()
+				_ = state.allocatableDevices()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, npus, npuStates(t, state), "vNPU slices")
+	assert.Equal(t, allocatable, slices.Sorted(maps.Keys(state.allocatable)), "allocatable devices")
+	assert.Empty(t, state.checkpoint.V1.PreparedClaims)
+	assert.Empty(t, state.checkpoint.V1.PreparingClaims)
+	checkpoint := newCheckpoint()
+	require.NoError(t, state.checkpointManager.GetCheckpoint(DriverPluginCheckpointFile, checkpoint))
+	assert.Empty(t, checkpoint.V1.PreparedClaims)
+	assert.Empty(t, checkpoint.V1.PreparingClaims)
+}
+
+func TestPrepareSameNpuConcurrently(t *testing.T) {
+	const claims = 8
+	config := newTestConfig(t)
+	checkpointDir := t.TempDir()
+	newBackend := backendOf(&fakeBackend{devices: twoCards()}, nil)
+	state, err := newDeviceState(config, checkpointDir, newBackend)
+	require.NoError(t, err)
+
+	// All claims are allocated the same whole card, only one of them gets it.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var prepared []string
+	for i := range claims {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			uid := fmt.Sprintf("claim-%d", i)
+			if _, err := state.Prepare(newTestClaim(uid, []string{"npu-0-0"})); err != nil {
+				assert.ErrorContains(t, err, "cannot allocate npu-0-0")
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			prepared = append(prepared, uid)
+		}()
+	}
+	wg.Wait()
+	require.Len(t, prepared, 1)
+
+	// The checkpoint written through matches the one kept in memory.
+	checkpoint := newCheckpoint()
+	require.NoError(t, state.checkpointManager.GetCheckpoint(DriverPluginCheckpointFile, checkpoint))
+	assert.Equal(t, []string{prepared[0]}, slices.Collect(maps.Keys(checkpoint.V1.PreparedClaims)))
+	assert.Empty(t, checkpoint.V1.PreparingClaims)
+	assert.Equal(t, prepared, slices.Collect(maps.Keys(state.checkpoint.V1.PreparedClaims)))
+	specs, err := filepath.Glob(filepath.Join(config.flags.cdiRoot, "*claim-*"))
+	require.NoError(t, err)
+	assert.Len(t, specs, 1, "CDI spec files")
+
+	// Unpreparing concurrently releases the card once.
+	for range claims {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, state.Unprepare(prepared[0]))
+		}()
+	}
+	wg.Wait()
+	_, err = state.Prepare(newTestClaim("claim-next", []string{"npu-0-0"}))
+	require.NoError(t, err)
+}
+
+func TestPrepareAdminAccess(t *testing.T) {
+	config := newTestConfig(t)
+	checkpointDir := t.TempDir()
+	// Device nodes are numbered by the physical ID of the card.
+	newBackend := backendOf(&fakeBackend{devices: twoCards(), phyIDs: map[int32]int32{0: 4}}, nil)
+	state, err := newDeviceState(config, checkpointDir, newBackend)
+	require.NoError(t, err)
+	_, err = state.Prepare(newTestClaim("workload", []string{"npu-0-0"}, vnpuConfig(`{"template":"vir02"}`)))
+	require.NoError(t, err)
+	npus := npuStates(t, state)
+
+	// Monitoring the slice of the workload leaves it and the card alone.
+	admin := newTestClaim("monitor", []string{"npu-0-0"}, vnpuConfig(`{"template":"vir04"}`))
+	admin.Status.Allocation.Devices.Results[0].AdminAccess = ptr.To(true)
+	devices, err := state.Prepare(admin)
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, npus, npuStates(t, state))
+
+	prepared := state.checkpoint.V1.PreparedClaims["monitor"]
+	require.Len(t, prepared, 1)
+	assert.True(t, prepared[0].AdminAccess)
+	assert.Empty(t, prepared[0].TemplateName)
+	edits := prepared[0].ContainerEdits
+	assert.Equal(t, []string{"NPU_DEVICE_0_0_ADMIN_ACCESS=true"}, edits.Env)
+	var nodes []string
+	for _, node := range edits.DeviceNodes {
+		nodes = append(nodes, node.Path)
+	}
+	assert.Contains(t, nodes, "/dev/davinci4")
+	assert.NotContains(t, nodes, "/dev/davinci0")
+	assert.Contains(t, nodes, "/dev/davinci_manager")
+
+	// Unpreparing it does not release the slice of the workload.
+	require.NoError(t, state.Unprepare("monitor"))
+	assert.Equal(t, npus, npuStates(t, state))
+	_, err = state.Prepare(newTestClaim("other", []string{"npu-0-0"}, vnpuConfig(`{"template":"vir02"}`)))
+	assert.ErrorContains(t, err, "cannot allocate npu-0-0")
+}
+
+func partitionConfig(count int) string {
+	return fmt.Sprintf(`{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"NpuConfig","sharing":{"strategy":"SpacePartitioning","spacePartitioningConfig":{"partitionCount":%d}}}`, count)
+}
+
+func TestPrepareSpacePartitioning(t *testing.T) {
+	tests := map[string]struct {
+		count    int
+		template string
+		err      string
+	}{
+		"single partition": {count: 1},
+		"halves":           {count: 2, template: "vir01"},
+		"too many":         {count: 4, err: "no vNPU template of 310P3 NPUs fits 4 times into npu-0-0"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			newBackend := backendOf(&fakeBackend{devices: twoCards()}, nil)
+			state, err := newDeviceState(newTestConfig(t), t.TempDir(), newBackend)
+			require.NoError(t, err)
+
+			_, err = state.Prepare(newTestClaim("claim", []string{"npu-0-0"}, partitionConfig(test.count)))
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			prepared := state.checkpoint.V1.PreparedClaims["claim"]
+			require.Len(t, prepared, 1)
+			assert.Equal(t, test.template, prepared[0].TemplateName)
+			for _, env := range prepared[0].ContainerEdits.Env {
+				assert.NotContains(t, env, "NPU_DEVICE_")
+			}
+		})
+	}
+}
+
+func TestPrepareAllPartitions(t *testing.T) {
+	newBackend := backendOf(&fakeBackend{devices: twoCards()}, nil)
+	state, err := newDeviceState(newTestConfig(t), t.TempDir(), newBackend)
+	require.NoError(t, err)
+	m := state.vnpuManager
+	m.Templates = templates310P()
+	npu := m.PhysicalNpus["npu-0-0"]
+	npu.SupportTemplates = m.TemplatesFor(npu.ModelName)
+
+	// Each claim takes one of the two partitions of the card, the second on
+	// the free remainder the first left.
+	var devices []string
+	for i, device := range []string{"npu-0-0", "npu-0-1"} {
+		claimUID := fmt.Sprintf("claim-%d", i)
+		_, err := state.Prepare(newTestClaim(claimUID, []string{device}, partitionConfig(2)))
+		require.NoError(t, err, claimUID)
+		prepared := state.checkpoint.V1.PreparedClaims[claimUID]
+		require.Len(t, prepared, 1)
+		assert.Equal(t, "vir02", prepared[0].TemplateName, claimUID)
+		devices = append(devices, prepared[0].DeviceName)
+	}
+	assert.Equal(t, []string{"npu-0-0", "npu-0-1"}, devices)
+	assert.Equal(t, "vir02", npu.PartitionTemplate)
+	assert.NotContains(t, npu.SupportTemplates, "vir02", "no partition is left")
+
+	_, err = state.Prepare(newTestClaim("claim-2", []string{"npu-0-2"}, partitionConfig(2)))
+	assert.ErrorContains(t, err, "no partition scheme found")
+
+	// Releasing a partition makes room for another one.
+	require.NoError(t, state.Unprepare("claim-0"))
+	assert.Contains(t, npu.SupportTemplates, "vir02")
+}
+
+func TestPreparePartitionFails(t *testing.T) {
+	newBackend := backendOf(&fakeBackend{devices: twoCards()}, nil)
+	state, err := newDeviceState(newTestConfig(t), t.TempDir(), newBackend)
+	require.NoError(t, err)
+	m := state.vnpuManager
+	m.Templates = templates310P()
+	npu := m.PhysicalNpus["npu-0-0"]
+	npu.SupportTemplates = m.TemplatesFor(npu.ModelName)
+
+	// A partition that cannot be carved next to another vNPU leaves no
+	// partitioning behind.
+	_, err = state.Prepare(newTestClaim("small", []string{"npu-0-0"}, vnpuConfig(`{"template":"vir01"}`)))
+	require.NoError(t, err)
+	_, err = state.Prepare(newTestClaim("partition", []string{"npu-0-1"}, partitionConfig(2)))
+	assert.ErrorContains(t, err, "no partition scheme found")
+	assert.Empty(t, npu.PartitionTemplate)
+	assert.Zero(t, npu.PartitionCount)
+	assert.NotContains(t, npu.SupportTemplates, "vir02")
+
+	// Neither does a partition rolled back with the rest of its claim.
+	npus := npuStates(t, state)
+	_, err = state.Prepare(newTestClaim("rolled-back", []string{"npu-1-0", "npu-9-0"}, partitionConfig(2)))
+	assert.ErrorContains(t, err, "requested NPU is not allocatable: npu-9-0")
+	assert.Equal(t, npus, npuStates(t, state))
+	assert.Empty(t, m.PhysicalNpus["npu-1-0"].PartitionTemplate)
+}
+
+func TestPrepareTimeSlicing(t *testing.T) {
+	config := newTestConfig(t)
+	config.flags.maxTimeSlicedClaims = 2
+	newBackend := backendOf(&fakeBackend{devices: twoCards()}, nil)
+	state, err := newDeviceState(config, t.TempDir(), newBackend)
+	require.NoError(t, err)
+	deviceType := func(name string) string {
+		state.allocatableMu.Lock()
+		defer state.allocatableMu.Unlock()
+		device, ok := state.allocatable[name]
+		if !ok {
+			return ""
+		}
+		return *device.Basic.Attributes[DriverDomain+"type"].StringValue
+	}
+
+	// Claims time-slicing a card share it up to the maximum.
+	timeSlicing := sharingConfig(configapi.TimeSlicingStrategy)
+	_, err = state.Prepare(newTestClaim("first", []string{"npu-0-0"}, timeSlicing))
+	require.NoError(t, err)
+	assert.Equal(t, timeSliceType, deviceType("npu-0-1"))
+	_, err = state.Prepare(newTestClaim("unconfigured", []string{"npu-0-1"}))
+	assert.ErrorContains(t, err, "npu-0-1 is a time slice of npu-0-0, only claims time-slicing the whole card can use it")
+	second, err := state.Prepare(newTestClaim("second", []string{"npu-0-1"}, timeSlicing))
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	state.syncAllocatable()
+	assert.Equal(t, "", deviceType("npu-0-2"), "no time slice is offered beyond the maximum")
+
+	// Releasing a claim offers a time slice again, releasing the last one
+	// restores the card.
+	require.NoError(t, state.Unprepare("first"))
+	assert.Equal(t, timeSliceType, deviceType("npu-0-2"))
+	require.NoError(t, state.Unprepare("second"))
+	state.syncAllocatable()
+	assert.Equal(t, "NPU", deviceType("npu-0-0"))
+	assert.Equal(t, "", deviceType("npu-0-2"))
+
+	// Claims that leave sharing to the default keep the card to themselves.
+	_, err = state.Prepare(newTestClaim("exclusive", []string{"npu-1-0"}))
+	require.NoError(t, err)
+	assert.Equal(t, "", deviceType("npu-1-1"))
+}
@@ -4,10 +4,15 @@ import (
 	"bufio"
 	"fmt"
 	"log"
+	"maps"
+	"math"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+
+	"Ascend-dra-driver/pkg/common"
 )
 
 // NewVnpuManager creates and initializes a new VnpuManager.
@@ -42,9 +47,9 @@ func GetNpuTemplateInfo() (map[string]*VnpuTemplate, error) {
 // createDefaultTemplates generates a set of default templates.
 func createDefaultTemplates() map[string]*VnpuTemplate {
 	templates := map[string]*VnpuTemplate{
-		"vir01": {Name: "vir01", Attributes: VnpuTemplateAttribute{AICORE: 4, Memory: 8}},
-		"vir02": {Name: "vir02", Attributes: VnpuTemplateAttribute{AICORE: 8, Memory: 12}},
-		"vir04": {Name: "vir04", Attributes: VnpuTemplateAttribute{AICORE: 16, Memory: 16}},
+		"vir01": {Name: "vir01", Attributes: VnpuTemplateAttribute{AICORE: 4, Memory: 8, AICPU: 1, DVPP: true}},
+		"vir02": {Name: "vir02", Attributes: VnpuTemplateAttribute{AICORE: 8, Memory: 12, AICPU: 2, DVPP: true}},
+		"vir04": {Name: "vir04", Attributes: VnpuTemplateAttribute{AICORE: 16, Memory: 16, AICPU: 4, DVPP: true}},
 	}
 	log.Printf("Using default templates. Total: %d", len(templates))
 	return templates
@@ -116,6 +121,10 @@ func parseTemplateInfo(output string, templates map[string]*VnpuTemplate) error
 					currentAttrs.AICORE = val
 				case "Memory":
 					currentAttrs.Memory = val
+				case "AICPU":
+					currentAttrs.AICPU = val
+				case "VPC", "VENC", "VDEC", "JPEGD", "JPEGE", "PNGD":
+					currentAttrs.DVPP = currentAttrs.DVPP || val > 0
 				}
 			}
 			templates[currentTemplate] = &VnpuTemplate{
@@ -146,7 +155,7 @@ func (m *VnpuManager) InitPhysicalNpu(deviceName string, logicID int32, modelNam
 		ModelName:        modelName,
 		AvailableSlices:  []*VnpuSlice{},
 		AllocatedSlices:  []*VnpuSlice{},
-		SupportTemplates: cloneTemplates(m.Templates),
+		SupportTemplates: m.TemplatesFor(modelName),
 		NextSliceIndex:   1,
 	}
 
@@ -161,6 +170,336 @@ func (m *VnpuManager) InitPhysicalNpu(deviceName string, logicID int32, modelNam
 	log.Printf("Physical NPU %s has been initialized.", deviceName)
 }
 
+// InitStaticNpu initializes a physical NPU whose layout is declared by the
+// administrator. Each of the given vNPUs is published as a fixed device; a
+// card without vNPUs is published as a fixed whole card.
+func (m *VnpuManager) InitStaticNpu(deviceName string, logicID int32, modelName string, vdevs []VirtualDevice) {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, exists := m.PhysicalNpus[deviceName]; exists {
+		log.Printf("Physical NPU %s already exists, skipping initialization.", deviceName)
+		return
+	}
+
+	npu := &PhysicalNpuState{
+		DeviceName:       deviceName,
+		PhysicalDeviceID: fmt.Sprintf("npu-%d", logicID),
+		LogicID:          logicID,
+		ModelName:        modelName,
+		AvailableSlices:  []*VnpuSlice{},
+		AllocatedSlices:  []*VnpuSlice{},
+		SupportTemplates: map[string]*VnpuTemplate{},
+		NextSliceIndex:   1,
+		Static:           true,
+	}
+
+	if len(vdevs) == 0 {
+		npu.AvailableSlices = append(npu.AvailableSlices, &VnpuSlice{
+			SliceID: deviceName,
+			Type:    "NPU",
+		})
+	}
+	for _, vdev := range vdevs {
+		npu.AvailableSlices = append(npu.AvailableSlices, &VnpuSlice{
+			SliceID:      fmt.Sprintf("npu-%d-%d", logicID, npu.NextSliceIndex),
+			TemplateName: vdev.TemplateName,
+			Type:         "vNPU",
+			OnChip:       true,
+			VDevID:       vdev.VDevID,
+		})
+		npu.NextSliceIndex++
+	}
+	m.PhysicalNpus[deviceName] = npu
+
+	log.Printf("Static physical NPU %s has been initialized with %d vNPUs.", deviceName, len(vdevs))
+}
+
+// onChipAdoption associates a vNPU found on the chip with the claim that
+// prepared it before the plugin restarted.
+type onChipAdoption struct {
+	ClaimUID     string
+	SliceID      string
+	TemplateName string
+	// VDevID is the on-chip vNPU the slice was prepared with, if it was
+	// known. vNPUs created by the container runtime are only known by their
+	// template.
+	VDevID *uint32
+}
+
+// ImportOnChipSlices records the vNPUs that already exist on the chip of a
+// dynamically split card. vNPUs matching an adoption are handed back to their
+// claim under their previous slice ID, all others are kept allocated without
+// an owner. Adoptions that recorded their vNPU only match that vNPU; the
+// others match by template, but only if the template identifies a single vNPU
+// and a single adoption. The rest of the card is offered as a remainder
+// slice; the whole card is not offered while any of the vNPUs exist. It
+// returns the slices that were adopted by a claim.
+func (m *VnpuManager) ImportOnChipSlices(deviceName string, vdevs []VirtualDevice, adoptions []onChipAdoption) []*VnpuSlice {
+	m.Lock()
+	defer m.Unlock()
+
+	npu, exists := m.PhysicalNpus[deviceName]
+	if !exists || len(vdevs) == 0 {
+		return nil
+	}
+
+	var adopted []*VnpuSlice
+	used := make([]bool, len(vdevs))
+	adopt := func(adoption onChipAdoption, i int) {
+		used[i] = true
+		vdev := vdevs[i]
+		sliceType := "vNPU"
+		if adoption.SliceID == npu.DeviceName {
+			sliceType = "NPU"
+		}
+		slice := &VnpuSlice{
+			SliceID:      adoption.SliceID,
+			TemplateName: vdev.TemplateName,
+			Allocated:    true,
+			Type:         sliceType,
+			ClaimUID:     adoption.ClaimUID,
+			OnChip:       true,
+			VDevID:       vdev.VDevID,
+		}
+		npu.AllocatedSlices = append(npu.AllocatedSlices, slice)
+		adopted = append(adopted, slice)
+		if _, index, ok := parseSliceID(adoption.SliceID); ok && index >= npu.NextSliceIndex {
+			npu.NextSliceIndex = index + 1
+		}
+		log.Printf("Adopted on-chip vNPU %d (template %s) as slice %s of claim %s",
+			vdev.VDevID, vdev.TemplateName, adoption.SliceID, adoption.ClaimUID)
+	}
+
+	var byTemplate []onChipAdoption
+	for _, adoption := range adoptions {
+		if adoption.VDevID == nil {
+			byTemplate = append(byTemplate, adoption)
+			continue
+		}
+		i := slices.IndexFunc(vdevs, func(vdev VirtualDevice) bool { return vdev.VDevID == *adoption.VDevID })
+		if i < 0 || used[i] || vdevs[i].TemplateName != adoption.TemplateName {
+			log.Printf("On-chip vNPU %d (template %s) of slice %s of claim %s is gone",
+				*adoption.VDevID, adoption.TemplateName, adoption.SliceID, adoption.ClaimUID)
+			continue
+		}
+		adopt(adoption, i)
+	}
+	for _, adoption := range byTemplate {
+		var candidates []int
+		for i, vdev := range vdevs {
+			if !used[i] && vdev.TemplateName == adoption.TemplateName {
+				candidates = append(candidates, i)
+			}
+		}
+		claimants := 0
+		for _, other := range byTemplate {
+			if other.TemplateName == adoption.TemplateName {
+				claimants++
+			}
+		}
+		if len(candidates) != 1 || claimants != 1 {
+			log.Printf("Cannot tell which of %d on-chip vNPUs with template %s belongs to slice %s of claim %s",
+				len(candidates), adoption.TemplateName, adoption.SliceID, adoption.ClaimUID)
+			continue
+		}
+		adopt(adoption, candidates[0])
+	}
+
+	for i, vdev := range vdevs {
+		if used[i] {
+			continue
+		}
+		sliceID := fmt.Sprintf("npu-%d-%d", npu.LogicID, npu.NextSliceIndex)
+		npu.NextSliceIndex++
+		npu.AllocatedSlices = append(npu.AllocatedSlices, &VnpuSlice{
+			SliceID:      sliceID,
+			TemplateName: vdev.TemplateName,
+			Allocated:    true,
+			Type:         "vNPU",
+			OnChip:       true,
+			VDevID:       vdev.VDevID,
+		})
+		log.Printf("Imported unowned on-chip vNPU %d (template %s) as slice %s", vdev.VDevID, vdev.TemplateName, sliceID)
+	}
+
+	npu.AvailableSlices = []*VnpuSlice{{
+		SliceID: fmt.Sprintf("npu-%d-%d", npu.LogicID, npu.NextSliceIndex),
+		Type:    "vNPU",
+	}}
+	npu.NextSliceIndex++
+	m.updateSupportTemplates(npu)
+
+	return adopted
+}
+
+// sliceReservation is a slice recorded in a prepared claim that has to be
+// allocated to the claim again.
+type sliceReservation struct {
+	ClaimUID     string
+	SliceID      string
+	TemplateName string
+	TimeSliced   bool
+}
+
+// ReservePreparedSlices allocates the slices of the devices recorded in
+// prepared to their claims again, unless they already are, such as the
+// vNPUs adopted from the chip. Whole cards, time slices and vNPUs the
+// container runtime has not created on the chip yet are thus not handed out
+// again. Cards the driver does not report are kept as unavailable cards
+// holding the slices. It returns the dynamically split cards whose slices
+// changed, the slices of static cards keep their devices.
+func (m *VnpuManager) ReservePreparedSlices(prepared PreparedClaims) []*PhysicalNpuState {
+	m.Lock()
+	defer m.Unlock()
+
+	reservations := make(map[string][]sliceReservation)
+	for _, claimUID := range slices.Sorted(maps.Keys(prepared)) {
+		for _, device := range prepared[claimUID] {
+			if device.AdminAccess {
+				continue
+			}
+			if _, _, slice, err := m.findAllocatedSlice(device.DeviceName); err == nil && slice.ClaimUID == claimUID {
+				continue
+			}
+			logicID, _, ok := parseSliceID(device.DeviceName)
+			if !ok {
+				log.Printf("Cannot reserve device %s of claim %s: not a slice of a physical NPU", device.DeviceName, claimUID)
+				continue
+			}
+			deviceName := fmt.Sprintf("npu-%d-0", logicID)
+			reservations[deviceName] = append(reservations[deviceName], sliceReservation{
+				ClaimUID:     claimUID,
+				SliceID:      device.DeviceName,
+				TemplateName: device.TemplateName,
+				TimeSliced:   device.TimeSliced,
+			})
+		}
+	}
+
+	var changed []*PhysicalNpuState
+	for _, deviceName := range slices.Sorted(maps.Keys(reservations)) {
+		npu, exists := m.PhysicalNpus[deviceName]
+		if !exists {
+			logicID, _, _ := parseSliceID(deviceName)
+			npu = &PhysicalNpuState{
+				DeviceName:       deviceName,
+				PhysicalDeviceID: fmt.Sprintf("npu-%d", logicID),
+				LogicID:          logicID,
+				AvailableSlices:  []*VnpuSlice{},
+				AllocatedSlices:  []*VnpuSlice{},
+				SupportTemplates: map[string]*VnpuTemplate{},
+				NextSliceIndex:   1,
+				Unavailable:      true,
+			}
+			m.PhysicalNpus[deviceName] = npu
+			log.Printf("Physical NPU %s of prepared claims is not reported by the driver, marked unavailable", deviceName)
+		}
+		if npu.Static {
+			m.reserveStaticSlices(npu, reservations[deviceName])
+			continue
+		}
+		m.reserveSlices(npu, reservations[deviceName])
+		changed = append(changed, npu)
+	}
+	return changed
+}
+
+// reserveStaticSlices allocates the given fixed slices of a static card to
+// their claims.
+func (m *VnpuManager) reserveStaticSlices(npu *PhysicalNpuState, reservations []sliceReservation) {
+	for _, reservation := range reservations {
+		i := slices.IndexFunc(npu.AvailableSlices, func(s *VnpuSlice) bool { return s.SliceID == reservation.SliceID })
+		if i < 0 {
+			log.Printf("Cannot reserve slice %s of claim %s: %s has no such free slice", reservation.SliceID, reservation.ClaimUID, npu.DeviceName)
+			continue
+		}
+		slice := npu.AvailableSlices[i]
+		slice.Allocated = true
+		slice.ClaimUID = reservation.ClaimUID
+		npu.AllocatedSlices = append(npu.AllocatedSlices, slice)
+		npu.AvailableSlices = slices.Delete(npu.AvailableSlices, i, i+1)
+		log.Printf("Reserved static slice %s of claim %s", slice.SliceID, slice.ClaimUID)
+	}
+}
+
+// reserveSlices allocates the given slices of a dynamically split card to
+// their claims and recomputes what is left of the card: nothing but a time
+// slice next to whole cards, or a remainder next to vNPUs.
+func (m *VnpuManager) reserveSlices(npu *PhysicalNpuState, reservations []sliceReservation) {
+	wholeCard, timeSliced := false, false
+	for _, reservation := range reservations {
+		sliceType := "vNPU"
+		switch {
+		case reservation.TemplateName == "" && reservation.SliceID != npu.DeviceName:
+			sliceType = timeSliceType
+		case reservation.SliceID == npu.DeviceName:
+			sliceType = "NPU"
+		}
+		npu.AllocatedSlices = append(npu.AllocatedSlices, &VnpuSlice{
+			SliceID:      reservation.SliceID,
+			TemplateName: reservation.TemplateName,
+			Allocated:    true,
+			Type:         sliceType,
+			ClaimUID:     reservation.ClaimUID,
+			TimeSliced:   reservation.TimeSliced,
+		})
+		if _, index, ok := parseSliceID(reservation.SliceID); ok && index >= npu.NextSliceIndex {
+			npu.NextSliceIndex = index + 1
+		}
+		if reservation.TemplateName == "" {
+			wholeCard = true
+			timeSliced = timeSliced || reservation.TimeSliced
+		}
+		log.Printf("Reserved slice %s (template %q) of claim %s", reservation.SliceID, reservation.TemplateName, reservation.ClaimUID)
+	}
+
+	npu.AvailableSlices = []*VnpuSlice{}
+	switch {
+	case timeSliced:
+		m.offerTimeSlice(npu)
+	case wholeCard:
+		npu.SupportTemplates = map[string]*VnpuTemplate{}
+	default:
+		npu.AvailableSlices = append(npu.AvailableSlices, &VnpuSlice{
+			SliceID: fmt.Sprintf("npu-%d-%d", npu.LogicID, npu.NextSliceIndex),
+			Type:    "vNPU",
+		})
+		npu.NextSliceIndex++
+		m.updateSupportTemplates(npu)
+	}
+}
+
+// SetAvailability marks a physical NPU as available or unavailable and
+// reports whether its state changed.
+func (m *VnpuManager) SetAvailability(deviceName string, available bool) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	npu, exists := m.PhysicalNpus[deviceName]
+	if !exists || npu.Unavailable == !available {
+		return false
+	}
+	npu.Unavailable = !available
+	if available {
+		log.Printf("Physical NPU %s is available again", deviceName)
+	} else {
+		log.Printf("Physical NPU %s is no longer reported by the driver, marked unavailable", deviceName)
+	}
+	return true
+}
+
+// SetCapacity records the total AI Core and memory of an uncarved physical NPU.
+func (m *VnpuManager) SetCapacity(deviceName string, aicore, memory int) {
+	m.Lock()
+	defer m.Unlock()
+
+	if npu, exists := m.PhysicalNpus[deviceName]; exists {
+		npu.TotalAicore = aicore
+		npu.TotalMemory = memory
+	}
+}
+
 // ReleaseSlice releases the specified VNPU slice.
 func (m *VnpuManager) ReleaseSlice(sliceID string) error {
 	m.Lock()
@@ -173,53 +512,166 @@ func (m *VnpuManager) ReleaseSlice(sliceID string) error {
 
 	pnpu.AllocatedSlices = append(pnpu.AllocatedSlices[:idx], pnpu.AllocatedSlices[idx+1:]...)
 	slice.Allocated = false
+	slice.ClaimUID = ""
 
-	if slice.Type == "NPU" {
-		pnpu.AllocatedSlices = []*VnpuSlice{}
-		pnpu.AvailableSlices = []*VnpuSlice{}
-		pnpu.NextSliceIndex = 1
-		pnpu.AvailableSlices = append(pnpu.AvailableSlices, &VnpuSlice{
-			SliceID:      pnpu.DeviceName,
-			TemplateName: "",
-			Allocated:    false,
-			Type:         "NPU",
-		})
-		log.Printf("Successfully released the entire NPU card %s, restored to initial state", pnpu.DeviceName)
+	if pnpu.Static {
+		pnpu.AvailableSlices = append(pnpu.AvailableSlices, slice)
+		log.Printf("Released static slice %s", sliceID)
 		return nil
 	}
 
-	if len(pnpu.AllocatedSlices) == 0 {
-		pnpu.AvailableSlices = []*VnpuSlice{}
-		pnpu.NextSliceIndex = 1
-		pnpu.AvailableSlices = append(pnpu.AvailableSlices, &VnpuSlice{
-			SliceID:      pnpu.DeviceName,
-			TemplateName: "",
-			Allocated:    false,
-			Type:         "NPU",
-		})
-		log.Printf("All vNPU slices released for device %s, restored to full card state", pnpu.DeviceName)
-	} else {
-		pnpu.AvailableSlices = []*VnpuSlice{}
-		newSliceID := fmt.Sprintf("npu-%d-%d", pnpu.LogicID, pnpu.NextSliceIndex)
-		newSlice := &VnpuSlice{
-			SliceID:      newSliceID,
-			TemplateName: "",
-			Allocated:    false,
-			Type:         "vNPU",
+	// A time-sliced card is restored once the last claim sharing it is gone,
+	// until then one more claim can share it.
+	if slice.TimeSliced {
+		slice.TimeSliced = false
+		if len(pnpu.AllocatedSlices) == 0 {
+			m.resetPhysicalNpu(pnpu)
+			log.Printf("Released the last time slice %s, restored %s to initial state", sliceID, pnpu.DeviceName)
+			return nil
 		}
-		pnpu.AvailableSlices = append(pnpu.AvailableSlices, newSlice)
-		pnpu.NextSliceIndex++
+		m.offerTimeSlice(pnpu)
+		log.Printf("Released time slice %s of %s", sliceID, pnpu.DeviceName)
+		return nil
+	}
 
-		if m.deviceUpdateCallback != nil {
-			m.deviceUpdateCallback(newSliceID, pnpu)
+	// The whole-card slice keeps its "NPU" type once it has been carved, so
+	// only an uncarved whole-card allocation restores the card unconditionally.
+	if slice.Type == "NPU" && slice.TemplateName == "" {
+		m.resetPhysicalNpu(pnpu)
+		log.Printf("Successfully released the entire NPU card %s, restored to initial state", pnpu.DeviceName)
+		return nil
+	}
+
+	if len(pnpu.AllocatedSlices) == 0 {
+		if m.ReclaimEmptyCards {
+			m.resetPhysicalNpu(pnpu)
+			log.Printf("All vNPU slices released for device %s, restored to full card state", pnpu.DeviceName)
+			return nil
 		}
-		log.Printf("Released vNPU slice %s, created new available slice %s", sliceID, newSliceID)
+		pnpu.PartitionTemplate, pnpu.PartitionCount = "", 0
 	}
 
+	pnpu.AvailableSlices = []*VnpuSlice{}
+	newSliceID := fmt.Sprintf("npu-%d-%d", pnpu.LogicID, pnpu.NextSliceIndex)
+	newSlice := &VnpuSlice{
+		SliceID:      newSliceID,
+		TemplateName: "",
+		Allocated:    false,
+		Type:         "vNPU",
+	}
+	pnpu.AvailableSlices = append(pnpu.AvailableSlices, newSlice)
+	pnpu.NextSliceIndex++
+	// The new slice is published with the templates the card can host now.
 	m.updateSupportTemplates(pnpu)
+
+	if m.deviceUpdateCallback != nil {
+		m.deviceUpdateCallback(newSliceID, pnpu)
+	}
+	log.Printf("Released vNPU slice %s, created new available slice %s", sliceID, newSliceID)
 	return nil
 }
 
+// resetPhysicalNpu restores a physical NPU to a single, free whole-card slice
+// and makes sure the whole-card device is published again.
+func (m *VnpuManager) resetPhysicalNpu(pnpu *PhysicalNpuState) {
+	pnpu.AllocatedSlices = []*VnpuSlice{}
+	pnpu.AvailableSlices = []*VnpuSlice{{
+		SliceID:      pnpu.DeviceName,
+		TemplateName: "",
+		Allocated:    false,
+		Type:         "NPU",
+	}}
+	pnpu.NextSliceIndex = 1
+	pnpu.PartitionTemplate, pnpu.PartitionCount = "", 0
+	pnpu.SupportTemplates = m.TemplatesFor(pnpu.ModelName)
+
+	if m.deviceUpdateCallback != nil {
+		m.deviceUpdateCallback(pnpu.DeviceName, pnpu)
+	}
+}
+
+// timeSliceType is the type of the devices offering a share of the time of a
+// whole card that other claims time-slice.
+const timeSliceType = "TimeSlice"
+
+// isTimeSlice reports whether deviceName is a time slice of npu.
+func isTimeSlice(npu *PhysicalNpuState, deviceName string) bool {
+	for _, slice := range npu.AvailableSlices {
+		if slice.SliceID == deviceName {
+			return slice.Type == timeSliceType
+		}
+	}
+	return false
+}
+
+// offerTimeSlice offers another time slice of a time-sliced card, unless one
+// is offered already or the card is shared by the maximum number of claims.
+func (m *VnpuManager) offerTimeSlice(npu *PhysicalNpuState) {
+	if len(npu.AllocatedSlices) >= m.MaxTimeSlicedClaims ||
+		slices.ContainsFunc(npu.AvailableSlices, func(s *VnpuSlice) bool { return s.Type == timeSliceType }) {
+		return
+	}
+	sliceID := fmt.Sprintf("npu-%d-%d", npu.LogicID, npu.NextSliceIndex)
+	npu.NextSliceIndex++
+	npu.AvailableSlices = append(npu.AvailableSlices, &VnpuSlice{
+		SliceID: sliceID,
+		Type:    timeSliceType,
+	})
+	// No vNPU can be carved from a card that is time-sliced.
+	npu.SupportTemplates = map[string]*VnpuTemplate{}
+
+	if m.deviceUpdateCallback != nil {
+		m.deviceUpdateCallback(sliceID, npu)
+	}
+	log.Printf("Offered time slice %s of %s, shared by %d claims", sliceID, npu.DeviceName, len(npu.AllocatedSlices))
+}
+
+// allocateTimeSlice allocates a time slice of a card to a claim that
+// time-slices the whole card.
+func (m *VnpuManager) allocateTimeSlice(npu *PhysicalNpuState, deviceName string, req VnpuRequirements) (*VnpuSlice, error) {
+	if !req.WholeCard() || !req.TimeSlicing {
+		return nil, fmt.Errorf("%s is a time slice of %s, only claims time-slicing the whole card can use it", deviceName, npu.DeviceName)
+	}
+	for i, slice := range npu.AvailableSlices {
+		if slice.SliceID != deviceName {
+			continue
+		}
+		slice.Allocated = true
+		slice.TimeSliced = true
+		npu.AllocatedSlices = append(npu.AllocatedSlices, slice)
+		npu.AvailableSlices = append(npu.AvailableSlices[:i], npu.AvailableSlices[i+1:]...)
+		log.Printf("Successfully allocated time slice %s of %s", deviceName, npu.DeviceName)
+		m.offerTimeSlice(npu)
+		return slice, nil
+	}
+	return nil, fmt.Errorf("the slice %s has already been allocated", deviceName)
+}
+
+// partitionTemplate returns the largest template of the model of npu that the
+// whole card can be carved into n of.
+func (m *VnpuManager) partitionTemplate(npu *PhysicalNpuState, n int) (*VnpuTemplate, error) {
+	if npu.TotalAicore == 0 || npu.TotalMemory == 0 {
+		return nil, fmt.Errorf("cannot partition %s into %d vNPUs, its capacity is unknown", npu.DeviceName, n)
+	}
+	var best *VnpuTemplate
+	for _, tpl := range m.TemplatesFor(npu.ModelName) {
+		attrs := tpl.Attributes
+		if attrs.AICORE*n > npu.TotalAicore || attrs.Memory*n > npu.TotalMemory {
+			continue
+		}
+		if best == nil || attrs.AICORE > best.Attributes.AICORE ||
+			(attrs.AICORE == best.Attributes.AICORE && attrs.Memory > best.Attributes.Memory) ||
+			(attrs.AICORE == best.Attributes.AICORE && attrs.Memory == best.Attributes.Memory && tpl.Name < best.Name) {
+			best = tpl
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no vNPU template of %s NPUs fits %d times into %s (AICORE: %d, Memory: %dGB)",
+			npu.ModelName, n, npu.DeviceName, npu.TotalAicore, npu.TotalMemory)
+	}
+	return best, nil
+}
+
 // GetVnpuSpecsEnv returns the ASCEND_VNPU_SPECS environment variable for a given slice.
 func (m *VnpuManager) GetVnpuSpecsEnv(sliceID string) (string, error) {
 	m.Lock()
@@ -235,6 +687,123 @@ func (m *VnpuManager) GetVnpuSpecsEnv(sliceID string) (string, error) {
 	return slice.TemplateName, nil
 }
 
+// GetOnChipVDevID returns the ID of the on-chip vNPU backing an allocated slice.
+func (m *VnpuManager) GetOnChipVDevID(sliceID string) (uint32, bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	_, _, slice, err := m.findAllocatedSlice(sliceID)
+	if err != nil || !slice.OnChip {
+		return 0, false
+	}
+	return slice.VDevID, true
+}
+
+// IsTimeSliced reports whether an allocated slice is a whole card that its
+// claim time-slices with other claims.
+func (m *VnpuManager) IsTimeSliced(sliceID string) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	_, _, slice, err := m.findAllocatedSlice(sliceID)
+	return err == nil && slice.TimeSliced
+}
+
+// CheckPreparedClaims returns an error naming the devices of the claims
+// recorded in prepared that are not allocated to their claims, such as the
+// slices of a static layout that no longer has them.
+func (m *VnpuManager) CheckPreparedClaims(prepared PreparedClaims) error {
+	m.Lock()
+	defer m.Unlock()
+
+	var missing []string
+	for _, claimUID := range slices.Sorted(maps.Keys(prepared)) {
+		for _, device := range prepared[claimUID] {
+			if device.AdminAccess {
+				continue
+			}
+			if _, _, slice, err := m.findAllocatedSlice(device.DeviceName); err != nil || slice.ClaimUID != claimUID {
+				missing = append(missing, fmt.Sprintf("%s of claim %s", device.DeviceName, claimUID))
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("prepared devices not allocated to their claims: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// findPhysicalNpu locates the physical NPU that a device belongs to, either
+// by its whole-card name or by one of its available slices.
+func (m *VnpuManager) findPhysicalNpu(deviceName string) (*PhysicalNpuState, bool) {
+	if npu, ok := m.PhysicalNpus[deviceName]; ok {
+		return npu, true
+	}
+	for _, npu := range m.PhysicalNpus {
+		for _, s := range npu.AvailableSlices {
+			if s.SliceID == deviceName {
+				return npu, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// saveNpuState returns a function restoring the slices and the partitioning of
+// the physical NPU deviceName belongs to, so that an allocation on it can be
+// undone.
+func (m *VnpuManager) saveNpuState(deviceName string) func() {
+	m.Lock()
+	defer m.Unlock()
+
+	npu, ok := m.findPhysicalNpu(deviceName)
+	if !ok {
+		return func() {}
+	}
+	available := cloneSlices(npu.AvailableSlices)
+	allocated := cloneSlices(npu.AllocatedSlices)
+	supportTemplates := npu.SupportTemplates
+	nextSliceIndex := npu.NextSliceIndex
+	partitionTemplate, partitionCount := npu.PartitionTemplate, npu.PartitionCount
+	return func() {
+		m.Lock()
+		defer m.Unlock()
+		npu.AvailableSlices = available
+		npu.AllocatedSlices = allocated
+		npu.SupportTemplates = supportTemplates
+		npu.NextSliceIndex = nextSliceIndex
+		npu.PartitionTemplate, npu.PartitionCount = partitionTemplate, partitionCount
+	}
+}
+
+// cloneSlices returns a deep copy of slices.
+func cloneSlices(slices []*VnpuSlice) []*VnpuSlice {
+	cloned := make([]*VnpuSlice, 0, len(slices))
+	for _, slice := range slices {
+		copied := *slice
+		cloned = append(cloned, &copied)
+	}
+	return cloned
+}
+
+// parseSliceID extracts the logic ID of the physical NPU and the slice index
+// from a slice ID of the form npu-<logicID>-<index>.
+func parseSliceID(sliceID string) (int32, int, bool) {
+	matches := regexp.MustCompile(`^npu-(\d+)-(\d+)$`).FindStringSubmatch(sliceID)
+	if matches == nil {
+		return 0, 0, false
+	}
+	logicID, err := strconv.ParseInt(matches[1], 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	index, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return 0, 0, false
+	}
+	return int32(logicID), index, true
+}
+
 // findAllocatedSlice is a helper method to locate an allocated slice by its ID.
 func (m *VnpuManager) findAllocatedSlice(sliceID string) (*PhysicalNpuState, int, *VnpuSlice, error) {
 	for _, npu := range m.PhysicalNpus {
@@ -257,52 +826,63 @@ func (m *VnpuManager) wholeCardIsAvailable(npu *PhysicalNpuState) bool {
 	return false
 }
 
-func (d *driver) syncAllocatable() {
-	deviceNames := d.getAvailableDeviceNames()
-
-	availableMap := make(map[string]struct{}, len(deviceNames))
-	for _, name := range deviceNames {
-		availableMap[name] = struct{}{}
-	}
-
-	for k := range d.state.allocatable {
-		if _, ok := availableMap[k]; !ok {
-			delete(d.state.allocatable, k)
-		}
-	}
-}
-
-func (d *driver) getAvailableDeviceNames() []string {
-	var deviceNames []string
-	if d.state.vnpuManager != nil {
-		for _, physicalNpu := range d.state.vnpuManager.PhysicalNpus {
-			for _, slice := range physicalNpu.AvailableSlices {
-				deviceNames = append(deviceNames, slice.SliceID)
-			}
-			for _, slice := range physicalNpu.AllocatedSlices {
-				deviceNames = append(deviceNames, slice.SliceID)
-			}
-		}
-	}
-
-	return deviceNames
-}
-
 // updateSupportTemplates updates the set of templates supported by the physical NPU.
 func (m *VnpuManager) updateSupportTemplates(npu *PhysicalNpuState) {
 	// If no slices are allocated, support all templates.
 	if len(npu.AllocatedSlices) == 0 {
-		npu.SupportTemplates = cloneTemplates(m.Templates)
+		npu.SupportTemplates = m.TemplatesFor(npu.ModelName)
 		return
 	}
 	// Otherwise, filter templates as needed.
 	npu.SupportTemplates = make(map[string]*VnpuTemplate)
-	for name, tpl := range m.Templates {
+	for name, tpl := range m.TemplatesFor(npu.ModelName) {
 		// For example, keep only "vir01" when any slice is allocated.
 		if strings.HasPrefix(name, "vir01") {
 			npu.SupportTemplates[name] = tpl
 		}
 	}
+	// A card being partitioned keeps hosting the template of its partitions
+	// until all of them are allocated.
+	if tpl, ok := m.TemplatesFor(npu.ModelName)[npu.PartitionTemplate]; ok && m.hasPartitionLeft(npu, tpl) {
+		npu.SupportTemplates[tpl.Name] = tpl
+	}
+}
+
+// hasPartitionLeft reports whether another partition with template tpl can be
+// allocated on npu: fewer than PartitionCount are, and the capacity left by
+// the allocated slices fits one more.
+func (m *VnpuManager) hasPartitionLeft(npu *PhysicalNpuState, tpl *VnpuTemplate) bool {
+	partitions := 0
+	freeAicore, freeMemory := npu.TotalAicore, npu.TotalMemory
+	for _, slice := range npu.AllocatedSlices {
+		allocated, ok := m.Templates[slice.TemplateName]
+		if !ok {
+			return false
+		}
+		if slice.TemplateName == tpl.Name {
+			partitions++
+		}
+		freeAicore -= allocated.Attributes.AICORE
+		freeMemory -= allocated.Attributes.Memory
+	}
+	return partitions < npu.PartitionCount && tpl.Attributes.AICORE <= freeAicore && tpl.Attributes.Memory <= freeMemory
+}
+
+// TemplatesFor returns a copy of the templates that can be carved on a chip
+// model. All templates are returned for models the driver does not know.
+func (m *VnpuManager) TemplatesFor(modelName string) map[string]*VnpuTemplate {
+	names := common.GetChipTemplateNames(modelName)
+	if names == nil {
+		return cloneTemplates(m.Templates)
+	}
+	templates := make(map[string]*VnpuTemplate, len(names))
+	for _, name := range names {
+		if tpl, ok := m.Templates[name]; ok {
+			copied := *tpl
+			templates[name] = &copied
+		}
+	}
+	return templates
 }
 
 // cloneTemplates performs a shallow copy of the templates.
@@ -314,3 +894,70 @@ func cloneTemplates(src map[string]*VnpuTemplate) map[string]*VnpuTemplate {
 	}
 	return dst
 }
+
+// VnpuRequirements are the minimum resources a vNPU slice has to provide, the
+// exact template it has to be carved with, or the number of equal vNPUs the
+// card is partitioned into.
+type VnpuRequirements struct {
+	Template   string
+	Aicore     int
+	Memory     int
+	Aicpu      int
+	Dvpp       bool
+	Partitions int
+	// TimeSlicing allows a whole card to be shared with other claims that
+	// time-slice it.
+	TimeSlicing bool
+}
+
+// WholeCard reports whether no vNPU was requested, i.e. the whole card is wanted.
+func (r VnpuRequirements) WholeCard() bool {
+	return r.Template == "" && r.Aicore == 0 && r.Memory == 0 && r.Aicpu == 0 && !r.Dvpp && r.Partitions <= 1
+}
+
+// SatisfiedBy reports whether a template is the requested one, or provides at
+// least the requested resources.
+func (r VnpuRequirements) SatisfiedBy(tpl *VnpuTemplate) bool {
+	if r.Template != "" {
+		return tpl.Name == r.Template
+	}
+	attrs := tpl.Attributes
+	return attrs.AICORE >= r.Aicore &&
+		attrs.Memory >= r.Memory &&
+		attrs.AICPU >= r.Aicpu &&
+		(attrs.DVPP || !r.Dvpp)
+}
+
+func (r VnpuRequirements) String() string {
+	if r.Template != "" {
+		return "template " + r.Template
+	}
+	if r.Partitions > 1 {
+		return fmt.Sprintf("1/%d of the card", r.Partitions)
+	}
+	return fmt.Sprintf("AICORE>=%d, Memory>=%dGB, AICPU>=%d, DVPP=%t", r.Aicore, r.Memory, r.Aicpu, r.Dvpp)
+}
+
+// resolveTemplate returns the template that satisfies req while wasting the
+// fewest resources, or nil. Media processing units that were not asked for
+// count as waste, so templates without DVPP win ties. Remaining ties are
+// broken by name to keep the choice stable.
+func resolveTemplate(templates map[string]*VnpuTemplate, req VnpuRequirements) *VnpuTemplate {
+	var best *VnpuTemplate
+	bestWaste := math.MaxInt
+	for _, tpl := range templates {
+		if !req.SatisfiedBy(tpl) {
+			continue
+		}
+		waste := (tpl.Attributes.AICORE - req.Aicore) +
+			(tpl.Attributes.Memory - req.Memory) +
+			(tpl.Attributes.AICPU - req.Aicpu)
+		if tpl.Attributes.DVPP && !req.Dvpp {
+			waste++
+		}
+		if waste < bestWaste || (waste == bestWaste && tpl.Name < best.Name) {
+			best, bestWaste = tpl, waste
+		}
+	}
+	return best
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTemplateInfo(t *testing.T) {
+	output := `
++-------------------------------------------------------------------------------------------+
+|NPU instance template info is:                                                             |
+|Name                AICORE    Memory    AICPU     VPC       VENC      JPEGD     VDEC       |
+|                              GB                                                           |
+|===========================================================================================|
+|vir01               1         3         1         1         0         2         1          |
++-------------------------------------------------------------------------------------------+
+|vir04_3c_ndvpp      4         12        3         0         0         0         0          |
++-------------------------------------------------------------------------------------------+
+`
+	templates := make(map[string]*VnpuTemplate)
+	require.NoError(t, parseTemplateInfo(output, templates))
+	assert.Equal(t, map[string]*VnpuTemplate{
+		"vir01":          {Name: "vir01", Attributes: VnpuTemplateAttribute{AICORE: 1, Memory: 3, AICPU: 1, DVPP: true}},
+		"vir04_3c_ndvpp": {Name: "vir04_3c_ndvpp", Attributes: VnpuTemplateAttribute{AICORE: 4, Memory: 12, AICPU: 3}},
+	}, templates)
+
+	assert.Error(t, parseTemplateInfo("no header", templates))
+}
+
+// templates310P are the vNPU templates of 310P NPUs with 8 AI Cores.
+func templates310P() map[string]*VnpuTemplate {
+	templates := map[string]*VnpuTemplate{}
+	for _, tpl := range []*VnpuTemplate{
+		{Name: "vir01", Attributes: VnpuTemplateAttribute{AICORE: 1, Memory: 3, AICPU: 1, DVPP: true}},
+		{Name: "vir02", Attributes: VnpuTemplateAttribute{AICORE: 2, Memory: 6, AICPU: 2, DVPP: true}},
+		{Name: "vir02_1c", Attributes: VnpuTemplateAttribute{AICORE: 2, Memory: 6, AICPU: 1, DVPP: true}},
+		{Name: "vir04", Attributes: VnpuTemplateAttribute{AICORE: 4, Memory: 12, AICPU: 4, DVPP: true}},
+		{Name: "vir04_3c", Attributes: VnpuTemplateAttribute{AICORE: 4, Memory: 12, AICPU: 3, DVPP: true}},
+		{Name: "vir04_3c_ndvpp", Attributes: VnpuTemplateAttribute{AICORE: 4, Memory: 12, AICPU: 3}},
+		{Name: "vir04_4c_dvpp", Attributes: VnpuTemplateAttribute{AICORE: 4, Memory: 12, AICPU: 4, DVPP: true}},
+	} {
+		templates[tpl.Name] = tpl
+	}
+	return templates
+}
+
+func TestResolveTemplate(t *testing.T) {
+	templates := templates310P()
+
+	tests := map[string]struct {
+		req      VnpuRequirements
+		expected string
+	}{
+		"smallest template":             {req: VnpuRequirements{Aicore: 1}, expected: "vir01"},
+		"memory":                        {req: VnpuRequirements{Memory: 4}, expected: "vir02_1c"},
+		"AI CPUs":                       {req: VnpuRequirements{Aicore: 2, Aicpu: 2}, expected: "vir02"},
+		"DVPP not needed":               {req: VnpuRequirements{Aicore: 3, Aicpu: 3}, expected: "vir04_3c_ndvpp"},
+		"DVPP needed":                   {req: VnpuRequirements{Aicore: 3, Aicpu: 3, Dvpp: true}, expected: "vir04_3c"},
+		"equal waste is broken by name": {req: VnpuRequirements{Aicore: 4, Aicpu: 4}, expected: "vir04"},
+		"too large":                     {req: VnpuRequirements{Aicore: 8}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tpl := resolveTemplate(templates, test.req)
+			if test.expected == "" {
+				assert.Nil(t, tpl)
+				return
+			}
+			require.NotNil(t, tpl)
+			assert.Equal(t, test.expected, tpl.Name)
+		})
+	}
+}
+
+func TestPartitionTemplate(t *testing.T) {
+	m := &VnpuManager{Templates: templates310P()}
+	tests := map[string]struct {
+		npu      *PhysicalNpuState
+		n        int
+		expected string
+		err      string
+	}{
+		"halves":     {npu: &PhysicalNpuState{ModelName: "310P3", TotalAicore: 8, TotalMemory: 24}, n: 2, expected: "vir04"},
+		"quarters":   {npu: &PhysicalNpuState{ModelName: "310P3", TotalAicore: 8, TotalMemory: 24}, n: 4, expected: "vir02"},
+		"eighths":    {npu: &PhysicalNpuState{ModelName: "310P3", TotalAicore: 8, TotalMemory: 24}, n: 8, expected: "vir01"},
+		"too many":   {npu: &PhysicalNpuState{DeviceName: "npu-0-0", ModelName: "310P3", TotalAicore: 8, TotalMemory: 24}, n: 16, err: "no vNPU template of 310P3 NPUs fits 16 times into npu-0-0"},
+		"low memory": {npu: &PhysicalNpuState{ModelName: "310P3", TotalAicore: 8, TotalMemory: 20}, n: 2, expected: "vir02"},
+		"unknown capacity": {
+			npu: &PhysicalNpuState{DeviceName: "npu-0-0", ModelName: "310P3"},
+			n:   2,
+			err: "cannot partition npu-0-0 into 2 vNPUs, its capacity is unknown",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tpl, err := m.partitionTemplate(test.npu, test.n)
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, tpl.Name)
+		})
+	}
+}
+
+func TestImportOnChipSlices(t *testing.T) {
+	vdevs := []VirtualDevice{
+		{VDevID: 100, TemplateName: "vir02"},
+		{VDevID: 101, TemplateName: "vir02"},
+		{VDevID: 102, TemplateName: "vir04"},
+	}
+	vdevID := func(id uint32) *uint32 { return &id }
+
+	tests := map[string]struct {
+		adoptions []onChipAdoption
+		// expected maps the adopted slices to the vNPUs backing them.
+		expected map[string]uint32
+	}{
+		"by vNPU ID": {
+			adoptions: []onChipAdoption{
+				{ClaimUID: "claim-a", SliceID: "npu-0-1", TemplateName: "vir02", VDevID: vdevID(101)},
+				{ClaimUID: "claim-b", SliceID: "npu-0-2", TemplateName: "vir02", VDevID: vdevID(100)},
+			},
+			expected: map[string]uint32{"npu-0-1": 101, "npu-0-2": 100},
+		},
+		"by unique template": {
+			adoptions: []onChipAdoption{
+				{ClaimUID: "claim-a", SliceID: "npu-0-5", TemplateName: "vir04"},
+			},
+			expected: map[string]uint32{"npu-0-5": 102},
+		},
+		"by template shared by several vNPUs": {
+			adoptions: []onChipAdoption{
+				{ClaimUID: "claim-a", SliceID: "npu-0-1", TemplateName: "vir02"},
+			},
+			expected: map[string]uint32{},
+		},
+		"by template shared by several slices": {
+			adoptions: []onChipAdoption{
+				{ClaimUID: "claim-a", SliceID: "npu-0-1", TemplateName: "vir04"},
+				{ClaimUID: "claim-b", SliceID: "npu-0-2", TemplateName: "vir04"},
+			},
+			expected: map[string]uint32{},
+		},
+		"by template after the other vNPU was adopted by ID": {
+			adoptions: []onChipAdoption{
+				{ClaimUID: "claim-b", SliceID: "npu-0-2", TemplateName: "vir02"},
+				{ClaimUID: "claim-a", SliceID: "npu-0-1", TemplateName: "vir02", VDevID: vdevID(100)},
+			},
+			expected: map[string]uint32{"npu-0-1": 100, "npu-0-2": 101},
+		},
+		"vNPU gone": {
+			adoptions: []onChipAdoption{
+				{ClaimUID: "claim-a", SliceID: "npu-0-1", TemplateName: "vir02", VDevID: vdevID(105)},
+			},
+			expected: map[string]uint32{},
+		},
+		"vNPU with another template": {
+			adoptions: []onChipAdoption{
+				{ClaimUID: "claim-a", SliceID: "npu-0-1", TemplateName: "vir02", VDevID: vdevID(102)},
+			},
+			expected: map[string]uint32{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &VnpuManager{PhysicalNpus: map[string]*PhysicalNpuState{}, Templates: templates310P()}
+			m.InitPhysicalNpu("npu-0-0", 0, "310P3")
+
+			adopted := m.ImportOnChipSlices("npu-0-0", vdevs, test.adoptions)
+
+			claims := make(map[string]string)
+			for _, adoption := range test.adoptions {
+				claims[adoption.SliceID] = adoption.ClaimUID
+			}
+			actual := make(map[string]uint32)
+			for _, slice := range adopted {
+				actual[slice.SliceID] = slice.VDevID
+				assert.Equal(t, claims[slice.SliceID], slice.ClaimUID, slice.SliceID)
+			}
+			assert.Equal(t, test.expected, actual)
+
+			// Every vNPU is imported exactly once, owned or not.
+			npu := m.PhysicalNpus["npu-0-0"]
+			var imported []uint32
+			var sliceIDs []string
+			for _, slice := range npu.AllocatedSlices {
+				imported = append(imported, slice.VDevID)
+				sliceIDs = append(sliceIDs, slice.SliceID)
+			}
+			assert.ElementsMatch(t, []uint32{100, 101, 102}, imported)
+			require.Len(t, npu.AvailableSlices, 1)
+			sliceIDs = append(sliceIDs, npu.AvailableSlices[0].SliceID)
+			assert.Len(t, slices.Compact(slices.Sorted(slices.Values(sliceIDs))), len(sliceIDs), "slice IDs are reused: %v", sliceIDs)
+		})
+	}
+}
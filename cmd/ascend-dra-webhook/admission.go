@@ -0,0 +1,249 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	resourceapi "k8s.io/api/resource/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog/v2"
+
+	configapi "Ascend-dra-driver/api/example.com/resource/npu"
+	configinstall "Ascend-dra-driver/api/example.com/resource/npu/install"
+)
+
+var (
+	resourceClaimResource = metav1.GroupVersionResource{
+		Group:    resourceapi.SchemeGroupVersion.Group,
+		Version:  resourceapi.SchemeGroupVersion.Version,
+		Resource: "resourceclaims",
+	}
+	resourceClaimTemplateResource = metav1.GroupVersionResource{
+		Group:    resourceapi.SchemeGroupVersion.Group,
+		Version:  resourceapi.SchemeGroupVersion.Version,
+		Resource: "resourceclaimtemplates",
+	}
+	deviceClassResource = metav1.GroupVersionResource{
+		Group:    resourceapi.SchemeGroupVersion.Group,
+		Version:  resourceapi.SchemeGroupVersion.Version,
+		Resource: "deviceclasses",
+	}
+)
+
+var codecs = func() serializer.CodecFactory {
+	scheme := runtime.NewScheme()
+	if err := admissionv1.AddToScheme(scheme); err != nil {
+		panic(err)
+	}
+	if err := resourceapi.AddToScheme(scheme); err != nil {
+		panic(err)
+	}
+	return serializer.NewCodecFactory(scheme)
+}()
+
+// admissionHandler validates the opaque configs for our driver in
+// AdmissionReviews.
+type admissionHandler struct {
+	catalog *templateCatalog
+}
+
+func (h *admissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := klog.FromContext(r.Context())
+	if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+		http.Error(w, fmt.Sprintf("unsupported content type %q, expected application/json", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("read request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	review := &admissionv1.AdmissionReview{}
+	if _, _, err := codecs.UniversalDeserializer().Decode(body, nil, review); err != nil {
+		http.Error(w, fmt.Sprintf("decode AdmissionReview: %v", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "AdmissionReview has no request", http.StatusBadRequest)
+		return
+	}
+
+	response := h.admit(review.Request)
+	response.UID = review.Request.UID
+	if !response.Allowed {
+		logger.V(2).Info("Denied object", "resource", review.Request.Resource, "namespace", review.Request.Namespace,
+			"name", review.Request.Name, "reason", response.Result.Message)
+	}
+	out, err := json.Marshal(&admissionv1.AdmissionReview{
+		TypeMeta: review.TypeMeta,
+		Response: response,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("encode AdmissionReview: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
+
+// admit validates the opaque configs of the ResourceClaim,
+// ResourceClaimTemplate or DeviceClass in req.
+func (h *admissionHandler) admit(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if unchangedSpec(req) {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	var errs field.ErrorList
+	var kind string
+	var err error
+	switch req.Resource {
+	case resourceClaimResource:
+		kind = "ResourceClaim"
+		claim := &resourceapi.ResourceClaim{}
+		if err = decodeObject(req, claim); err == nil {
+			errs = h.validateClaimConfigs(claim.Spec.Devices.Config, field.NewPath("spec", "devices", "config"))
+		}
+	case resourceClaimTemplateResource:
+		kind = "ResourceClaimTemplate"
+		template := &resourceapi.ResourceClaimTemplate{}
+		if err = decodeObject(req, template); err == nil {
+			errs = h.validateClaimConfigs(template.Spec.Spec.Devices.Config, field.NewPath("spec", "spec", "devices", "config"))
+		}
+	case deviceClassResource:
+		kind = "DeviceClass"
+		class := &resourceapi.DeviceClass{}
+		if err = decodeObject(req, class); err == nil {
+			path := field.NewPath("spec", "config")
+			for i, config := range class.Spec.Config {
+				errs = append(errs, h.validateOpaqueConfig(config.Opaque, path.Index(i).Child("opaque"))...)
+			}
+		}
+	default:
+		err = fmt.Errorf("unexpected resource %v", req.Resource)
+	}
+	if err != nil {
+		return &admissionv1.AdmissionResponse{
+			Result: &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Message: err.Error(),
+				Reason:  metav1.StatusReasonBadRequest,
+				Code:    http.StatusBadRequest,
+			},
+		}
+	}
+
+	if len(errs) > 0 {
+		return &admissionv1.AdmissionResponse{
+			Result: &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Message: fmt.Sprintf("%s %q is invalid: %v", kind, req.Name, errs.ToAggregate()),
+				Reason:  metav1.StatusReasonInvalid,
+				Code:    http.StatusUnprocessableEntity,
+			},
+		}
+	}
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+func decodeObject(req *admissionv1.AdmissionRequest, into runtime.Object) error {
+	if _, _, err := codecs.UniversalDeserializer().Decode(req.Object.Raw, nil, into); err != nil {
+		return fmt.Errorf("decode %v: %w", req.Resource, err)
+	}
+	return nil
+}
+
+// unchangedSpec reports whether req updates an object without changing its
+// spec, such as when a finalizer is added. Such updates are admitted even if
+// the configs no longer pass the checks, e.g. after a template was removed
+// from the catalog.
+func unchangedSpec(req *admissionv1.AdmissionRequest) bool {
+	if req.Operation != admissionv1.Update || req.OldObject.Raw == nil {
+		return false
+	}
+	newSpec, err := decodeSpec(req.Resource, req.Object.Raw)
+	if err != nil {
+		return false
+	}
+	oldSpec, err := decodeSpec(req.Resource, req.OldObject.Raw)
+	if err != nil {
+		return false
+	}
+	return apiequality.Semantic.DeepEqual(newSpec, oldSpec)
+}
+
+// decodeSpec decodes the spec of a raw object of the given resource.
+func decodeSpec(resource metav1.GroupVersionResource, raw []byte) (any, error) {
+	deserializer := codecs.UniversalDeserializer()
+	switch resource {
+	case resourceClaimResource:
+		claim := &resourceapi.ResourceClaim{}
+		_, _, err := deserializer.Decode(raw, nil, claim)
+		return claim.Spec, err
+	case resourceClaimTemplateResource:
+		template := &resourceapi.ResourceClaimTemplate{}
+		_, _, err := deserializer.Decode(raw, nil, template)
+		return template.Spec, err
+	case deviceClassResource:
+		class := &resourceapi.DeviceClass{}
+		_, _, err := deserializer.Decode(raw, nil, class)
+		return class.Spec, err
+	}
+	return nil, fmt.Errorf("unexpected resource %v", resource)
+}
+
+func (h *admissionHandler) validateClaimConfigs(configs []resourceapi.DeviceClaimConfiguration, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, config := range configs {
+		errs = append(errs, h.validateOpaqueConfig(config.Opaque, path.Index(i).Child("opaque"))...)
+	}
+	return errs
+}
+
+// validateOpaqueConfig decodes and validates an opaque config for our driver.
+// Configs for other drivers are ignored.
+func (h *admissionHandler) validateOpaqueConfig(opaque *resourceapi.OpaqueDeviceConfiguration, path *field.Path) field.ErrorList {
+	if opaque == nil || opaque.Driver != DriverName {
+		return nil
+	}
+	path = path.Child("parameters")
+
+	obj, err := runtime.Decode(configinstall.Decoder, opaque.Parameters.Raw)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, field.OmitValueType{}, fmt.Sprintf("cannot decode %s config: %v", DriverName, err))}
+	}
+	config, ok := obj.(*configapi.NpuConfig)
+	if !ok {
+		return field.ErrorList{field.Invalid(path, field.OmitValueType{}, fmt.Sprintf("unexpected config type %T", obj))}
+	}
+	if err := config.Validate(); err != nil {
+		return field.ErrorList{field.Invalid(path, field.OmitValueType{}, err.Error())}
+	}
+	if config.Vnpu != nil && config.Vnpu.Template != "" && !h.catalog.Has(config.Vnpu.Template) {
+		return field.ErrorList{field.NotSupported(path.Child("vnpu", "template"), config.Vnpu.Template, h.catalog.Templates())}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	resourceapi "k8s.io/api/resource/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	validConfig  = `{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"NpuConfig","vnpu":{"template":"vir02"}}`
+	legacyConfig = `{"apiVersion":"gpu.resource.example.com/v1alpha1","kind":"GpuConfig","vnpuSpec":{"templateName":"vir04"}}`
+)
+
+func opaque(driver, params string) *resourceapi.OpaqueDeviceConfiguration {
+	return &resourceapi.OpaqueDeviceConfiguration{
+		Driver:     driver,
+		Parameters: runtime.RawExtension{Raw: []byte(params)},
+	}
+}
+
+func claimWithConfigs(configs ...*resourceapi.OpaqueDeviceConfiguration) *resourceapi.ResourceClaim {
+	claim := &resourceapi.ResourceClaim{
+		TypeMeta:   metav1.TypeMeta{APIVersion: resourceapi.SchemeGroupVersion.String(), Kind: "ResourceClaim"},
+		ObjectMeta: metav1.ObjectMeta{Name: "claim", Namespace: "default"},
+	}
+	for _, config := range configs {
+		claim.Spec.Devices.Config = append(claim.Spec.Devices.Config, resourceapi.DeviceClaimConfiguration{
+			DeviceConfiguration: resourceapi.DeviceConfiguration{Opaque: config},
+		})
+	}
+	return claim
+}
+
+func TestAdmit(t *testing.T) {
+	template := &resourceapi.ResourceClaimTemplate{
+		TypeMeta: metav1.TypeMeta{APIVersion: resourceapi.SchemeGroupVersion.String(), Kind: "ResourceClaimTemplate"},
+		Spec: resourceapi.ResourceClaimTemplateSpec{
+			Spec: claimWithConfigs(opaque(DriverName, `{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"NpuConfig","sharing":{"strategy":"Unknown"}}`)).Spec,
+		},
+	}
+	class := &resourceapi.DeviceClass{
+		TypeMeta: metav1.TypeMeta{APIVersion: resourceapi.SchemeGroupVersion.String(), Kind: "DeviceClass"},
+		Spec: resourceapi.DeviceClassSpec{
+			Config: []resourceapi.DeviceClassConfiguration{
+				{DeviceConfiguration: resourceapi.DeviceConfiguration{Opaque: opaque(DriverName, validConfig)}},
+				{DeviceConfiguration: resourceapi.DeviceConfiguration{Opaque: opaque(DriverName, `{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"NpuConfig","vnpu":{"template":"vir99"}}`)}},
+			},
+		},
+	}
+
+	invalidClaim := claimWithConfigs(opaque(DriverName, `{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"NpuConfig","vnpu":{"template":"vir99"}}`))
+	finalizedClaim := invalidClaim.DeepCopy()
+	finalizedClaim.Finalizers = []string{"example.com/finalizer"}
+	validClass := class.DeepCopy()
+	validClass.Spec.Config = validClass.Spec.Config[:1]
+
+	tests := map[string]struct {
+		resource        metav1.GroupVersionResource
+		object          runtime.Object
+		oldObject       runtime.Object
+		expectedMessage string
+	}{
+		"valid claim": {
+			resource: resourceClaimResource,
+			object:   claimWithConfigs(opaque(DriverName, validConfig), opaque(DriverName, legacyConfig)),
+		},
+		"claim without configs": {
+			resource: resourceClaimResource,
+			object:   claimWithConfigs(),
+		},
+		"config of another driver": {
+			resource: resourceClaimResource,
+			object:   claimWithConfigs(opaque("gpu.example.com", `{"anything":true}`)),
+		},
+		"typo in a field name": {
+			resource: resourceClaimResource,
+			object:   claimWithConfigs(opaque(DriverName, `{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"NpuConfig","vnpu":{"templat":"vir02"}}`)),
+			expectedMessage: `ResourceClaim "claim" is invalid: spec.devices.config[0].opaque.parameters: Invalid value: ` +
+				`cannot decode npu.example.com config: strict decoding error: unknown field "vnpu.templat"`,
+		},
+		"conflicting vNPU fields": {
+			resource: resourceClaimResource,
+			object: claimWithConfigs(opaque(DriverName, validConfig),
+				opaque(DriverName, `{"apiVersion":"npu.resource.example.com/v1alpha1","kind":"NpuConfig","vnpuSpec":{"templateName":"vir02","aicore":2}}`)),
+			expectedMessage: `ResourceClaim "claim" is invalid: spec.devices.config[1].opaque.parameters: Invalid value: ` +
+				`vNPU template "vir02" and resources are mutually exclusive`,
+		},
+		"invalid sharing in a claim template": {
+			resource: resourceClaimTemplateResource,
+			object:   template,
+			expectedMessage: `ResourceClaimTemplate "" is invalid: spec.spec.devices.config[0].opaque.parameters: Invalid value: ` +
+				`unknown NPU sharing strategy: Unknown`,
+		},
+		"unknown template in a device class": {
+			resource:        deviceClassResource,
+			object:          class,
+			expectedMessage: `DeviceClass "" is invalid: spec.config[1].opaque.parameters.vnpu.template: Unsupported value: "vir99": supported values: "vir01", "vir02"`,
+		},
+		"metadata update of a claim with an invalid config": {
+			resource:  resourceClaimResource,
+			object:    finalizedClaim,
+			oldObject: invalidClaim,
+		},
+		"invalid config added to a device class": {
+			resource:        deviceClassResource,
+			object:          class,
+			oldObject:       validClass,
+			expectedMessage: `DeviceClass "" is invalid: spec.config[1].opaque.parameters.vnpu.template: Unsupported value: "vir99"`,
+		},
+		"unexpected resource": {
+			resource:        metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+			object:          claimWithConfigs(),
+			expectedMessage: "unexpected resource",
+		},
+	}
+
+	handler := &admissionHandler{catalog: defaultTemplateCatalog()}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			raw, err := json.Marshal(test.object)
+			require.NoError(t, err)
+			req := &admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				Resource:  test.resource,
+				Name:      test.object.(metav1.Object).GetName(),
+				Object:    runtime.RawExtension{Raw: raw},
+			}
+			if test.oldObject != nil {
+				req.Operation = admissionv1.Update
+				req.OldObject.Raw, err = json.Marshal(test.oldObject)
+				require.NoError(t, err)
+			}
+			response := handler.admit(req)
+			if test.expectedMessage == "" {
+				assert.True(t, response.Allowed, "unexpected denial: %v", response.Result)
+				return
+			}
+			assert.False(t, response.Allowed)
+			assert.Contains(t, response.Result.Message, test.expectedMessage)
+		})
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	server := httptest.NewServer(newMux(defaultTemplateCatalog()))
+	defer server.Close()
+	url := server.URL + "/validate-resource-claim-parameters"
+
+	raw, err := json.Marshal(claimWithConfigs(opaque(DriverName, `{"apiVersion":"npu.resource.example.com/v1alpha2","kind":"NpuConfig","vnpu":{}}`)))
+	require.NoError(t, err)
+	body, err := json.Marshal(&admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: admissionv1.SchemeGroupVersion.String(), Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:      "review-1",
+			Resource: resourceClaimResource,
+			Object:   runtime.RawExtension{Raw: raw},
+		},
+	})
+	require.NoError(t, err)
+
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	review := &admissionv1.AdmissionReview{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(review))
+	assert.Equal(t, "AdmissionReview", review.Kind)
+	require.NotNil(t, review.Response)
+	assert.Equal(t, "review-1", string(review.Response.UID))
+	assert.False(t, review.Response.Allowed)
+	assert.Contains(t, review.Response.Result.Message, "vNPU template name or resources are required")
+
+	resp, err = http.Post(url, "text/plain", bytes.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	resp, err = http.Post(url, "application/json", bytes.NewReader([]byte(`{"kind":"AdmissionReview","apiVersion":"admission.k8s.io/v1"}`)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
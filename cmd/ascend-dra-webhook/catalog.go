@@ -0,0 +1,84 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"sigs.k8s.io/yaml"
+
+	"Ascend-dra-driver/pkg/common"
+)
+
+// templateCatalog lists the vNPU templates that the chip models in the
+// cluster support. A config may only name a template from the catalog.
+type templateCatalog struct {
+	ChipModels map[string][]string `json:"chipModels"`
+}
+
+// defaultTemplateCatalog returns the built-in templates of the chip models
+// the driver knows.
+func defaultTemplateCatalog() *templateCatalog {
+	return &templateCatalog{
+		ChipModels: map[string][]string{
+			"310P": common.GetChipTemplateNames("310P"),
+			"910":  common.GetChipTemplateNames("910"),
+		},
+	}
+}
+
+// loadTemplateCatalog reads the catalog in path, or returns the default
+// catalog if path is empty.
+func loadTemplateCatalog(path string) (*templateCatalog, error) {
+	if path == "" {
+		return defaultTemplateCatalog(), nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read vNPU template catalog: %w", err)
+	}
+	var catalog templateCatalog
+	if err := yaml.UnmarshalStrict(content, &catalog); err != nil {
+		return nil, fmt.Errorf("parse vNPU template catalog %s: %w", path, err)
+	}
+	if len(catalog.ChipModels) == 0 {
+		return nil, fmt.Errorf("vNPU template catalog %s declares no chip models", path)
+	}
+	return &catalog, nil
+}
+
+// Has reports whether any chip model in the cluster supports template.
+func (c *templateCatalog) Has(template string) bool {
+	for _, templates := range c.ChipModels {
+		if slices.Contains(templates, template) {
+			return true
+		}
+	}
+	return false
+}
+
+// Templates returns the sorted names of all templates in the catalog.
+func (c *templateCatalog) Templates() []string {
+	var names []string
+	for _, templates := range c.ChipModels {
+		names = append(names, templates...)
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTemplateCatalog(t *testing.T) {
+	catalog, err := loadTemplateCatalog("")
+	require.NoError(t, err)
+	assert.True(t, catalog.Has("vir04_3c_ndvpp"))
+	assert.True(t, catalog.Has("vir16"))
+	assert.False(t, catalog.Has("vir99"))
+
+	write := func(content string) string {
+		path := filepath.Join(t.TempDir(), "catalog.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	catalog, err = loadTemplateCatalog(write("chipModels:\n  310P3: [vir02, vir01]\n  910B3: [vir05_1c_16g, vir02]\n"))
+	require.NoError(t, err)
+	assert.True(t, catalog.Has("vir05_1c_16g"))
+	assert.False(t, catalog.Has("vir04"))
+	assert.Equal(t, []string{"vir01", "vir02", "vir05_1c_16g"}, catalog.Templates())
+
+	_, err = loadTemplateCatalog(write("chipModels: {}\n"))
+	assert.Error(t, err)
+	_, err = loadTemplateCatalog(write("templates: [vir01]\n"))
+	assert.Error(t, err)
+	_, err = loadTemplateCatalog(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
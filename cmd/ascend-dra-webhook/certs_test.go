@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeyPair writes a self-signed certificate for commonName.
+func writeKeyPair(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func commonName(t *testing.T, r *certReloader) string {
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	_, err := newCertReloader(certFile, keyFile)
+	assert.Error(t, err, "missing files")
+
+	now := time.Now()
+	writeKeyPair(t, certFile, keyFile, "first", now.Add(-time.Minute))
+	r, err := newCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, "first", commonName(t, r))
+
+	writeKeyPair(t, certFile, keyFile, "rotated", now)
+	assert.Equal(t, "rotated", commonName(t, r))
+
+	// A broken rotation keeps the last good certificate.
+	require.NoError(t, os.WriteFile(certFile, []byte("garbage"), 0600))
+	require.NoError(t, os.Chtimes(certFile, now.Add(time.Minute), now.Add(time.Minute)))
+	assert.Equal(t, "rotated", commonName(t, r))
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright 2025 The Kubernetes Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"k8s.io/klog/v2"
+
+	"Ascend-dra-driver/pkg/flags"
+)
+
+// DriverName is the name of the DRA driver whose opaque configs are validated.
+const DriverName = "npu.example.com"
+
+type Flags struct {
+	loggingConfig *flags.LoggingConfig
+
+	port                int
+	certFile            string
+	keyFile             string
+	templateCatalogFile string
+}
+
+func main() {
+	if err := newApp().Run(os.Args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func newApp() *cli.App {
+	flags := &Flags{
+		loggingConfig: flags.NewLoggingConfig(),
+	}
+	cliFlags := []cli.Flag{
+		&cli.IntFlag{
+			Name:        "port",
+			Usage:       "The `port` the HTTPS server listens on.",
+			Value:       8443,
+			Destination: &flags.port,
+			EnvVars:     []string{"PORT"},
+		},
+		&cli.StringFlag{
+			Name:        "tls-cert-file",
+			Usage:       "Path to the PEM-encoded serving certificate. The file is reloaded when it changes.",
+			Required:    true,
+			Destination: &flags.certFile,
+			EnvVars:     []string{"TLS_CERT_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "tls-private-key-file",
+			Usage:       "Path to the PEM-encoded private key of the serving certificate. The file is reloaded when it changes.",
+			Required:    true,
+			Destination: &flags.keyFile,
+			EnvVars:     []string{"TLS_PRIVATE_KEY_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "template-catalog-file",
+			Usage:       "Path to a file listing the vNPU templates of each chip model in the cluster. The built-in templates of the known chip models are used if it is not set.",
+			Destination: &flags.templateCatalogFile,
+			EnvVars:     []string{"TEMPLATE_CATALOG_FILE"},
+		},
+	}
+	cliFlags = append(cliFlags, flags.loggingConfig.Flags()...)
+
+	app := &cli.App{
+		Name:  "ascend-dra-webhook",
+		Usage: "ascend-dra-webhook validates the opaque NPU configs of ResourceClaims, ResourceClaimTemplates and DeviceClasses.",
+		Flags: cliFlags,
+		Before: func(c *cli.Context) error {
+			return flags.loggingConfig.Apply()
+		},
+		Action: func(c *cli.Context) error {
+			catalog, err := loadTemplateCatalog(flags.templateCatalogFile)
+			if err != nil {
+				return err
+			}
+			certs, err := newCertReloader(flags.certFile, flags.keyFile)
+			if err != nil {
+				return err
+			}
+			return runServer(c.Context, flags.port, certs, catalog)
+		},
+	}
+
+	return app
+}
+
+func newMux(catalog *templateCatalog) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/validate-resource-claim-parameters", &admissionHandler{catalog: catalog})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	return mux
+}
+
+func runServer(ctx context.Context, port int, certs *certReloader, catalog *templateCatalog) error {
+	logger := klog.FromContext(ctx)
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: newMux(catalog),
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("Starting webhook server", "address", server.Addr)
+		errCh <- server.ListenAndServeTLS("", "")
+	}()
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("webhook server: %w", err)
+	case <-sigc:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shut down webhook server: %w", err)
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("webhook server: %w", err)
+	}
+	return nil
+}
@@ -21,7 +21,9 @@ VERSION  ?= v0.1.0
 vVERSION := v$(VERSION:v%=%)
 
 VENDOR := example.com
-APIS := gpu/v1alpha1
+APIS := gpu/v1alpha1 npu npu/v1alpha1 npu/v1alpha2
+# Versions that are converted to and defaulted from the internal npu types.
+VERSIONED_APIS := npu/v1alpha1 npu/v1alpha2
 
 PLURAL_EXCEPTIONS  = DeviceClassParameters:DeviceClassParameters
 PLURAL_EXCEPTIONS += GpuClaimParameters:GpuClaimParameters
@@ -17,6 +17,8 @@ package common
 
 const (
 	MaxVirtualDeviceNum = 1024
+	// DefaultIDForCreateVNPU lets the driver choose the vdev and vfg ID of a new vNPU
+	DefaultIDForCreateVNPU = 0xFFFFFFFF
 )
 
 const (
@@ -15,6 +15,8 @@
 // Package common a series of common function
 package common
 
+import "strings"
+
 // GetTemplateName2DeviceTypeMap get virtual device type by template
 func GetTemplateName2DeviceTypeMap() map[string]string {
 	return map[string]string{
@@ -29,3 +31,14 @@ func GetTemplateName2DeviceTypeMap() map[string]string {
 		Vir04C3Ndvpp: Core4Cpu3Ndvpp,
 	}
 }
+
+// GetChipTemplateNames get the virtual device templates a chip supports, nil if the chip is unknown
+func GetChipTemplateNames(chipName string) []string {
+	switch {
+	case strings.HasPrefix(chipName, "310P"):
+		return []string{Vir01, Vir02, Vir02C1, Vir04, Vir04C3, Vir04C3Ndvpp, Vir04C4Dvpp}
+	case strings.HasPrefix(chipName, "910") && !strings.HasPrefix(chipName, "910B"):
+		return []string{Vir02, Vir04, Vir08, Vir16}
+	}
+	return nil
+}
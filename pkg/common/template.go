@@ -0,0 +1,73 @@
+/* Copyright(C) 2025. Huawei Technologies Co.,Ltd. All rights reserved.
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package common a series of common function
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TemplateAttributeDomain is the attribute domain under which a published NPU
+// lists the vNPU templates its chip model supports, one attribute per template
+// named after it, with the TemplateShape of the template as value
+const TemplateAttributeDomain = "vnpu.npu.example.com"
+
+// TemplateHostableAttributeDomain is the attribute domain under which a
+// published NPU tells, for every template listed under TemplateAttributeDomain,
+// whether it can currently carve a vNPU of that template
+const TemplateHostableAttributeDomain = "hostable." + TemplateAttributeDomain
+
+// TemplateShape the resources a vNPU template carves out of a chip
+type TemplateShape struct {
+	AICore int
+	Memory int
+	AICPU  int
+	DVPP   bool
+}
+
+// String encode the shape as a device attribute value, e.g. aicore=8,memory=12,aicpu=2,dvpp=true
+func (s TemplateShape) String() string {
+	return fmt.Sprintf("aicore=%d,memory=%d,aicpu=%d,dvpp=%t", s.AICore, s.Memory, s.AICPU, s.DVPP)
+}
+
+// ParseTemplateShape decode a shape encoded by TemplateShape.String
+func ParseTemplateShape(value string) (TemplateShape, error) {
+	var shape TemplateShape
+	for _, field := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(field, "=")
+		if !ok {
+			return TemplateShape{}, fmt.Errorf("invalid template shape field %q", field)
+		}
+		var err error
+		switch key {
+		case "aicore":
+			shape.AICore, err = strconv.Atoi(val)
+		case "memory":
+			shape.Memory, err = strconv.Atoi(val)
+		case "aicpu":
+			shape.AICPU, err = strconv.Atoi(val)
+		case "dvpp":
+			shape.DVPP, err = strconv.ParseBool(val)
+		default:
+			err = fmt.Errorf("unknown key")
+		}
+		if err != nil {
+			return TemplateShape{}, fmt.Errorf("invalid template shape field %q: %v", field, err)
+		}
+	}
+	return shape, nil
+}
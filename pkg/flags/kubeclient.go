@@ -30,10 +30,21 @@ type KubeClientConfig struct {
 	KubeConfig   string
 	KubeAPIQPS   float64
 	KubeAPIBurst int
+
+	// PublisherKubeAPIQPS and PublisherKubeAPIBurst rate limit the client
+	// publishing ResourceSlices. Zero values fall back to KubeAPIQPS and
+	// KubeAPIBurst.
+	PublisherKubeAPIQPS   float64
+	PublisherKubeAPIBurst int
 }
 
+// ClientSets are the clients a component uses for all its API access.
 type ClientSets struct {
 	Core coreclientset.Interface
+	// Publisher is the client publishing ResourceSlices. It is rate limited
+	// separately from Core, so that frequent ResourceSlice updates do not
+	// delay other API access, nor the other way around.
+	Publisher coreclientset.Interface
 }
 
 func (k *KubeClientConfig) Flags() []cli.Flag {
@@ -66,6 +77,27 @@ func (k *KubeClientConfig) Flags() []cli.Flag {
 	return flags
 }
 
+// PublisherFlags returns the flags configuring the client of components that
+// publish ResourceSlices.
+func (k *KubeClientConfig) PublisherFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.Float64Flag{
+			Category:    "Kubernetes client:",
+			Name:        "kube-api-publisher-qps",
+			Usage:       "`QPS` to use while publishing ResourceSlices. Defaults to --kube-api-qps.",
+			Destination: &k.PublisherKubeAPIQPS,
+			EnvVars:     []string{"KUBE_API_PUBLISHER_QPS"},
+		},
+		&cli.IntFlag{
+			Category:    "Kubernetes client:",
+			Name:        "kube-api-publisher-burst",
+			Usage:       "`Burst` to use while publishing ResourceSlices. Defaults to --kube-api-burst.",
+			Destination: &k.PublisherKubeAPIBurst,
+			EnvVars:     []string{"KUBE_API_PUBLISHER_BURST"},
+		},
+	}
+}
+
 func (k *KubeClientConfig) NewClientSetConfig() (*rest.Config, error) {
 	var csconfig *rest.Config
 
@@ -99,7 +131,21 @@ func (k *KubeClientConfig) NewClientSets() (ClientSets, error) {
 		return ClientSets{}, fmt.Errorf("create core client: %v", err)
 	}
 
+	// Each client set gets its own rate limiter from its configuration.
+	pubconfig := rest.CopyConfig(csconfig)
+	if k.PublisherKubeAPIQPS > 0 {
+		pubconfig.QPS = float32(k.PublisherKubeAPIQPS)
+	}
+	if k.PublisherKubeAPIBurst > 0 {
+		pubconfig.Burst = k.PublisherKubeAPIBurst
+	}
+	publisherclient, err := coreclientset.NewForConfig(pubconfig)
+	if err != nil {
+		return ClientSets{}, fmt.Errorf("create publisher client: %v", err)
+	}
+
 	return ClientSets{
-		Core: coreclient,
+		Core:      coreclient,
+		Publisher: publisherclient,
 	}, nil
 }